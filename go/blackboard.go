@@ -1,6 +1,7 @@
 package reflex
 
 import (
+	"path"
 	"sync"
 	"time"
 )
@@ -15,6 +16,8 @@ import (
 // chronological order (oldest first, newest last).
 type scopedBlackboardReader struct {
 	scopes [][]BlackboardEntry
+	// copy makes every value returned a deep copy of the stored one.
+	copy bool
 }
 
 // NewBlackboardReader creates a BlackboardReader over the given scope chain.
@@ -29,14 +32,30 @@ func NewBlackboardReader(scopes [][]BlackboardEntry) BlackboardReader {
 // Get walks scopes local → parent → grandparent. Returns the value of the
 // latest entry for key in the first scope that contains it.
 func (r *scopedBlackboardReader) Get(key string) (any, bool) {
+	entry, ok := r.latest(key)
+	if !ok {
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+// latest returns the entry Get reads for key.
+func (r *scopedBlackboardReader) latest(key string) (BlackboardEntry, bool) {
 	for _, scope := range r.scopes {
-		for i := len(scope) - 1; i >= 0; i-- {
-			if scope[i].Key == key {
-				return scope[i].Value, true
-			}
+		if entry, ok := latestIn(scope, key); ok {
+			return r.out(entry), true
 		}
 	}
-	return nil, false
+	return BlackboardEntry{}, false
+}
+
+// out returns entry as handed to callers, with its value copied if the
+// reader copies values.
+func (r *scopedBlackboardReader) out(entry BlackboardEntry) BlackboardEntry {
+	if r.copy {
+		return copyEntry(entry)
+	}
+	return entry
 }
 
 // Has returns true if key exists in any scope.
@@ -58,7 +77,7 @@ func (r *scopedBlackboardReader) GetAll(key string) []BlackboardEntry {
 	for _, scope := range r.scopes {
 		for _, e := range scope {
 			if e.Key == key {
-				result = append(result, e)
+				result = append(result, r.out(e))
 			}
 		}
 	}
@@ -69,7 +88,9 @@ func (r *scopedBlackboardReader) GetAll(key string) []BlackboardEntry {
 func (r *scopedBlackboardReader) Entries() []BlackboardEntry {
 	var result []BlackboardEntry
 	for _, scope := range r.scopes {
-		result = append(result, scope...)
+		for _, e := range scope {
+			result = append(result, r.out(e))
+		}
 	}
 	return result
 }
@@ -95,10 +116,37 @@ func (r *scopedBlackboardReader) Local() []BlackboardEntry {
 		return nil
 	}
 	result := make([]BlackboardEntry, len(r.scopes[0]))
-	copy(result, r.scopes[0])
+	for i, e := range r.scopes[0] {
+		result[i] = r.out(e)
+	}
 	return result
 }
 
+// Lineage starts at the entry Get would read for key and follows
+// PromotedFrom links back through each returnMap promotion. The last element
+// is the entry written by the node that produced the value.
+func (r *scopedBlackboardReader) Lineage(key string) []BlackboardEntry {
+	entry, ok := r.latest(key)
+	if !ok {
+		return nil
+	}
+	result := []BlackboardEntry{entry}
+	for from := entry.PromotedFrom; from != nil; from = from.PromotedFrom {
+		result = append(result, r.out(*from))
+	}
+	return result
+}
+
+// latestIn returns the newest entry for key within a single scope.
+func latestIn(scope []BlackboardEntry, key string) (BlackboardEntry, bool) {
+	for i := len(scope) - 1; i >= 0; i-- {
+		if scope[i].Key == key {
+			return scope[i], true
+		}
+	}
+	return BlackboardEntry{}, false
+}
+
 // ---------------------------------------------------------------------------
 // ScopedBlackboard — write side (DESIGN.md Section 2.7)
 // ---------------------------------------------------------------------------
@@ -109,11 +157,20 @@ func (r *scopedBlackboardReader) Local() []BlackboardEntry {
 type ScopedBlackboard struct {
 	mu      sync.RWMutex
 	entries []BlackboardEntry
+	mode    ValueMode
 }
 
 // NewBlackboard creates a new ScopedBlackboard, optionally seeded with entries.
+// Values are stored by reference (ValueModeReference).
 func NewBlackboard(entries ...BlackboardEntry) *ScopedBlackboard {
-	bb := &ScopedBlackboard{}
+	return NewBlackboardWithMode(ValueModeReference, entries...)
+}
+
+// NewBlackboardWithMode creates a new ScopedBlackboard that stores appended
+// values according to mode, optionally seeded with entries. Seed entries are
+// stored as given.
+func NewBlackboardWithMode(mode ValueMode, entries ...BlackboardEntry) *ScopedBlackboard {
+	bb := &ScopedBlackboard{mode: mode}
 	if len(entries) > 0 {
 		bb.entries = make([]BlackboardEntry, len(entries))
 		copy(bb.entries, entries)
@@ -123,6 +180,10 @@ func NewBlackboard(entries ...BlackboardEntry) *ScopedBlackboard {
 
 // Append converts writes to full entries and appends them to this scope.
 // All entries in a single call share the same source and timestamp.
+// Under ValueModeCopy and ValueModeStrict each value is deep-copied first,
+// and the returned entries hold a further copy. Append does not reject
+// values; callers check them with CheckValue. A value that cannot be copied
+// because it contains a reference cycle is stored as given.
 // Returns the newly created entries.
 func (bb *ScopedBlackboard) Append(writes []BlackboardWrite, source BlackboardSource) []BlackboardEntry {
 	now := time.Now().UnixMilli()
@@ -133,25 +194,92 @@ func (bb *ScopedBlackboard) Append(writes []BlackboardWrite, source BlackboardSo
 			Value:     w.Value,
 			Source:    source,
 			Timestamp: now,
+			Sensitive: w.Sensitive,
+		}
+		if bb.mode != ValueModeReference {
+			newEntries[i] = copyEntry(newEntries[i])
 		}
 	}
 	bb.mu.Lock()
 	bb.entries = append(bb.entries, newEntries...)
 	bb.mu.Unlock()
+	if bb.mode != ValueModeReference {
+		return copyEntries(newEntries)
+	}
 	return newEntries
 }
 
-// Entries returns a copy of this scope's entries.
+// copyEntries returns entries with their values copied.
+func copyEntries(entries []BlackboardEntry) []BlackboardEntry {
+	result := make([]BlackboardEntry, len(entries))
+	for i, e := range entries {
+		result[i] = copyEntry(e)
+	}
+	return result
+}
+
+// copyEntry returns entry with a deep copy of its value and of the values in
+// its lineage. Values that cannot be copied are kept.
+func copyEntry(entry BlackboardEntry) BlackboardEntry {
+	if cp, err := CopyValue(entry.Value); err == nil {
+		entry.Value = cp
+	}
+	if entry.PromotedFrom != nil {
+		from := copyEntry(*entry.PromotedFrom)
+		entry.PromotedFrom = &from
+	}
+	return entry
+}
+
+// promote appends a copy of a child scope's entry under key, keeping the child
+// entry as PromotedFrom so the value's lineage survives the child's pop. The
+// promoted entry is sensitive if the child entry was or sensitive is set; in
+// the latter case its whole lineage is marked sensitive as well, so the value
+// is not exposed through PromotedFrom.
+func (bb *ScopedBlackboard) promote(key string, from BlackboardEntry, source BlackboardSource, sensitive bool) BlackboardEntry {
+	if sensitive {
+		from = markLineageSensitive(from)
+	}
+	entry := BlackboardEntry{
+		Key:          key,
+		Value:        from.Value,
+		Source:       source,
+		Timestamp:    time.Now().UnixMilli(),
+		PromotedFrom: &from,
+		Sensitive:    from.Sensitive || sensitive,
+	}
+	bb.mu.Lock()
+	bb.entries = append(bb.entries, entry)
+	bb.mu.Unlock()
+	if bb.mode != ValueModeReference {
+		return copyEntry(entry)
+	}
+	return entry
+}
+
+// latest returns the newest entry for key in this scope.
+func (bb *ScopedBlackboard) latest(key string) (BlackboardEntry, bool) {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+	return latestIn(bb.entries, key)
+}
+
+// Entries returns a copy of this scope's entries. Under ValueModeCopy and
+// ValueModeStrict their values are copied too.
 func (bb *ScopedBlackboard) Entries() []BlackboardEntry {
 	bb.mu.RLock()
 	defer bb.mu.RUnlock()
+	if bb.mode != ValueModeReference {
+		return copyEntries(bb.entries)
+	}
 	result := make([]BlackboardEntry, len(bb.entries))
 	copy(result, bb.entries)
 	return result
 }
 
 // Reader constructs a BlackboardReader with this scope as the local (innermost)
-// scope, plus any ancestor scopes from the call stack.
+// scope, plus any ancestor scopes from the call stack. Under ValueModeCopy and
+// ValueModeStrict the reader returns copies of the stored values.
 func (bb *ScopedBlackboard) Reader(parentScopes ...[]BlackboardEntry) BlackboardReader {
 	bb.mu.RLock()
 	local := make([]BlackboardEntry, len(bb.entries))
@@ -160,5 +288,81 @@ func (bb *ScopedBlackboard) Reader(parentScopes ...[]BlackboardEntry) Blackboard
 	scopes := make([][]BlackboardEntry, 0, 1+len(parentScopes))
 	scopes = append(scopes, local)
 	scopes = append(scopes, parentScopes...)
-	return NewBlackboardReader(scopes)
+	return &scopedBlackboardReader{scopes: scopes, copy: bb.mode != ValueModeReference}
+}
+
+// ---------------------------------------------------------------------------
+// Sensitive entries
+// ---------------------------------------------------------------------------
+
+// RedactedValue replaces the value of sensitive entries in events and exports.
+const RedactedValue = "[REDACTED]"
+
+// RedactEntries returns a copy of entries with the value of every sensitive
+// entry, including entries in PromotedFrom chains, replaced by RedactedValue.
+func RedactEntries(entries []BlackboardEntry) []BlackboardEntry {
+	if entries == nil {
+		return nil
+	}
+	result := make([]BlackboardEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = redactEntry(entry)
+	}
+	return result
+}
+
+func redactEntry(entry BlackboardEntry) BlackboardEntry {
+	if entry.Sensitive {
+		entry.Value = RedactedValue
+	}
+	if entry.PromotedFrom != nil {
+		from := redactEntry(*entry.PromotedFrom)
+		entry.PromotedFrom = &from
+	}
+	return entry
+}
+
+// markLineageSensitive returns entry and the entries it was promoted from,
+// all marked sensitive.
+func markLineageSensitive(entry BlackboardEntry) BlackboardEntry {
+	entry.Sensitive = true
+	if entry.PromotedFrom != nil {
+		from := markLineageSensitive(*entry.PromotedFrom)
+		entry.PromotedFrom = &from
+	}
+	return entry
+}
+
+// isSensitiveKey reports whether key matches any of the given patterns.
+// Patterns are validated at registration, so match errors are ignored.
+func isSensitiveKey(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// markSensitive returns writes with Sensitive set on every write whose key
+// matches one of patterns. writes is returned unchanged if nothing matches.
+func markSensitive(writes []BlackboardWrite, patterns []string) []BlackboardWrite {
+	if len(patterns) == 0 {
+		return writes
+	}
+	var marked []BlackboardWrite
+	for i, w := range writes {
+		if w.Sensitive || !isSensitiveKey(patterns, w.Key) {
+			continue
+		}
+		if marked == nil {
+			marked = make([]BlackboardWrite, len(writes))
+			copy(marked, writes)
+		}
+		marked[i].Sensitive = true
+	}
+	if marked == nil {
+		return writes
+	}
+	return marked
 }
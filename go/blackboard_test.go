@@ -205,6 +205,43 @@ func TestBlackboardReaderThreeScopes(t *testing.T) {
 	})
 }
 
+// ---------------------------------------------------------------------------
+// ScopedBlackboardReader — lineage
+// ---------------------------------------------------------------------------
+
+func TestBlackboardReaderLineage(t *testing.T) {
+	origin := bbEntryWithSource("out", "v", "grandchild", "GC_END", 2)
+	hop := bbEntryWithSource("mid", "v", "child", "C_INVOKE", 1)
+	hop.PromotedFrom = &origin
+	top := bbEntryWithSource("result", "v", "parent", "P_INVOKE", 0)
+	top.PromotedFrom = &hop
+	reader := NewBlackboardReader([][]BlackboardEntry{{top, bbEntry("plain", 1)}}).(LineageReader)
+
+	t.Run("walks every promotion back to the original write", func(t *testing.T) {
+		lineage := reader.Lineage("result")
+		if len(lineage) != 3 {
+			t.Fatalf("expected 3 entries, got %d", len(lineage))
+		}
+		want := []string{"P_INVOKE", "C_INVOKE", "GC_END"}
+		for i, nodeID := range want {
+			if lineage[i].Source.NodeID != nodeID {
+				t.Errorf("lineage[%d]: expected %s, got %s", i, nodeID, lineage[i].Source.NodeID)
+			}
+		}
+	})
+	t.Run("unpromoted entry is its own lineage", func(t *testing.T) {
+		lineage := reader.Lineage("plain")
+		if len(lineage) != 1 || lineage[0].Key != "plain" {
+			t.Errorf("expected single entry, got %v", lineage)
+		}
+	})
+	t.Run("missing key returns nil", func(t *testing.T) {
+		if reader.Lineage("missing") != nil {
+			t.Error("expected nil lineage")
+		}
+	})
+}
+
 // ---------------------------------------------------------------------------
 // ScopedBlackboard — write side
 // ---------------------------------------------------------------------------
@@ -250,6 +287,70 @@ func TestBlackboardAppend(t *testing.T) {
 	})
 }
 
+// ---------------------------------------------------------------------------
+// ScopedBlackboard — value modes
+// ---------------------------------------------------------------------------
+
+func TestBlackboardAppendValueMode(t *testing.T) {
+	source := BlackboardSource{WorkflowID: "wf", NodeID: "n", StackDepth: 0}
+
+	t.Run("reference mode shares written containers", func(t *testing.T) {
+		bb := NewBlackboard()
+		inventory := []any{"sword"}
+		bb.Append([]BlackboardWrite{{Key: "inventory", Value: inventory}}, source)
+		inventory[0] = "stick"
+		v, _ := bb.Reader().Get("inventory")
+		if v.([]any)[0] != "stick" {
+			t.Error("expected reference mode to observe the mutation")
+		}
+	})
+	t.Run("copy mode preserves history", func(t *testing.T) {
+		bb := NewBlackboardWithMode(ValueModeCopy)
+		inventory := map[string]any{"weapon": "sword"}
+		bb.Append([]BlackboardWrite{{Key: "inventory", Value: inventory}}, source)
+		inventory["weapon"] = "stick"
+		v, _ := bb.Reader().Get("inventory")
+		if v.(map[string]any)["weapon"] != "sword" {
+			t.Errorf("expected history unchanged, got %v", v)
+		}
+	})
+	t.Run("copy mode hands out copies", func(t *testing.T) {
+		bb := NewBlackboardWithMode(ValueModeCopy)
+		entries := bb.Append([]BlackboardWrite{{Key: "inventory", Value: map[string]any{"weapon": "sword"}}}, source)
+		entries[0].Value.(map[string]any)["weapon"] = "stick"
+		v, _ := bb.Reader().Get("inventory")
+		v.(map[string]any)["weapon"] = "axe"
+		bb.Entries()[0].Value.(map[string]any)["weapon"] = "club"
+		if v, _ := bb.Reader().Get("inventory"); v.(map[string]any)["weapon"] != "sword" {
+			t.Errorf("expected history unchanged, got %v", v)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Sensitive entries — redaction
+// ---------------------------------------------------------------------------
+
+func TestRedactEntries(t *testing.T) {
+	origin := bbEntry("token", "secret")
+	origin.Sensitive = true
+	promoted := bbEntry("session", "secret")
+	promoted.Sensitive = true
+	promoted.PromotedFrom = &origin
+	entries := []BlackboardEntry{promoted, bbEntry("user", "alice")}
+
+	redacted := RedactEntries(entries)
+	if redacted[0].Value != RedactedValue || redacted[0].PromotedFrom.Value != RedactedValue {
+		t.Errorf("expected redacted promotion chain, got %v / %v", redacted[0].Value, redacted[0].PromotedFrom.Value)
+	}
+	if redacted[1].Value != "alice" {
+		t.Errorf("expected non-sensitive value kept, got %v", redacted[1].Value)
+	}
+	if entries[0].Value != "secret" || origin.Value != "secret" {
+		t.Error("RedactEntries must not modify its input")
+	}
+}
+
 // ---------------------------------------------------------------------------
 // ScopedBlackboard — initial entries
 // ---------------------------------------------------------------------------
@@ -0,0 +1,241 @@
+package reflex
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ---------------------------------------------------------------------------
+// Workflow builder
+// ---------------------------------------------------------------------------
+
+// DefaultEvent is the event name given to edges built without On.
+const DefaultEvent = "NEXT"
+
+// WorkflowBuilder assembles a Workflow with a fluent API. Node IDs are given
+// once, edge IDs are derived from the edge ("FROM->TO", or "FROM->TO:EVENT"
+// for events other than DefaultEvent) unless EdgeID names one, and Build
+// reports every structural problem at once:
+//
+//	w, err := reflex.NewWorkflow("combat").
+//		Node("ENCOUNTER", reflex.NodeSpec{"autoAdvance": true}).
+//		Node("PLAYER_TURN", nil).Invoke("attack", reflex.Return("damage", "damage")).
+//		Node("VICTORY", reflex.NodeSpec{"complete": true}).
+//		Edge("ENCOUNTER", "PLAYER_TURN").
+//		Edge("PLAYER_TURN", "VICTORY").On("WIN").When(reflex.Exists("damage")).
+//		Build()
+//
+// Describe and Invoke must directly follow the Node they apply to; On, When
+// and EdgeID must directly follow their Edge. The first node added is the
+// entry unless Entry names another.
+type WorkflowBuilder struct {
+	w         *Workflow
+	lastNode  *Node // nil unless the previous call added or modified a node
+	lastEdge  int   // -1 unless the previous call added or modified an edge
+	edgeNamed bool  // the edge at lastEdge has an explicit ID
+	problems  ValidationErrors
+}
+
+// NewWorkflow starts building a workflow with the given ID.
+func NewWorkflow(id string) *WorkflowBuilder {
+	return &WorkflowBuilder{
+		w:        &Workflow{ID: id, Nodes: make(map[string]*Node)},
+		lastEdge: -1,
+	}
+}
+
+// Return builds a ReturnMapping that copies the child's childKey into the
+// parent's parentKey. Use it with WorkflowBuilder.Invoke.
+func Return(parentKey, childKey string) ReturnMapping {
+	return ReturnMapping{ParentKey: parentKey, ChildKey: childKey}
+}
+
+// Entry sets the entry node.
+func (b *WorkflowBuilder) Entry(nodeID string) *WorkflowBuilder {
+	b.w.Entry = nodeID
+	return b
+}
+
+// Version sets the workflow version.
+func (b *WorkflowBuilder) Version(v int) *WorkflowBuilder {
+	b.w.Version = v
+	return b
+}
+
+// Metadata sets one metadata entry.
+func (b *WorkflowBuilder) Metadata(key string, value any) *WorkflowBuilder {
+	if b.w.Metadata == nil {
+		b.w.Metadata = make(map[string]any)
+	}
+	b.w.Metadata[key] = value
+	return b
+}
+
+// Sensitive adds blackboard key patterns to Workflow.SensitiveKeys.
+func (b *WorkflowBuilder) Sensitive(patterns ...string) *WorkflowBuilder {
+	b.w.SensitiveKeys = append(b.w.SensitiveKeys, patterns...)
+	return b
+}
+
+// Node adds a node. A nil spec is stored as an empty NodeSpec.
+func (b *WorkflowBuilder) Node(id string, spec NodeSpec) *WorkflowBuilder {
+	if _, exists := b.w.Nodes[id]; exists {
+		b.misuse(ErrDuplicateNodeID, map[string]any{"nodeId": id},
+			"node '%s' is declared more than once", id)
+		b.lastNode = b.w.Nodes[id]
+		b.lastEdge = -1
+		return b
+	}
+	if spec == nil {
+		spec = NodeSpec{}
+	}
+	node := &Node{ID: id, Spec: spec}
+	b.w.Nodes[id] = node
+	b.lastNode = node
+	b.lastEdge = -1
+	if b.w.Entry == "" {
+		b.w.Entry = id
+	}
+	return b
+}
+
+// Describe sets the description of the node added by the preceding Node.
+func (b *WorkflowBuilder) Describe(description string) *WorkflowBuilder {
+	if b.lastNode == nil {
+		b.misuse(ErrBuilderUsage, nil, "Describe must follow the Node it describes")
+		return b
+	}
+	b.lastNode.Description = description
+	return b
+}
+
+// Invoke makes the node added by the preceding Node an invocation of
+// workflowRef.
+func (b *WorkflowBuilder) Invoke(workflowRef string, returnMap ...ReturnMapping) *WorkflowBuilder {
+	if b.lastNode == nil {
+		b.misuse(ErrBuilderUsage, nil, "Invoke must follow the Node it applies to")
+		return b
+	}
+	b.lastNode.Invokes = &InvocationSpec{WorkflowID: workflowRef, ReturnMap: returnMap}
+	return b
+}
+
+// Edge adds an edge from one node to another with DefaultEvent and no guard.
+func (b *WorkflowBuilder) Edge(from, to string) *WorkflowBuilder {
+	b.w.Edges = append(b.w.Edges, Edge{
+		ID:    derivedEdgeID(from, to, DefaultEvent),
+		From:  from,
+		To:    to,
+		Event: DefaultEvent,
+	})
+	b.lastEdge = len(b.w.Edges) - 1
+	b.edgeNamed = false
+	b.lastNode = nil
+	return b
+}
+
+// EdgeID sets the ID of the edge added by the preceding Edge, replacing the
+// derived one. Parallel edges with the same endpoints and event need it.
+func (b *WorkflowBuilder) EdgeID(id string) *WorkflowBuilder {
+	if b.lastEdge < 0 {
+		b.misuse(ErrBuilderUsage, nil, "EdgeID must follow the Edge it names")
+		return b
+	}
+	b.w.Edges[b.lastEdge].ID = id
+	b.edgeNamed = true
+	return b
+}
+
+// On sets the event of the edge added by the preceding Edge. A derived ID is
+// updated to include the event.
+func (b *WorkflowBuilder) On(event string) *WorkflowBuilder {
+	if b.lastEdge < 0 {
+		b.misuse(ErrBuilderUsage, nil, "On must follow the Edge it applies to")
+		return b
+	}
+	edge := &b.w.Edges[b.lastEdge]
+	edge.Event = event
+	if !b.edgeNamed {
+		edge.ID = derivedEdgeID(edge.From, edge.To, event)
+	}
+	return b
+}
+
+// When sets the guard of the edge added by the preceding Edge.
+func (b *WorkflowBuilder) When(guard Guard) *WorkflowBuilder {
+	if b.lastEdge < 0 {
+		b.misuse(ErrBuilderUsage, nil, "When must follow the Edge it applies to")
+		return b
+	}
+	b.w.Edges[b.lastEdge].Guard = guard
+	return b
+}
+
+// Build returns the workflow, or a ValidationErrors holding every builder
+// misuse and every error the registry's structural checks find. Checks that
+// need other workflows, such as invocation targets, and custom validators are
+// left to Register; use BuildFor to run them against a registry. Each call
+// returns a new copy, so the builder can go on to build variants without
+// changing workflows it already returned.
+func (b *WorkflowBuilder) Build() (*Workflow, error) {
+	return b.build(NewRegistry(), false)
+}
+
+// BuildFor is like Build but checks the workflow as r.Check would, including
+// r's custom validators. Invocations of workflows or templates r does not
+// have, which Register only warns about, are errors here. The workflow is not
+// registered.
+func (b *WorkflowBuilder) BuildFor(r *Registry) (*Workflow, error) {
+	return b.build(r, true)
+}
+
+func (b *WorkflowBuilder) build(r *Registry, targets bool) (*Workflow, error) {
+	problems := append(ValidationErrors{}, b.problems...)
+	checked := r.Check(b.w)
+	problems = append(problems, checked.Errors()...)
+	if targets {
+		for _, warning := range checked.Warnings() {
+			if warning.Code == ErrInvocationNotFound {
+				problem := *warning
+				problem.Severity = SeverityError
+				problems = append(problems, &problem)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return b.copy(), nil
+}
+
+// copy returns a copy of the workflow being built that later builder calls
+// do not reach.
+func (b *WorkflowBuilder) copy() *Workflow {
+	w := *b.w
+	w.Nodes = make(map[string]*Node, len(b.w.Nodes))
+	for id, node := range b.w.Nodes {
+		w.Nodes[id] = cloneNode(node)
+	}
+	w.Edges = slices.Clone(b.w.Edges)
+	w.Metadata = maps.Clone(b.w.Metadata)
+	w.SensitiveKeys = slices.Clone(b.w.SensitiveKeys)
+	w.Outputs = slices.Clone(b.w.Outputs)
+	return &w
+}
+
+// derivedEdgeID names an edge after its endpoints and, unless it is
+// DefaultEvent, its event.
+func derivedEdgeID(from, to, event string) string {
+	if event == DefaultEvent {
+		return from + "->" + to
+	}
+	return from + "->" + to + ":" + event
+}
+
+func (b *WorkflowBuilder) misuse(code ValidationErrorCode, details map[string]any, format string, args ...any) {
+	problem := newValidationError(code, b.w.ID,
+		fmt.Sprintf("workflow '%s': ", b.w.ID)+fmt.Sprintf(format, args...))
+	problem.Details = details
+	b.problems = append(b.problems, problem)
+}
@@ -0,0 +1,207 @@
+package reflex
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWorkflowBuilderBuild(t *testing.T) {
+	w, err := NewWorkflow("combat").
+		Version(2).
+		Node("ENCOUNTER", nil).Describe("an enemy appears").
+		Node("PLAYER_TURN", NodeSpec{"x": 1}).Invoke("attack", Return("damage", "dealt")).
+		Node("VICTORY", NodeSpec{"complete": true}).
+		Edge("ENCOUNTER", "PLAYER_TURN").
+		Edge("PLAYER_TURN", "VICTORY").On("WIN").When(Equals("damage", 10)).
+		Build()
+	if err != nil {
+		t.Fatalf("expected workflow to build: %v", err)
+	}
+
+	if w.Entry != "ENCOUNTER" || w.Version != 2 {
+		t.Errorf("expected entry ENCOUNTER version 2, got %s version %d", w.Entry, w.Version)
+	}
+	for id, node := range w.Nodes {
+		if node.ID != id {
+			t.Errorf("node key %s holds node %s", id, node.ID)
+		}
+	}
+	if w.Nodes["ENCOUNTER"].Description != "an enemy appears" || w.Nodes["ENCOUNTER"].Spec == nil {
+		t.Errorf("unexpected ENCOUNTER node: %+v", w.Nodes["ENCOUNTER"])
+	}
+	want := &InvocationSpec{WorkflowID: "attack", ReturnMap: []ReturnMapping{{ParentKey: "damage", ChildKey: "dealt"}}}
+	if !reflect.DeepEqual(w.Nodes["PLAYER_TURN"].Invokes, want) {
+		t.Errorf("expected %+v, got %+v", want, w.Nodes["PLAYER_TURN"].Invokes)
+	}
+	wantEdges := []Edge{
+		{ID: "ENCOUNTER->PLAYER_TURN", From: "ENCOUNTER", To: "PLAYER_TURN", Event: DefaultEvent},
+		{ID: "PLAYER_TURN->VICTORY:WIN", From: "PLAYER_TURN", To: "VICTORY", Event: "WIN", Guard: Equals("damage", 10)},
+	}
+	if !reflect.DeepEqual(w.Edges, wantEdges) {
+		t.Errorf("expected edges %+v, got %+v", wantEdges, w.Edges)
+	}
+}
+
+func TestWorkflowBuilderEdgeIDs(t *testing.T) {
+	w, err := NewWorkflow("ids").
+		Node("A", nil).Node("B", nil).
+		Edge("A", "B").EdgeID("primary").On("GO").
+		Edge("A", "B").On("GO").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Edges[0].ID != "primary" || w.Edges[1].ID != "A->B:GO" {
+		t.Errorf("unexpected edge IDs %q, %q", w.Edges[0].ID, w.Edges[1].ID)
+	}
+
+	_, err = NewWorkflow("dup").
+		Node("A", nil).Node("B", nil).
+		Edge("A", "B").Edge("A", "B").
+		Build()
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code != ErrDuplicateEdgeID {
+		t.Errorf("expected %s for parallel edges, got %v", ErrDuplicateEdgeID, err)
+	}
+}
+
+func TestWorkflowBuilderChainOrder(t *testing.T) {
+	// Invoke and Describe after an Edge would otherwise land on whichever
+	// node happened to be added last.
+	b := NewWorkflow("order").
+		Node("A", nil).Node("B", nil).
+		Edge("A", "B").Invoke("child").Describe("late").
+		Node("C", nil).When(Exists("k"))
+	_, err := b.Build()
+	errs := validationErrors(t, err)
+	usage := 0
+	for _, ve := range errs {
+		if ve.Code == ErrBuilderUsage {
+			usage++
+		}
+	}
+	if usage != 3 {
+		t.Errorf("expected 3 usage errors, got %v", errs)
+	}
+	if b.w.Nodes["B"].Invokes != nil || b.w.Nodes["B"].Description != "" {
+		t.Errorf("expected B untouched, got %+v", b.w.Nodes["B"])
+	}
+}
+
+func TestWorkflowBuilderRuns(t *testing.T) {
+	w, err := NewWorkflow("built").
+		Node("A", nil).Node("B", nil).Node("C", nil).
+		Edge("A", "B").Edge("B", "C").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	if err := r.Register(w); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(r, autoAdvanceAgent())
+	_, _ = e.Init("built")
+	result, err := e.Run(context.Background())
+	if err != nil || result.Status != StepCompleted {
+		t.Fatalf("expected completion, got %+v, %v", result, err)
+	}
+}
+
+func TestWorkflowBuilderReportsAllErrors(t *testing.T) {
+	_, err := NewWorkflow("broken").
+		When(Exists("k")).
+		Entry("MISSING").
+		Node("A", nil).
+		Node("A", nil).
+		Node("B", nil).
+		Edge("A", "B").
+		Edge("B", "A").
+		Edge("A", "NOWHERE").
+		Build()
+	if err == nil {
+		t.Fatal("expected build to fail")
+	}
+
+	codes := make(map[ValidationErrorCode]bool)
+	for _, ve := range validationErrors(t, err) {
+		codes[ve.Code] = true
+	}
+	for _, code := range []ValidationErrorCode{ErrBuilderUsage, ErrDuplicateNodeID, ErrInvalidEntryNode, ErrInvalidEdge, ErrNoTerminalNodes} {
+		if !codes[code] {
+			t.Errorf("expected %s among %v", code, err)
+		}
+	}
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Error("expected errors.As to reach a *ValidationError")
+	}
+}
+
+func TestWorkflowBuilderBuildFor(t *testing.T) {
+	r := NewRegistry()
+	r.AddValidator(func(w *Workflow) error {
+		if w.Metadata["owner"] == nil {
+			return errors.New("owner metadata is required")
+		}
+		return nil
+	})
+
+	b := NewWorkflow("owned").Node("A", nil).Node("B", nil).Edge("A", "B")
+	if _, err := b.Build(); err != nil {
+		t.Fatalf("expected Build to skip custom validators: %v", err)
+	}
+	_, err := b.BuildFor(r)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code != ErrCustomValidation {
+		t.Fatalf("expected %s, got %v", ErrCustomValidation, err)
+	}
+	if _, err := b.Metadata("owner", "ops").BuildFor(r); err != nil {
+		t.Fatalf("expected workflow to pass: %v", err)
+	}
+	if r.Has("owned") {
+		t.Error("BuildFor must not register the workflow")
+	}
+}
+
+func TestWorkflowBuilderBuildForTargets(t *testing.T) {
+	r := NewRegistry()
+	b := NewWorkflow("caller").Node("A", nil).Invoke("child").Node("B", nil).Edge("A", "B")
+	if _, err := b.Build(); err != nil {
+		t.Fatalf("expected Build to leave invocation targets to Register: %v", err)
+	}
+	_, err := b.BuildFor(r)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code != ErrInvocationNotFound || ve.Severity != SeverityError {
+		t.Fatalf("expected %s as an error, got %v", ErrInvocationNotFound, err)
+	}
+	_ = r.Register(linearWorkflow("child"))
+	if _, err := b.BuildFor(r); err != nil {
+		t.Errorf("expected the registered target to pass: %v", err)
+	}
+}
+
+func TestWorkflowBuilderReturnsCopies(t *testing.T) {
+	b := NewWorkflow("wf").Node("A", NodeSpec{"x": 1}).Node("B", nil).Edge("A", "B")
+	first, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := b.Node("C", nil).Describe("added").Edge("B", "C").Metadata("k", "v").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Nodes) != 2 || len(first.Edges) != 1 || first.Metadata != nil {
+		t.Errorf("expected the first workflow unchanged, got %+v", first)
+	}
+	if second.Nodes["C"].Description != "added" || len(second.Nodes) != 3 {
+		t.Errorf("expected the second workflow to have the changes, got %+v", second)
+	}
+	second.Nodes["A"].Spec["x"] = 2
+	if first.Nodes["A"].Spec["x"] != 1 {
+		t.Error("expected built workflows not to share specs")
+	}
+}
@@ -0,0 +1,203 @@
+// Command reflex-gen generates Go constants for the workflow, node, edge and
+// blackboard key IDs in reflex workflow files. It is meant for go generate:
+//
+//	//go:generate go run github.com/corpus-relica/reflex/go/cmd/reflex-gen -o flows_ids.go workflows/*.json
+//
+// Each argument is a glob pattern in fs.Glob syntax; wildcards may appear in
+// any path element, as in workflows/*/flow.json. Every pattern must match a
+// file. All patterns are loaded with a single Registry.LoadFS call, rooted at
+// the current directory or, for patterns outside it, their common ancestor,
+// so workflows are registered in dependency order across patterns and
+// nothing is registered if any file fails. The package defaults to
+// $GOPACKAGE, which go
+// generate sets. Custom guards referenced by the files must be named with
+// -guards; they are never evaluated. With -skeleton, a DecisionAgent with an
+// exhaustive switch over every workflow and node is written to that file if
+// it does not exist yet.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	reflex "github.com/corpus-relica/reflex/go"
+	"github.com/corpus-relica/reflex/go/codegen"
+)
+
+func main() {
+	output := flag.String("o", "", "output file for the constants (default stdout)")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated files")
+	skeleton := flag.String("skeleton", "", "write a DecisionAgent skeleton to this file unless it exists")
+	agent := flag.String("agent", "Agent", "type name of the skeleton agent")
+	guards := flag.String("guards", "", "comma-separated names of custom guards the files reference")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: reflex-gen [flags] pattern...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Args(), *output, *skeleton, *guards, codegen.Options{
+		Package: *pkg,
+		Source:  strings.Join(flag.Args(), " "),
+		Agent:   *agent,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "reflex-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(patterns []string, output, skeleton, guards string, opts codegen.Options) error {
+	registry := reflex.NewRegistry(reflex.RegistryOptions{Guards: placeholderGuards(guards)})
+	fsys, err := loadFS(patterns)
+	if err != nil {
+		return err
+	}
+	if err := registry.LoadFS(fsys, "*"); err != nil {
+		return err
+	}
+	workflows := codegen.Workflows(registry)
+
+	src, err := codegen.Generate(workflows, opts)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = codegen.WriteFile(output, src)
+	}
+	if err != nil {
+		return err
+	}
+
+	if skeleton != "" {
+		src, err := codegen.GenerateSkeleton(workflows, opts)
+		if err != nil {
+			return err
+		}
+		return codegen.WriteSkeleton(skeleton, src)
+	}
+	return nil
+}
+
+// patternFS is a file system whose Glob ignores its argument and returns the
+// matches of every pattern, so that one LoadFS call loads them all.
+type patternFS struct {
+	fs.FS
+	patterns []string // relative to the root of FS
+	sources  []string // the patterns as given, for error messages
+}
+
+func (f *patternFS) Glob(string) ([]string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	for i, pattern := range f.patterns {
+		matches, err := fs.Glob(f.FS, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", f.sources[i], err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("load %q: no files match", f.sources[i])
+		}
+		for _, path := range matches {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// loadFS roots the patterns at the current directory, or at the deepest
+// directory holding it and every pattern's leading directories, and
+// rewrites them relative to that root.
+func loadFS(patterns []string) (*patternFS, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, len(patterns))
+	globs := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		dir, glob := splitPattern(pattern)
+		if dirs[i], err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+		globs[i] = glob
+		root = commonDir(root, dirs[i])
+	}
+	if root == "" {
+		return nil, fmt.Errorf("patterns %q share no root directory", patterns)
+	}
+	f := &patternFS{FS: os.DirFS(root), sources: patterns}
+	for i, dir := range dirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		f.patterns = append(f.patterns, path.Join(filepath.ToSlash(rel), globs[i]))
+	}
+	return f, nil
+}
+
+// commonDir returns the deepest directory holding both absolute, clean
+// directories a and b, or "" if they are on different volumes.
+func commonDir(a, b string) string {
+	if filepath.VolumeName(a) != filepath.VolumeName(b) {
+		return ""
+	}
+	for {
+		if rel, err := filepath.Rel(a, b); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+}
+
+// splitPattern splits pattern into its leading directories without glob
+// metacharacters and the remaining, slash-separated pattern relative to them.
+func splitPattern(pattern string) (dir, glob string) {
+	elems := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	i := 0
+	for i < len(elems)-1 && !strings.ContainsAny(elems[i], `*?[\`) {
+		i++
+	}
+	dir = strings.Join(elems[:i], "/")
+	switch {
+	case i == 1 && elems[0] == "":
+		dir = "/"
+	case dir == "":
+		dir = "."
+	}
+	return filepath.FromSlash(dir), strings.Join(elems[i:], "/")
+}
+
+// placeholderGuards stands in for the custom guards named in a
+// comma-separated list, so that files referencing them load.
+func placeholderGuards(names string) map[string]reflex.Guard {
+	guards := make(map[string]reflex.Guard)
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			guards[name] = &reflex.CustomGuardFunc{Name: name, Fn: func(reflex.BlackboardReader) (bool, error) {
+				return false, nil
+			}}
+		}
+	}
+	return guards
+}
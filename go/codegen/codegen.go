@@ -0,0 +1,402 @@
+// Package codegen generates Go constants for the IDs in reflex workflow
+// definitions, so that agents switch on checked identifiers instead of string
+// literals. The reflex-gen command runs it on workflow files; programs that
+// build a registry in Go can call it directly:
+//
+//	//go:generate go run ./internal/gen
+//
+//	func main() {
+//		src, err := codegen.Generate(codegen.Workflows(flows.Registry()), codegen.Options{Package: "flows"})
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		if err := codegen.WriteFile("flows_ids.go", src); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	reflex "github.com/corpus-relica/reflex/go"
+)
+
+// Options configures Generate and GenerateSkeleton.
+type Options struct {
+	// Package is the package clause of the generated file.
+	Package string
+	// Source describes where the definitions came from, for the header
+	// comment.
+	Source string
+	// Agent names the skeleton's agent type. Defaults to "Agent".
+	Agent string
+}
+
+// Workflows returns every registered version of every workflow in r, in ID
+// then version order.
+func Workflows(r *reflex.Registry) []*reflex.Workflow {
+	var result []*reflex.Workflow
+	for _, id := range r.List() {
+		for _, v := range r.Versions(id) {
+			if w, ok := r.Get(fmt.Sprintf("%s@%d", id, v)); ok {
+				result = append(result, w)
+			}
+		}
+	}
+	return result
+}
+
+// group is the definitions sharing one name: the versions of a workflow, or
+// the instances of a template, which share their node and edge IDs.
+type group struct {
+	id        string // workflow or template ID
+	name      string // Go identifier derived from id
+	workflows []*reflex.Workflow
+	nodes     []constant
+	edges     []constant
+}
+
+type constant struct {
+	name  string
+	value string
+}
+
+// groupWorkflows groups workflows by ID, template instances by their
+// template's ID. Nodes and edges are the union over the group, so an agent can
+// still handle sessions on older versions.
+func groupWorkflows(workflows []*reflex.Workflow) ([]*group, error) {
+	byID := make(map[string]*group)
+	for _, w := range workflows {
+		id := w.ID
+		if template, ok := w.Metadata["template"].(string); ok {
+			id = template
+		}
+		g, ok := byID[id]
+		if !ok {
+			g = &group{id: id, name: identifier(id)}
+			byID[id] = g
+		}
+		g.workflows = append(g.workflows, w)
+	}
+
+	var groups []*group
+	for _, g := range byID {
+		nodes := make(map[string]bool)
+		edges := make(map[string]bool)
+		for _, w := range g.workflows {
+			for id := range w.Nodes {
+				nodes[id] = true
+			}
+			for _, e := range w.Edges {
+				edges[e.ID] = true
+			}
+		}
+		var err error
+		if g.nodes, err = constants(g.name+"Node", nodes); err != nil {
+			return nil, fmt.Errorf("workflow '%s': %w", g.id, err)
+		}
+		if g.edges, err = constants(g.name+"Edge", edges); err != nil {
+			return nil, fmt.Errorf("workflow '%s': %w", g.id, err)
+		}
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].id < groups[j].id })
+	return groups, nil
+}
+
+// constants names each value with prefix and its identifier, sorted by name.
+// Two values mapping to one name are an error.
+func constants(prefix string, values map[string]bool) ([]constant, error) {
+	byName := make(map[string]string, len(values))
+	var result []constant
+	for value := range values {
+		name := prefix + identifier(value)
+		if other, ok := byName[name]; ok {
+			a, b := min(value, other), max(value, other)
+			return nil, fmt.Errorf("'%s' and '%s' both map to %s", a, b, name)
+		}
+		byName[name] = value
+		result = append(result, constant{name: name, value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
+
+// declarations returns the groups and blackboard key constants Generate
+// declares, and every package-level name they declare with a description
+// of its origin. Names are checked across categories too: blackboard key
+// "node" and workflow "key" would both declare KeyNode.
+func declarations(workflows []*reflex.Workflow) ([]*group, []constant, map[string]string, error) {
+	groups, err := groupWorkflows(workflows)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("codegen: %w", err)
+	}
+	keys, err := constants("Key", contractKeys(workflows))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("codegen: blackboard keys: %w", err)
+	}
+
+	names := map[string]string{"WorkflowID": "type WorkflowID"}
+	var clash error
+	declare := func(name, origin string) {
+		if other, ok := names[name]; ok && clash == nil {
+			clash = fmt.Errorf("codegen: %s and %s both map to %s", other, origin, name)
+		}
+		names[name] = origin
+	}
+	for _, g := range groups {
+		declare("Workflow"+g.name, fmt.Sprintf("workflow '%s'", g.id))
+	}
+	for _, g := range groups {
+		for _, set := range []struct {
+			kind   string
+			consts []constant
+		}{{"Node", g.nodes}, {"Edge", g.edges}} {
+			if len(set.consts) == 0 {
+				continue
+			}
+			declare(g.name+set.kind, fmt.Sprintf("the %s type of workflow '%s'", strings.ToLower(set.kind), g.id))
+			for _, c := range set.consts {
+				declare(c.name, fmt.Sprintf("%s '%s' of workflow '%s'", strings.ToLower(set.kind), c.value, g.id))
+			}
+		}
+	}
+	if len(keys) > 0 {
+		declare("BlackboardKey", "type BlackboardKey")
+	}
+	for _, c := range keys {
+		declare(c.name, fmt.Sprintf("blackboard key '%s'", c.value))
+	}
+	if clash != nil {
+		return nil, nil, nil, clash
+	}
+	return groups, keys, names, nil
+}
+
+// contractKeys collects the blackboard keys declared in node contracts and
+// workflow outputs.
+func contractKeys(workflows []*reflex.Workflow) map[string]bool {
+	keys := make(map[string]bool)
+	for _, w := range workflows {
+		for _, k := range w.OutputKeys() {
+			keys[k] = true
+		}
+		for _, node := range w.Nodes {
+			for _, in := range node.Inputs {
+				keys[in.Key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// identifier turns an ID such as "dungeon-crawl", "GUARD_ROOM" or
+// "playerHp" into an exported Go identifier part: DungeonCrawl, GuardRoom,
+// PlayerHp. Words split at characters that are not letters or digits; all
+// upper-case words are title-cased. A result starting with a digit gets an
+// "X" prefix.
+func identifier(id string) string {
+	var b strings.Builder
+	words := strings.FieldsFunc(id, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		runes := []rune(word)
+		if strings.ToUpper(word) == word {
+			runes = []rune(strings.ToLower(word))
+		}
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// Generate returns gofmt-formatted Go source declaring:
+//
+//   - a WorkflowID type with a Workflow<Name> constant per workflow ID,
+//   - per workflow, <Name>Node and <Name>Edge types with a constant per node
+//     and edge ID,
+//   - a BlackboardKey type with a Key<Name> constant per key declared in node
+//     contracts or Workflow.Outputs.
+//
+// Types without constants are left out.
+//
+// Template instances are named after their template. Conversions such as
+// CombatNode(dc.Node.ID) let linters check switches for exhaustiveness.
+// Returns an error if two IDs map to the same identifier, within a category
+// or across them.
+func Generate(workflows []*reflex.Workflow, opts Options) ([]byte, error) {
+	if !token.IsIdentifier(opts.Package) {
+		return nil, fmt.Errorf("codegen: invalid package name %q", opts.Package)
+	}
+	groups, keys, _, err := declarations(workflows)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	writeHeader(&b, opts)
+	fmt.Fprintf(&b, "package %s\n\n", opts.Package)
+
+	b.WriteString("// WorkflowID identifies a workflow, or the template of a template instance.\n")
+	b.WriteString("type WorkflowID string\n\n")
+	if len(groups) > 0 {
+		b.WriteString("const (\n")
+		for _, g := range groups {
+			fmt.Fprintf(&b, "Workflow%s WorkflowID = %q\n", g.name, g.id)
+		}
+		b.WriteString(")\n\n")
+	}
+
+	for _, g := range groups {
+		writeConstants(&b, g.name+"Node", "a node ID of "+g.id, g.nodes)
+		writeConstants(&b, g.name+"Edge", "an edge ID of "+g.id, g.edges)
+	}
+	writeConstants(&b, "BlackboardKey", "a blackboard key declared in a node contract or workflow outputs", keys)
+	return formatSource(b.Bytes())
+}
+
+// GenerateSkeleton returns Go source for a DecisionAgent with an exhaustive
+// switch over every workflow and node of the constants Generate declares, to
+// be copied or edited by hand. Terminal nodes complete; other nodes advance
+// along their first edge, with the alternatives listed in a comment.
+func GenerateSkeleton(workflows []*reflex.Workflow, opts Options) ([]byte, error) {
+	if !token.IsIdentifier(opts.Package) {
+		return nil, fmt.Errorf("codegen: invalid package name %q", opts.Package)
+	}
+	agent := opts.Agent
+	if agent == "" {
+		agent = "Agent"
+	}
+	groups, _, names, err := declarations(workflows)
+	if err != nil {
+		return nil, err
+	}
+	if other, ok := names[agent]; ok {
+		return nil, fmt.Errorf("codegen: agent type %s clashes with %s", agent, other)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "package %s\n\n", opts.Package)
+	b.WriteString("import (\n\"context\"\n\"fmt\"\n\nreflex \"github.com/corpus-relica/reflex/go\"\n)\n\n")
+	fmt.Fprintf(&b, "// %s is a DecisionAgent skeleton generated by reflex-gen.\ntype %s struct{}\n\n", agent, agent)
+	fmt.Fprintf(&b, "// Resolve implements reflex.DecisionAgent. Template instances are dispatched\n// by the template they came from.\n")
+	fmt.Fprintf(&b, "func (a *%s) Resolve(ctx context.Context, dc reflex.DecisionContext) (reflex.Decision, error) {\n", agent)
+	b.WriteString("workflowID := dc.Workflow.ID\n")
+	b.WriteString("if template, ok := dc.Workflow.Metadata[\"template\"].(string); ok {\nworkflowID = template\n}\n")
+	b.WriteString("switch WorkflowID(workflowID) {\n")
+	for _, g := range groups {
+		fmt.Fprintf(&b, "case Workflow%s:\nreturn a.resolve%s(ctx, dc)\n", g.name, g.name)
+	}
+	b.WriteString("}\nreturn reflex.Decision{}, fmt.Errorf(\"unknown workflow %q\", dc.Workflow.ID)\n}\n")
+
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\nfunc (a *%s) resolve%s(ctx context.Context, dc reflex.DecisionContext) (reflex.Decision, error) {\n", agent, g.name)
+		fmt.Fprintf(&b, "switch %sNode(dc.Node.ID) {\n", g.name)
+		for _, n := range g.nodes {
+			fmt.Fprintf(&b, "case %s:\n", n.name)
+			writeSkeletonCase(&b, g, n.value)
+		}
+		fmt.Fprintf(&b, "}\nreturn reflex.Decision{}, fmt.Errorf(\"%s: unknown node %%q\", dc.Node.ID)\n}\n", g.id)
+	}
+	return formatSource(b.Bytes())
+}
+
+// writeSkeletonCase writes the body of one node's case, using the newest
+// version that has the node.
+func writeSkeletonCase(b *bytes.Buffer, g *group, nodeID string) {
+	var w *reflex.Workflow
+	for i := len(g.workflows) - 1; i >= 0 && w == nil; i-- {
+		if _, ok := g.workflows[i].Nodes[nodeID]; ok {
+			w = g.workflows[i]
+		}
+	}
+	if inv := w.Nodes[nodeID].Invokes; inv != nil {
+		fmt.Fprintf(b, "// Invokes %s; resolved once it returns.\n", inv.WorkflowID)
+	}
+	var edges []reflex.Edge
+	for _, e := range w.Edges {
+		if e.From == nodeID {
+			edges = append(edges, e)
+		}
+	}
+	if len(edges) == 0 {
+		b.WriteString("return reflex.Decision{Type: reflex.DecisionComplete}, nil\n")
+		return
+	}
+	if len(edges) > 1 {
+		b.WriteString("// TODO: choose one of these edges.\n")
+		for _, e := range edges {
+			fmt.Fprintf(b, "// %sEdge%s -> %s", g.name, identifier(e.ID), e.To)
+			if e.Guard != nil {
+				fmt.Fprintf(b, " [%s]", reflex.DescribeGuard(e.Guard))
+			}
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(b, "return reflex.Decision{Type: reflex.DecisionAdvance, Edge: string(%sEdge%s)}, nil\n", g.name, identifier(edges[0].ID))
+}
+
+func writeHeader(b *bytes.Buffer, opts Options) {
+	b.WriteString("// Code generated from reflex workflow definitions. DO NOT EDIT.\n")
+	if opts.Source != "" {
+		fmt.Fprintf(b, "// Source: %s\n", opts.Source)
+	}
+	b.WriteString("\n")
+}
+
+// writeConstants declares typ and its constants, or nothing if there are
+// none.
+func writeConstants(b *bytes.Buffer, typ, doc string, consts []constant) {
+	if len(consts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "// %s is %s.\ntype %s string\n\n", typ, doc, typ)
+	b.WriteString("const (\n")
+	for _, c := range consts {
+		fmt.Fprintf(b, "%s %s = %q\n", c.name, typ, c.value)
+	}
+	b.WriteString(")\n\n")
+}
+
+func formatSource(src []byte) ([]byte, error) {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("codegen: formatting generated source: %w", err)
+	}
+	return formatted, nil
+}
+
+// WriteFile writes generated source to path, leaving the file untouched if
+// the content is unchanged so that build caches stay warm.
+func WriteFile(path string, src []byte) error {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, src) {
+		return nil
+	}
+	return os.WriteFile(path, src, 0o644)
+}
+
+// WriteSkeleton writes a skeleton to path unless the file exists, since it is
+// meant to be edited after generation.
+func WriteSkeleton(path string, src []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("codegen: %s already exists; remove it to regenerate the skeleton", path)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.WriteFile(path, src, 0o644)
+}
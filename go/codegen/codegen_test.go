@@ -0,0 +1,179 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	reflex "github.com/corpus-relica/reflex/go"
+)
+
+func TestIdentifier(t *testing.T) {
+	for id, want := range map[string]string{
+		"GUARD_ROOM":    "GuardRoom",
+		"e-hall-boss":   "EHallBoss",
+		"dungeon-crawl": "DungeonCrawl",
+		"playerHp":      "PlayerHp",
+		"combat#1a2b":   "Combat1a2b",
+		"3rd_floor":     "X3rdFloor",
+		"--":            "X",
+	} {
+		if got := identifier(id); got != want {
+			t.Errorf("identifier(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func orderRegistry(t *testing.T) *reflex.Registry {
+	t.Helper()
+	r := reflex.NewRegistry()
+	v1, err := reflex.NewWorkflow("order-flow").
+		Node("START", nil).
+		Node("REVIEW", nil).
+		Edge("START", "REVIEW").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := reflex.NewWorkflow("order-flow").Version(2).
+		Node("START", nil).
+		Node("APPROVE", nil).Describe("needs sign-off").
+		Node("REJECT", nil).
+		Edge("START", "APPROVE").When(reflex.Equals("approved", true)).
+		Edge("START", "REJECT").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2.Nodes["APPROVE"].Inputs = []reflex.NodeInput{{Key: "approved", Required: true}}
+	v2.Nodes["APPROVE"].Outputs = []reflex.NodeOutput{{Key: "approval_id", Guaranteed: true}}
+	v2.Outputs = []string{"order_total"}
+
+	tmpl := &reflex.Template{
+		ID:     "notify",
+		Params: []reflex.TemplateParam{{Name: "channel", Default: "email"}},
+		Body: &reflex.Workflow{
+			Entry: "SEND",
+			Nodes: map[string]*reflex.Node{"SEND": {ID: "SEND", Spec: reflex.NodeSpec{"channel": reflex.Param("channel")}}},
+		},
+	}
+	for _, err := range []error{r.Register(v1), r.Register(v2), r.RegisterTemplate(tmpl)} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, channel := range []string{"email", "sms"} {
+		if _, err := r.Instantiate("notify", map[string]any{"channel": channel}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestGenerate(t *testing.T) {
+	r := orderRegistry(t)
+	src, err := Generate(Workflows(r), Options{Package: "flows", Source: "orders.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"// Code generated from reflex workflow definitions. DO NOT EDIT.\n// Source: orders.json\n\npackage flows\n",
+		`WorkflowNotify    WorkflowID = "notify"`,
+		`WorkflowOrderFlow WorkflowID = "order-flow"`,
+		"type OrderFlowNode string",
+		`OrderFlowNodeReview  OrderFlowNode = "REVIEW"`,
+		`OrderFlowNodeApprove OrderFlowNode = "APPROVE"`,
+		`OrderFlowEdgeStartReject  OrderFlowEdge = "START->REJECT"`,
+		`NotifyNodeSend NotifyNode = "SEND"`,
+		`KeyApprovalId BlackboardKey = "approval_id"`,
+		`KeyOrderTotal BlackboardKey = "order_total"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "type NotifyEdge") {
+		t.Error("expected no type for a workflow without edges")
+	}
+	if strings.Count(out, "type NotifyNode string") != 1 || strings.Contains(out, "#") {
+		t.Errorf("expected template instances merged under the template:\n%s", out)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	w := &reflex.Workflow{
+		ID:    "clash",
+		Entry: "a-b",
+		Nodes: map[string]*reflex.Node{"a-b": {ID: "a-b"}, "A_B": {ID: "A_B"}},
+	}
+	if _, err := Generate([]*reflex.Workflow{w}, Options{Package: "flows"}); err == nil || !strings.Contains(err.Error(), "ClashNodeAB") {
+		t.Errorf("expected an identifier clash, got %v", err)
+	}
+	crossed := &reflex.Workflow{
+		ID:    "key",
+		Entry: "A",
+		Nodes: map[string]*reflex.Node{"A": {ID: "A", Outputs: []reflex.NodeOutput{{Key: "node"}}}},
+	}
+	if _, err := Generate([]*reflex.Workflow{crossed}, Options{Package: "flows"}); err == nil ||
+		!strings.Contains(err.Error(), "the node type of workflow 'key' and blackboard key 'node' both map to KeyNode") {
+		t.Errorf("expected a clash between a type and a key constant, got %v", err)
+	}
+	single := &reflex.Workflow{ID: "flow", Entry: "A", Nodes: map[string]*reflex.Node{"A": {ID: "A"}}}
+	if _, err := GenerateSkeleton([]*reflex.Workflow{single}, Options{Package: "flows", Agent: "FlowNode"}); err == nil ||
+		!strings.Contains(err.Error(), "agent type FlowNode") {
+		t.Errorf("expected the agent type to clash with a generated type, got %v", err)
+	}
+	if _, err := Generate(nil, Options{Package: "not a name"}); err == nil {
+		t.Error("expected an invalid package error")
+	}
+}
+
+func TestGenerateSkeleton(t *testing.T) {
+	r := orderRegistry(t)
+	src, err := GenerateSkeleton(Workflows(r), Options{Package: "flows", Agent: "OrderAgent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"func (a *OrderAgent) Resolve(ctx context.Context, dc reflex.DecisionContext) (reflex.Decision, error) {",
+		"case WorkflowOrderFlow:\n\t\treturn a.resolveOrderFlow(ctx, dc)",
+		"switch OrderFlowNode(dc.Node.ID) {",
+		"\t\t// OrderFlowEdgeStartApprove -> APPROVE [approved == true]\n",
+		"return reflex.Decision{Type: reflex.DecisionAdvance, Edge: string(OrderFlowEdgeStartApprove)}, nil",
+		// REVIEW only exists in version 0, where it is terminal.
+		"case OrderFlowNodeReview:\n\t\treturn reflex.Decision{Type: reflex.DecisionComplete}, nil",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "agent.go")
+	if err := WriteSkeleton(path, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSkeleton(path, src); err == nil {
+		t.Error("expected an existing skeleton to be kept")
+	}
+}
+
+func TestWriteFileUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.go")
+	if err := WriteFile(path, []byte("package a\n")); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.Stat(path)
+	if err := os.Chmod(path, 0o444); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, []byte("package a\n")); err != nil {
+		t.Errorf("expected identical content not to be rewritten, got %v", err)
+	}
+	after, _ := os.Stat(path)
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Error("expected the modification time to be kept")
+	}
+}
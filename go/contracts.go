@@ -0,0 +1,363 @@
+package reflex
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ---------------------------------------------------------------------------
+// Node contracts (ROADMAP-v1 M8)
+// ---------------------------------------------------------------------------
+
+// VerificationResult reports what Registry.Verify found. Contracts are
+// declarations, not enforcement: nothing in it blocks registration or
+// execution.
+type VerificationResult struct {
+	WorkflowID string
+	// Warnings are likely wiring errors: required inputs with no upstream
+	// producer, returnMap keys the child does not guarantee, invocations of
+	// unregistered workflows.
+	Warnings ValidationErrors
+	// Info notes conditions worth knowing about, such as required inputs
+	// produced on some paths only, or workflows without contracts.
+	Info ValidationErrors
+}
+
+// Clean reports whether verification produced no warnings.
+func (v VerificationResult) Clean() bool {
+	return len(v.Warnings) == 0
+}
+
+// keyFlow is the set of blackboard keys available at a point in a workflow:
+// possible on at least one path, guaranteed on every path.
+type keyFlow struct {
+	possible   map[string]bool
+	guaranteed map[string]bool
+}
+
+func newKeyFlow() keyFlow {
+	return keyFlow{possible: map[string]bool{}, guaranteed: map[string]bool{}}
+}
+
+func (f keyFlow) clone() keyFlow {
+	cp := newKeyFlow()
+	for k := range f.possible {
+		cp.possible[k] = true
+	}
+	for k := range f.guaranteed {
+		cp.guaranteed[k] = true
+	}
+	return cp
+}
+
+// mergeFlows joins the flows of alternative paths.
+func mergeFlows(flows []keyFlow) keyFlow {
+	merged := newKeyFlow()
+	for i, f := range flows {
+		for k := range f.possible {
+			merged.possible[k] = true
+		}
+		if i == 0 {
+			for k := range f.guaranteed {
+				merged.guaranteed[k] = true
+			}
+			continue
+		}
+		for k := range merged.guaranteed {
+			if !f.guaranteed[k] {
+				delete(merged.guaranteed, k)
+			}
+		}
+	}
+	return merged
+}
+
+// Verify checks the node contracts of a workflow and, through its
+// InvocationSpecs, of every workflow it invokes. It walks the DAG from Entry in
+// topological order, tracking which keys upstream nodes declare as outputs on
+// some or all paths. A child workflow sees the keys available at its invoking
+// node, since the scope chain lets it read them. Invocation nodes make their
+// returnMap ParentKeys available downstream.
+//
+// Verify reports:
+//
+//   - ErrMissingInput (warning): a required input no upstream node produces.
+//   - ErrConditionalInput (info): a required input produced on some paths only.
+//   - ErrUnguaranteedReturn (warning): a returnMap ChildKey that is not a
+//     guaranteed output on every path to the child's terminals. Only children
+//     that declare node outputs are checked.
+//   - ErrInvocationNotFound (warning): an invocation target is not registered.
+//   - ErrNoContracts (info): no node declares inputs or outputs; nothing was
+//     checked.
+//
+// Optional inputs without a producer are not reported. Returns an
+// ErrWorkflowNotFound ValidationError if ref is not registered.
+func (r *Registry) Verify(ref string) (VerificationResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.lookup(ref)
+	if !ok {
+		return VerificationResult{}, newValidationError(ErrWorkflowNotFound, ref,
+			fmt.Sprintf("cannot verify: workflow '%s' is not registered", ref))
+	}
+
+	v := &verifier{registry: r, active: map[*Workflow]bool{}}
+	if !v.hasContracts(w, map[*Workflow]bool{}) {
+		return VerificationResult{
+			WorkflowID: w.ID,
+			Info: ValidationErrors{newValidationInfo(ErrNoContracts, w.ID,
+				fmt.Sprintf("workflow '%s' declares no node contracts; verification skipped", w.Ref()))},
+		}, nil
+	}
+
+	v.walk(w, newKeyFlow(), nil, true)
+	return VerificationResult{WorkflowID: w.ID, Warnings: v.problems.Warnings(), Info: v.problems.Info()}, nil
+}
+
+// verifier carries the state of one Verify call. Caller must hold the
+// registry's read lock.
+type verifier struct {
+	registry *Registry
+	active   map[*Workflow]bool // workflows on the current invocation path
+	problems ValidationErrors
+}
+
+// hasContracts reports whether w or any workflow it invokes declares node
+// inputs or outputs.
+func (v *verifier) hasContracts(w *Workflow, seen map[*Workflow]bool) bool {
+	if seen[w] {
+		return false
+	}
+	seen[w] = true
+	for _, node := range w.Nodes {
+		if len(node.Inputs) > 0 || len(node.Outputs) > 0 {
+			return true
+		}
+		if node.Invokes == nil {
+			continue
+		}
+		if child, ok := v.registry.invocationTarget(node.Invokes); ok && v.hasContracts(child, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputKeys returns the keys w declares it writes to its local scope: its
+// Outputs together with every key in its nodes' Outputs, sorted. A workflow
+// that declares neither returns nil and its returnMaps are not checked.
+func (w *Workflow) OutputKeys() []string {
+	keys := slices.Clone(w.Outputs)
+	for _, node := range w.Nodes {
+		for _, o := range node.Outputs {
+			keys = append(keys, o.Key)
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// walk runs the dataflow over w starting from seed and returns the flow at
+// its terminal nodes. via lists the invoking "ref/node" steps that led here.
+// Problems are recorded only when report is true.
+func (v *verifier) walk(w *Workflow, seed keyFlow, via []string, report bool) keyFlow {
+	v.active[w] = true
+	defer delete(v.active, w)
+
+	out := make(map[string]keyFlow)
+	var terminals []keyFlow
+	for _, nodeID := range reachableOrder(w) {
+		node := w.Nodes[nodeID]
+		in := seed.clone()
+		if nodeID != w.Entry {
+			var flows []keyFlow
+			for _, p := range w.Predecessors(nodeID) {
+				if f, ok := out[p]; ok {
+					flows = append(flows, f)
+				}
+			}
+			in = mergeFlows(flows)
+		}
+
+		if report {
+			v.checkInputs(w, node, in, via)
+		}
+
+		flow := in.clone()
+		for _, o := range node.Outputs {
+			flow.possible[o.Key] = true
+			if o.Guaranteed {
+				flow.guaranteed[o.Key] = true
+			}
+		}
+		if node.Invokes != nil {
+			v.invoke(w, node, in, flow, via, report)
+		}
+		out[nodeID] = flow
+
+		if len(w.Successors(nodeID)) == 0 {
+			terminals = append(terminals, flow)
+		}
+	}
+	return mergeFlows(terminals)
+}
+
+func (v *verifier) checkInputs(w *Workflow, node *Node, in keyFlow, via []string) {
+	for _, input := range node.Inputs {
+		if !input.Required || in.guaranteed[input.Key] {
+			continue
+		}
+		if in.possible[input.Key] {
+			v.add(newValidationInfo(ErrConditionalInput, w.ID,
+				fmt.Sprintf("workflow '%s', node '%s': required input '%s' is produced on some paths only%s",
+					w.Ref(), node.ID, input.Key, viaSuffix(via))), node.ID, input.Key, via)
+			continue
+		}
+		v.add(newValidationWarning(ErrMissingInput, w.ID,
+			fmt.Sprintf("workflow '%s', node '%s': required input '%s' has no upstream producer%s",
+				w.Ref(), node.ID, input.Key, viaSuffix(via))), node.ID, input.Key, via)
+	}
+}
+
+// invoke verifies the child workflow of an invocation node and adds the
+// returnMap ParentKeys to flow.
+func (v *verifier) invoke(w *Workflow, node *Node, in, flow keyFlow, via []string, report bool) {
+	target := node.Invokes.WorkflowID
+	child, ok := v.registry.invocationTarget(node.Invokes)
+	if !ok {
+		if report {
+			warning := newValidationWarning(ErrInvocationNotFound, w.ID,
+				fmt.Sprintf("workflow '%s', node '%s': invokes '%s' which is not registered%s",
+					w.Ref(), node.ID, target, viaSuffix(via)))
+			v.add(warning, node.ID, "", via)
+			warning.Details["target"] = target
+		}
+		return
+	}
+
+	if !v.active[child] {
+		v.walk(child, in, append(via, w.Ref()+"/"+node.ID), report)
+	}
+
+	// A recursive invocation is already being verified further up the path,
+	// and a child without declared outputs promises nothing to check: in both
+	// cases returned keys are taken on trust.
+	if v.active[child] || len(child.OutputKeys()) == 0 {
+		for _, m := range node.Invokes.ReturnMap {
+			flow.possible[m.ParentKey] = true
+			flow.guaranteed[m.ParentKey] = true
+		}
+		return
+	}
+
+	// returnMap reads the child's local scope only, so its terminal flow is
+	// computed again without the inherited keys. Workflow-level Outputs name
+	// no node, so they are possible but never guaranteed.
+	local := v.walk(child, newKeyFlow(), nil, false)
+	for _, key := range child.Outputs {
+		local.possible[key] = true
+	}
+	for _, m := range node.Invokes.ReturnMap {
+		if local.possible[m.ChildKey] {
+			flow.possible[m.ParentKey] = true
+		}
+		if local.guaranteed[m.ChildKey] {
+			flow.guaranteed[m.ParentKey] = true
+			continue
+		}
+		if report {
+			warning := newValidationWarning(ErrUnguaranteedReturn, w.ID,
+				fmt.Sprintf("workflow '%s', node '%s': returnMap child key '%s' is not a guaranteed output of '%s' on every path%s",
+					w.Ref(), node.ID, m.ChildKey, child.Ref(), viaSuffix(via)))
+			v.add(warning, node.ID, m.ChildKey, via)
+			warning.Details["target"] = child.Ref()
+			warning.Details["possible"] = local.possible[m.ChildKey]
+		}
+	}
+}
+
+func (v *verifier) add(problem *ValidationError, nodeID, key string, via []string) {
+	problem.Details = map[string]any{"nodeId": nodeID}
+	if key != "" {
+		problem.Details["key"] = key
+	}
+	if len(via) > 0 {
+		problem.Details["via"] = append([]string(nil), via...)
+	}
+	v.problems = append(v.problems, problem)
+}
+
+func viaSuffix(via []string) string {
+	if len(via) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (invoked via %v)", via)
+}
+
+// ---------------------------------------------------------------------------
+// Runtime enforcement (EngineOptions.StrictContracts)
+// ---------------------------------------------------------------------------
+
+// ContractViolation identifies how a node broke its contract at runtime.
+type ContractViolation string
+
+const (
+	// ViolationUndeclaredWrite: a decision wrote a key the node does not
+	// declare in Outputs.
+	ViolationUndeclaredWrite ContractViolation = "undeclared-write"
+	// ViolationMissingOutput: a node advanced or completed without writing
+	// one of its guaranteed Outputs.
+	ViolationMissingOutput ContractViolation = "missing-output"
+)
+
+// ContractError is carried by the engine:error event emitted when strict
+// contracts reject a decision.
+type ContractError struct {
+	Violation  ContractViolation
+	WorkflowID string
+	NodeID     string
+	Key        string
+}
+
+func (e *ContractError) Error() string {
+	if e.Violation == ViolationUndeclaredWrite {
+		return fmt.Sprintf("contract violation: node '%s' in workflow '%s' wrote undeclared key '%s'",
+			e.NodeID, e.WorkflowID, e.Key)
+	}
+	return fmt.Sprintf("contract violation: node '%s' in workflow '%s' did not write guaranteed output '%s'",
+		e.NodeID, e.WorkflowID, e.Key)
+}
+
+// checkContract verifies a decision's writes against the node's declared
+// Outputs. A guaranteed output counts as written if it is among writes or was
+// already written to the local scope by this node, as returnMap promotion does
+// for invocation nodes. Nodes that declare no Outputs have no contract and
+// may write anything.
+func (e *Engine) checkContract(w *Workflow, node *Node, writes []BlackboardWrite) error {
+	if len(node.Outputs) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool)
+	for _, o := range node.Outputs {
+		declared[o.Key] = true
+	}
+	written := make(map[string]bool)
+	for _, write := range writes {
+		if !declared[write.Key] {
+			return &ContractError{Violation: ViolationUndeclaredWrite, WorkflowID: w.ID, NodeID: node.ID, Key: write.Key}
+		}
+		written[write.Key] = true
+	}
+	for _, entry := range e.currentBlackboard.Entries() {
+		if entry.Source.NodeID == node.ID {
+			written[entry.Key] = true
+		}
+	}
+	for _, o := range node.Outputs {
+		if o.Guaranteed && !written[o.Key] {
+			return &ContractError{Violation: ViolationMissingOutput, WorkflowID: w.ID, NodeID: node.ID, Key: o.Key}
+		}
+	}
+	return nil
+}
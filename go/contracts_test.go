@@ -0,0 +1,183 @@
+package reflex
+
+import "testing"
+
+// contractWorkflow builds START → (LEFT | RIGHT) → END, with LEFT guarded.
+func contractWorkflow(id string) *Workflow {
+	return &Workflow{
+		ID:    id,
+		Entry: "START",
+		Nodes: map[string]*Node{
+			"START": {ID: "START", Spec: NodeSpec{}},
+			"LEFT":  {ID: "LEFT", Spec: NodeSpec{}},
+			"RIGHT": {ID: "RIGHT", Spec: NodeSpec{}},
+			"END":   {ID: "END", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{
+			{ID: "e-left", From: "START", To: "LEFT", Event: "GO",
+				Guard: &BuiltinGuard{Type: GuardExists, Key: "go_left"}},
+			{ID: "e-right", From: "START", To: "RIGHT", Event: "GO"},
+			{ID: "e-left-end", From: "LEFT", To: "END", Event: "NEXT"},
+			{ID: "e-right-end", From: "RIGHT", To: "END", Event: "NEXT"},
+		},
+	}
+}
+
+func verify(t *testing.T, r *Registry, ref string) VerificationResult {
+	t.Helper()
+	res, err := r.Verify(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestVerifySatisfiedInputs(t *testing.T) {
+	w := contractWorkflow("wf")
+	w.Nodes["START"].Outputs = []NodeOutput{{Key: "plan", Guaranteed: true}}
+	w.Nodes["END"].Inputs = []NodeInput{{Key: "plan", Required: true}}
+	r := NewRegistry()
+	_ = r.Register(w)
+
+	res := verify(t, r, "wf")
+	if !res.Clean() || len(res.Info) != 0 {
+		t.Errorf("expected clean result, got %v %v", res.Warnings, res.Info)
+	}
+}
+
+func TestVerifyMissingRequiredInput(t *testing.T) {
+	w := contractWorkflow("wf")
+	w.Nodes["END"].Inputs = []NodeInput{
+		{Key: "plan", Required: true},
+		{Key: "notes", Required: false},
+	}
+	r := NewRegistry()
+	_ = r.Register(w)
+
+	res := verify(t, r, "wf")
+	if len(res.Warnings) != 1 {
+		t.Fatalf("expected one warning, got %v", res.Warnings)
+	}
+	warning := res.Warnings[0]
+	if warning.Code != ErrMissingInput || warning.Details["nodeId"] != "END" || warning.Details["key"] != "plan" {
+		t.Errorf("unexpected warning %s %v", warning.Code, warning.Details)
+	}
+}
+
+func TestVerifyConditionalInput(t *testing.T) {
+	w := contractWorkflow("wf")
+	w.Nodes["LEFT"].Outputs = []NodeOutput{{Key: "loot", Guaranteed: true}}
+	w.Nodes["END"].Inputs = []NodeInput{{Key: "loot", Required: true}}
+	r := NewRegistry()
+	_ = r.Register(w)
+
+	res := verify(t, r, "wf")
+	if !res.Clean() {
+		t.Fatalf("expected no warnings, got %v", res.Warnings)
+	}
+	if len(res.Info) != 1 || res.Info[0].Code != ErrConditionalInput {
+		t.Errorf("expected conditional input info, got %v", res.Info)
+	}
+}
+
+func TestVerifyWithoutContracts(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(linearWorkflow("wf"))
+
+	res := verify(t, r, "wf")
+	if !res.Clean() || len(res.Info) != 1 || res.Info[0].Code != ErrNoContracts {
+		t.Errorf("expected skipped verification, got %v %v", res.Warnings, res.Info)
+	}
+
+	if _, err := r.Verify("missing"); err == nil {
+		t.Error("expected error for unregistered workflow")
+	} else {
+		assertValidationError(t, err, ErrWorkflowNotFound)
+	}
+}
+
+func TestVerifyReturnMapAgainstChildOutputs(t *testing.T) {
+	child := contractWorkflow("child")
+	child.Nodes["START"].Outputs = []NodeOutput{{Key: "always", Guaranteed: true}}
+	child.Nodes["LEFT"].Outputs = []NodeOutput{{Key: "sometimes", Guaranteed: true}}
+
+	parent := invokerWorkflow("parent", "child")
+	parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{
+		{ParentKey: "a", ChildKey: "always"},
+		{ParentKey: "s", ChildKey: "sometimes"},
+		{ParentKey: "n", ChildKey: "never"},
+	}
+	parent.Nodes["B"].Inputs = []NodeInput{{Key: "a", Required: true}, {Key: "s", Required: true}}
+
+	r := NewRegistry()
+	_ = r.Register(child)
+	_ = r.Register(parent)
+
+	res := verify(t, r, "parent")
+	if len(res.Warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", res.Warnings)
+	}
+	for i, key := range []string{"sometimes", "never"} {
+		w := res.Warnings[i]
+		if w.Code != ErrUnguaranteedReturn || w.Details["key"] != key || w.Details["target"] != "child@0" {
+			t.Errorf("warning %d: unexpected %s %v", i, w.Code, w.Details)
+		}
+	}
+	if res.Warnings[0].Details["possible"] != true || res.Warnings[1].Details["possible"] != false {
+		t.Errorf("expected possible flags true/false, got %v", res.Warnings)
+	}
+	// "s" may be returned, so B's input is conditional rather than missing.
+	if len(res.Info) != 1 || res.Info[0].Code != ErrConditionalInput || res.Info[0].Details["key"] != "s" {
+		t.Errorf("expected conditional input 's', got %v", res.Info)
+	}
+}
+
+func TestVerifyReturnMapAgainstWorkflowOutputs(t *testing.T) {
+	child := linearWorkflow("child")
+	child.Outputs = []string{"summary"}
+	parent := invokerWorkflow("parent", "child")
+	parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "s", ChildKey: "summary"}}
+	parent.Nodes["B"].Inputs = []NodeInput{{Key: "s", Required: true}}
+
+	r := NewRegistry()
+	_ = r.Register(child)
+	_ = r.Register(parent)
+
+	res := verify(t, r, "parent")
+	if len(res.Warnings) != 1 || res.Warnings[0].Code != ErrUnguaranteedReturn || res.Warnings[0].Details["possible"] != true {
+		t.Errorf("expected 'summary' possible but not guaranteed, got %v", res.Warnings)
+	}
+	if got := child.OutputKeys(); len(got) != 1 || got[0] != "summary" {
+		t.Errorf("expected OutputKeys [summary], got %v", got)
+	}
+}
+
+func TestVerifyAcrossInvocationBoundary(t *testing.T) {
+	child := linearWorkflow("child")
+	child.Nodes["A"].Inputs = []NodeInput{
+		{Key: "from_parent", Required: true},
+		{Key: "unset", Required: true},
+	}
+
+	parent := invokerWorkflow("parent", "child")
+	parent.Entry = "START"
+	parent.Nodes["START"] = &Node{ID: "START", Spec: NodeSpec{},
+		Outputs: []NodeOutput{{Key: "from_parent", Guaranteed: true}}}
+	parent.Edges = append(parent.Edges, Edge{ID: "e0", From: "START", To: "A", Event: "NEXT"})
+
+	r := NewRegistry()
+	_ = r.Register(child)
+	_ = r.Register(parent)
+
+	res := verify(t, r, "parent")
+	if len(res.Warnings) != 1 {
+		t.Fatalf("expected one warning, got %v", res.Warnings)
+	}
+	w := res.Warnings[0]
+	if w.Code != ErrMissingInput || w.WorkflowID != "child" || w.Details["key"] != "unset" {
+		t.Errorf("unexpected warning %s %s %v", w.WorkflowID, w.Code, w.Details)
+	}
+	if via, _ := w.Details["via"].([]string); len(via) != 1 || via[0] != "parent@0/A" {
+		t.Errorf("expected via [parent@0/A], got %v", w.Details["via"])
+	}
+}
@@ -0,0 +1,316 @@
+package reflex
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Structural diff
+// ---------------------------------------------------------------------------
+
+// ChangeKind says whether a field was added, removed or changed.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// FieldChange is one differing field of a node or edge. Field is "spec.<key>"
+// for NodeSpec entries, otherwise "description", "invokes", "returnMap",
+// "params", "inputs", "outputs", "from", "to", "event" or "guard". Old is nil
+// for additions and New for removals. Guards are given as DescribeGuard
+// strings, with the value's Go type appended when only that type differs.
+type FieldChange struct {
+	Field string
+	Kind  ChangeKind
+	Old   any
+	New   any
+}
+
+// NodeDiff lists the changed fields of a node present in both workflows.
+type NodeDiff struct {
+	NodeID  string
+	Changes []FieldChange
+}
+
+// EdgeDiff lists the changed fields of an edge ID present in both workflows.
+type EdgeDiff struct {
+	EdgeID  string
+	Changes []FieldChange
+}
+
+// WorkflowDiff is the structural difference between two workflow
+// definitions. Node and edge lists are sorted by ID.
+type WorkflowDiff struct {
+	From string
+	To   string
+	// OldEntry and NewEntry are set when the entry node changed.
+	OldEntry string
+	NewEntry string
+
+	AddedNodes   []string
+	RemovedNodes []string
+	ChangedNodes []NodeDiff
+	AddedEdges   []Edge
+	RemovedEdges []Edge
+	ChangedEdges []EdgeDiff
+
+	// Problems is filled by Registry.Diff: ErrStrandedNode warnings for
+	// removed nodes and the problems Registry.Check finds in the new
+	// definition.
+	Problems ValidationErrors
+}
+
+// DiffWorkflows compares two definitions, typically two versions of one
+// workflow. Nodes and edges are matched by ID, so a renamed node shows as one
+// removal and one addition.
+func DiffWorkflows(a, b *Workflow) *WorkflowDiff {
+	d := &WorkflowDiff{From: a.Ref(), To: b.Ref()}
+	if a.Entry != b.Entry {
+		d.OldEntry, d.NewEntry = a.Entry, b.Entry
+	}
+
+	for _, id := range sortedNodeIDs(a) {
+		if _, ok := b.Nodes[id]; !ok {
+			d.RemovedNodes = append(d.RemovedNodes, id)
+		} else if changes := diffNodes(a.Nodes[id], b.Nodes[id]); len(changes) > 0 {
+			d.ChangedNodes = append(d.ChangedNodes, NodeDiff{NodeID: id, Changes: changes})
+		}
+	}
+	for _, id := range sortedNodeIDs(b) {
+		if _, ok := a.Nodes[id]; !ok {
+			d.AddedNodes = append(d.AddedNodes, id)
+		}
+	}
+
+	oldEdges := edgesByID(a)
+	newEdges := edgesByID(b)
+	for _, id := range sortedKeys(oldEdges) {
+		if _, ok := newEdges[id]; !ok {
+			d.RemovedEdges = append(d.RemovedEdges, oldEdges[id])
+		} else if changes := diffEdges(oldEdges[id], newEdges[id]); len(changes) > 0 {
+			d.ChangedEdges = append(d.ChangedEdges, EdgeDiff{EdgeID: id, Changes: changes})
+		}
+	}
+	for _, id := range sortedKeys(newEdges) {
+		if _, ok := oldEdges[id]; !ok {
+			d.AddedEdges = append(d.AddedEdges, newEdges[id])
+		}
+	}
+	return d
+}
+
+// Empty reports whether the definitions are structurally identical.
+func (d *WorkflowDiff) Empty() bool {
+	return d.OldEntry == "" && d.NewEntry == "" &&
+		len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 && len(d.ChangedNodes) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0 && len(d.ChangedEdges) == 0
+}
+
+// Diff compares the registered workflow w would follow, the latest version
+// of w.ID, with w and adds the problems a deployment reviewer needs: every
+// removed node is reported as an ErrStrandedNode warning, since a session
+// can be parked at any node (the agent may suspend there, and an invocation
+// node holds its frame while the child runs), with Details["sessions"] set
+// when RegistryOptions.Sessions reports sessions in the old version; and the
+// result of Check(w) is appended. If w has the ID and version of a registered
+// workflow, it is checked as Replace would check it, so that version does not
+// count as a duplicate. Returns an ErrWorkflowNotFound error if w.ID is not
+// registered.
+func (r *Registry) Diff(w *Workflow) (*WorkflowDiff, error) {
+	old, ok := r.Get(w.ID)
+	if !ok {
+		return nil, newValidationError(ErrWorkflowNotFound, w.ID,
+			fmt.Sprintf("cannot diff: workflow '%s' is not registered", w.ID))
+	}
+	d := DiffWorkflows(old, w)
+
+	var sessions []string
+	if r.options.Sessions != nil {
+		sessions = r.options.Sessions.SessionsIn(old.ID, old.Version)
+	}
+	for _, id := range d.RemovedNodes {
+		warning := newValidationWarning(ErrStrandedNode, w.ID,
+			fmt.Sprintf("workflow '%s': removed node '%s' may hold suspended sessions; migrate them with a NodeMap entry", w.ID, id))
+		warning.Details = map[string]any{"nodeId": id}
+		if len(sessions) > 0 {
+			warning.Details["sessions"] = sessions
+		}
+		d.Problems = append(d.Problems, warning)
+	}
+	r.mu.RLock()
+	_, replaces := r.lookup(w.Ref())
+	d.Problems = append(d.Problems, r.checkWorkflow(w, !replaces)...)
+	r.mu.RUnlock()
+	return d, nil
+}
+
+// String renders the diff as text, one line per change: "+" added, "-"
+// removed, "~" changed, "!" problems.
+func (d *WorkflowDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff %s %s\n", d.From, d.To)
+	if d.OldEntry != d.NewEntry {
+		fmt.Fprintf(&b, "~ entry: %s -> %s\n", d.OldEntry, d.NewEntry)
+	}
+	for _, id := range d.AddedNodes {
+		fmt.Fprintf(&b, "+ node %s\n", id)
+	}
+	for _, id := range d.RemovedNodes {
+		fmt.Fprintf(&b, "- node %s\n", id)
+	}
+	for _, n := range d.ChangedNodes {
+		fmt.Fprintf(&b, "~ node %s\n", n.NodeID)
+		writeFieldChanges(&b, n.Changes)
+	}
+	for _, e := range d.AddedEdges {
+		fmt.Fprintf(&b, "+ edge %s\n", describeEdge(e))
+	}
+	for _, e := range d.RemovedEdges {
+		fmt.Fprintf(&b, "- edge %s\n", describeEdge(e))
+	}
+	for _, e := range d.ChangedEdges {
+		fmt.Fprintf(&b, "~ edge %s\n", e.EdgeID)
+		writeFieldChanges(&b, e.Changes)
+	}
+	for _, p := range d.Problems {
+		fmt.Fprintf(&b, "! %s %s: %s\n", p.Severity, p.Code, p.Message)
+	}
+	return b.String()
+}
+
+func writeFieldChanges(b *strings.Builder, changes []FieldChange) {
+	for _, c := range changes {
+		switch c.Kind {
+		case ChangeAdded:
+			fmt.Fprintf(b, "    + %s: %s\n", c.Field, formatDiffValue(c.New))
+		case ChangeRemoved:
+			fmt.Fprintf(b, "    - %s: %s\n", c.Field, formatDiffValue(c.Old))
+		default:
+			fmt.Fprintf(b, "    ~ %s: %s -> %s\n", c.Field, formatDiffValue(c.Old), formatDiffValue(c.New))
+		}
+	}
+}
+
+func describeEdge(e Edge) string {
+	s := fmt.Sprintf("%s %s -> %s on %s", e.ID, e.From, e.To, e.Event)
+	if e.Guard != nil {
+		s += " [" + DescribeGuard(e.Guard) + "]"
+	}
+	return s
+}
+
+// formatDiffValue renders a value as JSON where possible.
+func formatDiffValue(v any) string {
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func diffNodes(a, b *Node) []FieldChange {
+	var changes []FieldChange
+	keys := make(map[string]bool)
+	for k := range a.Spec {
+		keys[k] = true
+	}
+	for k := range b.Spec {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		oldV, inOld := a.Spec[k]
+		newV, inNew := b.Spec[k]
+		changes = appendChange(changes, "spec."+k, oldV, inOld, newV, inNew)
+	}
+	changes = appendChange(changes, "description", a.Description, a.Description != "", b.Description, b.Description != "")
+
+	var oldTarget, newTarget string
+	var oldReturn, newReturn []ReturnMapping
+	var oldParams, newParams map[string]any
+	if a.Invokes != nil {
+		oldTarget, oldReturn, oldParams = a.Invokes.WorkflowID, a.Invokes.ReturnMap, a.Invokes.Params
+	}
+	if b.Invokes != nil {
+		newTarget, newReturn, newParams = b.Invokes.WorkflowID, b.Invokes.ReturnMap, b.Invokes.Params
+	}
+	changes = appendChange(changes, "invokes", oldTarget, a.Invokes != nil, newTarget, b.Invokes != nil)
+	changes = appendChange(changes, "returnMap", oldReturn, len(oldReturn) > 0, newReturn, len(newReturn) > 0)
+	changes = appendChange(changes, "params", oldParams, oldParams != nil, newParams, newParams != nil)
+	changes = appendChange(changes, "inputs", a.Inputs, len(a.Inputs) > 0, b.Inputs, len(b.Inputs) > 0)
+	changes = appendChange(changes, "outputs", a.Outputs, len(a.Outputs) > 0, b.Outputs, len(b.Outputs) > 0)
+	return changes
+}
+
+func diffEdges(a, b Edge) []FieldChange {
+	var changes []FieldChange
+	changes = appendChange(changes, "from", a.From, true, b.From, true)
+	changes = appendChange(changes, "to", a.To, true, b.To, true)
+	changes = appendChange(changes, "event", a.Event, true, b.Event, true)
+	if a.Guard == nil || b.Guard == nil {
+		return appendChange(changes, "guard", DescribeGuard(a.Guard), a.Guard != nil, DescribeGuard(b.Guard), b.Guard != nil)
+	}
+	if !sameGuard(a.Guard, b.Guard) {
+		oldGuard, newGuard := DescribeGuard(a.Guard), DescribeGuard(b.Guard)
+		if oldGuard == newGuard {
+			oldGuard, newGuard = describeGuardType(a.Guard), describeGuardType(b.Guard)
+		}
+		changes = append(changes, FieldChange{Field: "guard", Kind: ChangeChanged, Old: oldGuard, New: newGuard})
+	}
+	return changes
+}
+
+// sameGuard reports whether two guards behave alike, comparing them as
+// Fingerprint does: Equals("hp", 0) and Equals("hp", 0.0) describe the same
+// but differ. Guards that cannot be canonicalized are compared directly.
+func sameGuard(a, b Guard) bool {
+	ca, errA := canonicalizeGuard(a)
+	cb, errB := canonicalizeGuard(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return reflect.DeepEqual(ca, cb)
+}
+
+// describeGuardType is DescribeGuard with the Go type of a builtin guard's
+// value appended, for guards that differ only in that type.
+func describeGuardType(g Guard) string {
+	if guard, ok := g.(*BuiltinGuard); ok && guard.Value != nil {
+		return fmt.Sprintf("%s (%T)", DescribeGuard(g), guard.Value)
+	}
+	return DescribeGuard(g)
+}
+
+func appendChange(changes []FieldChange, field string, oldV any, inOld bool, newV any, inNew bool) []FieldChange {
+	switch {
+	case inOld && !inNew:
+		return append(changes, FieldChange{Field: field, Kind: ChangeRemoved, Old: oldV})
+	case !inOld && inNew:
+		return append(changes, FieldChange{Field: field, Kind: ChangeAdded, New: newV})
+	case inOld && inNew && !reflect.DeepEqual(oldV, newV):
+		return append(changes, FieldChange{Field: field, Kind: ChangeChanged, Old: oldV, New: newV})
+	}
+	return changes
+}
+
+func edgesByID(w *Workflow) map[string]Edge {
+	result := make(map[string]Edge, len(w.Edges))
+	for _, e := range w.Edges {
+		result[e.ID] = e
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+package reflex
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func diffPair() (*Workflow, *Workflow) {
+	a := linearWorkflow("wf")
+	a.Nodes["A"].Spec = NodeSpec{"prompt": "hello", "retries": 1}
+	a.Nodes["B"].Invokes = &InvocationSpec{WorkflowID: "child", ReturnMap: []ReturnMapping{{ParentKey: "x", ChildKey: "y"}}}
+
+	b := linearWorkflow("wf")
+	b.Version = 1
+	b.Entry = "B"
+	b.Nodes["A"].Spec = NodeSpec{"prompt": "hi", "timeout": 30}
+	b.Nodes["B"].Invokes = &InvocationSpec{WorkflowID: "child@2", ReturnMap: []ReturnMapping{{ParentKey: "x", ChildKey: "y"}}}
+	delete(b.Nodes, "C")
+	b.Nodes["D"] = &Node{ID: "D", Spec: NodeSpec{}}
+	b.Edges = []Edge{
+		{ID: "e1", From: "A", To: "B", Event: "GO", Guard: Exists("ready")},
+		{ID: "e3", From: "B", To: "D", Event: "NEXT"},
+	}
+	return a, b
+}
+
+func TestDiffWorkflows(t *testing.T) {
+	a, b := diffPair()
+	d := DiffWorkflows(a, b)
+
+	if d.From != "wf@0" || d.To != "wf@1" || d.OldEntry != "A" || d.NewEntry != "B" {
+		t.Errorf("unexpected header: %+v", d)
+	}
+	if !reflect.DeepEqual(d.AddedNodes, []string{"D"}) || !reflect.DeepEqual(d.RemovedNodes, []string{"C"}) {
+		t.Errorf("unexpected node lists: +%v -%v", d.AddedNodes, d.RemovedNodes)
+	}
+
+	wantNodes := []NodeDiff{
+		{NodeID: "A", Changes: []FieldChange{
+			{Field: "spec.prompt", Kind: ChangeChanged, Old: "hello", New: "hi"},
+			{Field: "spec.retries", Kind: ChangeRemoved, Old: 1},
+			{Field: "spec.timeout", Kind: ChangeAdded, New: 30},
+		}},
+		{NodeID: "B", Changes: []FieldChange{
+			{Field: "invokes", Kind: ChangeChanged, Old: "child", New: "child@2"},
+		}},
+	}
+	if !reflect.DeepEqual(d.ChangedNodes, wantNodes) {
+		t.Errorf("expected %+v, got %+v", wantNodes, d.ChangedNodes)
+	}
+
+	wantEdges := []EdgeDiff{{EdgeID: "e1", Changes: []FieldChange{
+		{Field: "event", Kind: ChangeChanged, Old: "NEXT", New: "GO"},
+		{Field: "guard", Kind: ChangeAdded, New: "exists(ready)"},
+	}}}
+	if !reflect.DeepEqual(d.ChangedEdges, wantEdges) {
+		t.Errorf("expected %+v, got %+v", wantEdges, d.ChangedEdges)
+	}
+	if len(d.AddedEdges) != 1 || d.AddedEdges[0].ID != "e3" || len(d.RemovedEdges) != 1 || d.RemovedEdges[0].ID != "e2" {
+		t.Errorf("unexpected edge lists: +%v -%v", d.AddedEdges, d.RemovedEdges)
+	}
+
+	if !DiffWorkflows(a, a).Empty() || d.Empty() {
+		t.Error("expected only the self-diff to be empty")
+	}
+}
+
+func TestWorkflowDiffString(t *testing.T) {
+	a, b := diffPair()
+	out := DiffWorkflows(a, b).String()
+	for _, want := range []string{
+		"diff wf@0 wf@1\n",
+		"~ entry: A -> B\n",
+		"+ node D\n",
+		"- node C\n",
+		"~ node A\n    ~ spec.prompt: \"hello\" -> \"hi\"\n    - spec.retries: 1\n    + spec.timeout: 30\n",
+		"+ edge e3 B -> D on NEXT\n",
+		"- edge e2 B -> C on NEXT\n",
+		"~ edge e1\n    ~ event: \"NEXT\" -> \"GO\"\n    + guard: \"exists(ready)\"\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+type fixedSessions []string
+
+func (s fixedSessions) SessionsIn(string, int) []string { return s }
+
+func TestRegistryDiff(t *testing.T) {
+	r := NewRegistry(RegistryOptions{Sessions: fixedSessions{"s1"}})
+	a, b := diffPair()
+	a.Nodes["B"].Invokes = nil
+	b.Nodes["B"].Invokes = nil
+	_ = r.Register(a)
+
+	d, err := r.Diff(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranded := d.Problems[0]
+	if stranded.Code != ErrStrandedNode || stranded.Severity != SeverityWarning ||
+		stranded.Details["nodeId"] != "C" || !reflect.DeepEqual(stranded.Details["sessions"], []string{"s1"}) {
+		t.Errorf("expected C flagged with its sessions, got %+v", stranded)
+	}
+	if !strings.Contains(d.String(), "! warning STRANDED_NODE: ") {
+		t.Errorf("expected problems rendered:\n%s", d)
+	}
+
+	broken := linearWorkflow("wf")
+	broken.Version = 2
+	broken.Entry = "NOPE"
+	d, _ = r.Diff(broken)
+	if !d.Problems.HasErrors() || d.Problems.Errors()[0].Code != ErrInvalidEntryNode {
+		t.Errorf("expected registry check errors included, got %v", d.Problems)
+	}
+
+	if _, err := r.Diff(linearWorkflow("other")); err == nil {
+		t.Error("expected an unregistered workflow ID to fail")
+	}
+}
+
+func TestRegistryDiffSameVersion(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(linearWorkflow("wf"))
+
+	w := linearWorkflow("wf")
+	w.Nodes["A"].Description = "reworded"
+	d, err := r.Diff(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Problems) != 0 {
+		t.Errorf("expected a replacement of the same version to pass, got %v", d.Problems)
+	}
+}
+
+func TestDiffGuardValueTypes(t *testing.T) {
+	a := linearWorkflow("wf")
+	a.Edges[0].Guard = Equals("hp", 0)
+	b := linearWorkflow("wf")
+	b.Edges[0].Guard = Equals("hp", 0.0)
+
+	want := []EdgeDiff{{EdgeID: "e1", Changes: []FieldChange{
+		{Field: "guard", Kind: ChangeChanged, Old: "hp == 0 (int)", New: "hp == 0 (float64)"},
+	}}}
+	if d := DiffWorkflows(a, b); !reflect.DeepEqual(d.ChangedEdges, want) {
+		t.Errorf("expected %+v, got %+v", want, d.ChangedEdges)
+	}
+
+	b.Edges[0].Guard = Equals("hp", 0)
+	if d := DiffWorkflows(a, b); !d.Empty() {
+		t.Errorf("expected equal guards to match, got %+v", d.ChangedEdges)
+	}
+}
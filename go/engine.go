@@ -5,6 +5,8 @@ import (
 	"crypto/rand"
 	"fmt"
 	"encoding/hex"
+	"log/slog"
+	"slices"
 )
 
 // EngineError represents an error from the execution engine.
@@ -21,26 +23,44 @@ func (e *EngineError) Error() string { return e.Message }
 type Engine struct {
 	registry *Registry
 	agent    DecisionAgent
+	options  EngineOptions
 
 	sessionID        string
 	status           EngineStatus
 	currentWorkflowID string
+	currentVersion   int
 	currentNodeID    string
 	currentBlackboard *ScopedBlackboard
 	stack            []StackFrame
 	skipInvocation   bool
 
-	handlers map[EventType][]EventHandler
+	currentInvocationID string
+	archive             []ArchivedScope
+	fingerprints        map[string]string
+
+	handlers    map[EventType][]EventHandler
+	allHandlers []EventHandler
+	watchers    []watcher
+	logger      *slog.Logger
 }
 
 // NewEngine creates an engine bound to a registry and decision agent.
-func NewEngine(registry *Registry, agent DecisionAgent) *Engine {
-	return &Engine{
+// An optional EngineOptions may be provided.
+func NewEngine(registry *Registry, agent DecisionAgent, opts ...EngineOptions) *Engine {
+	e := &Engine{
 		registry: registry,
 		agent:    agent,
 		status:   StatusIdle,
 		handlers: make(map[EventType][]EventHandler),
 	}
+	if len(opts) > 0 {
+		e.options = opts[0]
+	}
+	e.logger = e.options.Logger
+	if e.logger == nil {
+		e.logger = discardLogger
+	}
+	return e
 }
 
 // On registers an event handler for the given event type.
@@ -48,36 +68,60 @@ func (e *Engine) On(event EventType, handler EventHandler) {
 	e.handlers[event] = append(e.handlers[event], handler)
 }
 
+// OnAll registers an event handler for every event type. It runs after the
+// handlers registered with On.
+func (e *Engine) OnAll(handler EventHandler) {
+	e.allHandlers = append(e.allHandlers, handler)
+}
+
 // Init initializes a new session for the given workflow.
 // An optional InitOptions may be provided to seed the root blackboard
 // before the first step executes.
 // Returns the session ID.
 func (e *Engine) Init(workflowID string, opts ...InitOptions) (string, error) {
+	if len(e.watchers) > 0 {
+		before := e.watchedEntries()
+		defer e.notifyWatchers(before)
+	}
+
 	w, ok := e.registry.Get(workflowID)
 	if !ok {
 		return "", &EngineError{Message: fmt.Sprintf("cannot initialize: workflow '%s' is not registered", workflowID)}
 	}
 
+	if len(opts) > 0 {
+		if err := checkWrites(opts[0].Blackboard, e.options.ValueMode); err != nil {
+			return "", &EngineError{Message: fmt.Sprintf("cannot initialize: seed %s", err)}
+		}
+	}
+
 	e.sessionID = generateUUID()
-	e.currentWorkflowID = workflowID
+	e.currentWorkflowID = w.ID
+	e.currentVersion = w.Version
 	e.currentNodeID = w.Entry
-	e.currentBlackboard = NewBlackboard()
+	e.currentBlackboard = NewBlackboardWithMode(e.options.ValueMode)
 	e.stack = nil
 	e.skipInvocation = false
+	e.currentInvocationID = ""
+	e.archive = nil
+	e.fingerprints = make(map[string]string)
+	e.recordFingerprint(w)
 	e.status = StatusRunning
+	e.log(context.Background(), slog.LevelInfo, "session started", slog.Int("version", w.Version))
 
 	// Apply seed blackboard entries if provided
 	if len(opts) > 0 && len(opts[0].Blackboard) > 0 {
 		seedSource := BlackboardSource{
-			WorkflowID: workflowID,
+			WorkflowID: w.ID,
 			NodeID:     "__init__",
 			StackDepth: 0,
 		}
-		seedEntries := e.currentBlackboard.Append(opts[0].Blackboard, seedSource)
+		seedEntries := e.currentBlackboard.Append(markSensitive(opts[0].Blackboard, w.SensitiveKeys), seedSource)
 		e.emit(EventBlackboardWrite, Event{
-			Type:       EventBlackboardWrite,
-			Entries:    seedEntries,
-			WorkflowID: workflowID,
+			Type:            EventBlackboardWrite,
+			Entries:         seedEntries,
+			WorkflowID:      w.ID,
+			WorkflowVersion: w.Version,
 		})
 	}
 
@@ -85,9 +129,10 @@ func (e *Engine) Init(workflowID string, opts ...InitOptions) (string, error) {
 	// step() has a matching node:enter.
 	entryNode := w.Nodes[w.Entry]
 	e.emit(EventNodeEnter, Event{
-		Type:       EventNodeEnter,
-		NodeID:     entryNode.ID,
-		WorkflowID: workflowID,
+		Type:            EventNodeEnter,
+		NodeID:          entryNode.ID,
+		WorkflowID:      w.ID,
+		WorkflowVersion: w.Version,
 	})
 
 	return e.sessionID, nil
@@ -107,43 +152,59 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 	if e.status == StatusSuspended {
 		e.status = StatusRunning
 	}
+	if len(e.watchers) > 0 {
+		before := e.watchedEntries()
+		defer e.notifyWatchers(before)
+	}
 
-	w, _ := e.registry.Get(e.currentWorkflowID)
+	w := e.CurrentWorkflow()
+	if w == nil {
+		return e.missingWorkflow(ctx, workflowRef(e.currentWorkflowID, e.currentVersion)), nil
+	}
 	node := w.Nodes[e.currentNodeID]
 
 	// -- Invocation node handling --
 	if node.Invokes != nil && !e.skipInvocation {
-		subW, ok := e.registry.Get(node.Invokes.WorkflowID)
-		if !ok {
+		subW, err := e.registry.resolveInvocation(node.Invokes)
+		if err != nil {
 			e.status = StatusSuspended
+			e.log(ctx, slog.LevelError, "sub-workflow not found", slog.String("target", node.Invokes.WorkflowID), slog.Any("error", err))
 			e.emit(EventEngineError, Event{
 				Type:   EventEngineError,
 				NodeID: e.currentNodeID,
-				Reason: fmt.Sprintf("sub-workflow '%s' not found", node.Invokes.WorkflowID),
+				Reason: err.Error(),
+				Error:  err,
 			})
 			return StepResult{
 				Status: StepSuspended,
-				Reason: fmt.Sprintf("sub-workflow '%s' not found", node.Invokes.WorkflowID),
+				Reason: err.Error(),
 			}, nil
 		}
 
 		// Push current frame
 		frame := StackFrame{
-			WorkflowID:    e.currentWorkflowID,
-			CurrentNodeID: e.currentNodeID,
-			ReturnMap:     node.Invokes.ReturnMap,
-			Blackboard:    e.currentBlackboard.Entries(),
+			WorkflowID:      e.currentWorkflowID,
+			WorkflowVersion: e.currentVersion,
+			CurrentNodeID:   e.currentNodeID,
+			ReturnMap:       node.Invokes.ReturnMap,
+			Blackboard:      e.currentBlackboard.Entries(),
+			InvocationID:    e.currentInvocationID,
 		}
 		e.stack = append([]StackFrame{frame}, e.stack...)
 
 		// Start sub-workflow
 		e.currentWorkflowID = subW.ID
+		e.currentVersion = subW.Version
 		e.currentNodeID = subW.Entry
-		e.currentBlackboard = NewBlackboard()
+		e.currentBlackboard = NewBlackboardWithMode(e.options.ValueMode)
+		e.currentInvocationID = generateUUID()
+		e.recordFingerprint(subW)
+		e.log(ctx, slog.LevelDebug, "sub-workflow invoked",
+			slog.Int("version", subW.Version), slog.String("invocation_id", e.currentInvocationID))
 
-		e.emit(EventWorkflowPush, Event{Type: EventWorkflowPush, WorkflowID: subW.ID})
+		e.emit(EventWorkflowPush, Event{Type: EventWorkflowPush, WorkflowID: subW.ID, WorkflowVersion: subW.Version, InvocationID: e.currentInvocationID})
 		entryNode := subW.Nodes[subW.Entry]
-		e.emit(EventNodeEnter, Event{Type: EventNodeEnter, NodeID: entryNode.ID, WorkflowID: subW.ID})
+		e.emit(EventNodeEnter, Event{Type: EventNodeEnter, NodeID: entryNode.ID, WorkflowID: subW.ID, WorkflowVersion: subW.Version})
 
 		return StepResult{Status: StepInvoked, Workflow: subW, Node: entryNode}, nil
 	}
@@ -154,6 +215,7 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 	validEdges, err := FilterEdges(e.currentNodeID, w.Edges, reader)
 	if err != nil {
 		e.status = StatusSuspended
+		e.log(ctx, slog.LevelError, "guard evaluation failed", slog.Any("error", err))
 		e.emit(EventEngineError, Event{Type: EventEngineError, NodeID: e.currentNodeID, Reason: err.Error()})
 		return StepResult{Status: StepSuspended, Reason: "guard evaluation error"}, nil
 	}
@@ -170,9 +232,15 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 	decision, err := e.agent.Resolve(ctx, dc)
 	if err != nil {
 		e.status = StatusSuspended
+		e.log(ctx, slog.LevelError, "decision agent failed", slog.Any("error", err))
 		e.emit(EventEngineError, Event{Type: EventEngineError, NodeID: e.currentNodeID, Reason: err.Error()})
 		return StepResult{Status: StepSuspended, Reason: "decision agent error"}, nil
 	}
+	e.log(ctx, slog.LevelDebug, "step",
+		slog.String("decision_type", string(decision.Type)),
+		slog.String("edge", decision.Edge),
+		slog.Int("valid_edges", len(validEdges)),
+		slog.Int("writes", len(decision.Writes)))
 
 	// -- Handle advance --
 	if decision.Type == DecisionAdvance {
@@ -185,6 +253,7 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 		}
 		if chosenEdge == nil {
 			e.status = StatusSuspended
+			e.log(ctx, slog.LevelWarn, "invalid edge selected", slog.String("edge", decision.Edge))
 			e.emit(EventEngineError, Event{
 				Type:   EventEngineError,
 				NodeID: e.currentNodeID,
@@ -192,19 +261,27 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 			})
 			return StepResult{Status: StepSuspended, Reason: "invalid edge selection"}, nil
 		}
+		if err := checkWrites(decision.Writes, e.options.ValueMode); err != nil {
+			return e.rejectDecision(ctx, err, "invalid blackboard write"), nil
+		}
+		if e.options.StrictContracts {
+			if err := e.checkContract(w, node, decision.Writes); err != nil {
+				return e.rejectDecision(ctx, err, "contract violation"), nil
+			}
+		}
 
-		e.emit(EventNodeExit, Event{Type: EventNodeExit, NodeID: e.currentNodeID, WorkflowID: e.currentWorkflowID})
-		e.emit(EventEdgeTraverse, Event{Type: EventEdgeTraverse, EdgeID: chosenEdge.ID, WorkflowID: e.currentWorkflowID})
+		e.emit(EventNodeExit, Event{Type: EventNodeExit, NodeID: e.currentNodeID, WorkflowID: e.currentWorkflowID, WorkflowVersion: e.currentVersion})
+		e.emit(EventEdgeTraverse, Event{Type: EventEdgeTraverse, EdgeID: chosenEdge.ID, WorkflowID: e.currentWorkflowID, WorkflowVersion: e.currentVersion})
 
 		if len(decision.Writes) > 0 {
 			source := BlackboardSource{WorkflowID: e.currentWorkflowID, NodeID: e.currentNodeID, StackDepth: len(e.stack)}
-			newEntries := e.currentBlackboard.Append(decision.Writes, source)
-			e.emit(EventBlackboardWrite, Event{Type: EventBlackboardWrite, Entries: newEntries, WorkflowID: e.currentWorkflowID})
+			newEntries := e.currentBlackboard.Append(markSensitive(decision.Writes, w.SensitiveKeys), source)
+			e.emit(EventBlackboardWrite, Event{Type: EventBlackboardWrite, Entries: newEntries, WorkflowID: e.currentWorkflowID, WorkflowVersion: e.currentVersion})
 		}
 
 		e.currentNodeID = chosenEdge.To
 		nextNode := w.Nodes[chosenEdge.To]
-		e.emit(EventNodeEnter, Event{Type: EventNodeEnter, NodeID: nextNode.ID, WorkflowID: e.currentWorkflowID})
+		e.emit(EventNodeEnter, Event{Type: EventNodeEnter, NodeID: nextNode.ID, WorkflowID: e.currentWorkflowID, WorkflowVersion: e.currentVersion})
 
 		return StepResult{Status: StepAdvanced, Node: nextNode}, nil
 	}
@@ -212,6 +289,7 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 	// -- Handle suspend --
 	if decision.Type == DecisionSuspend {
 		e.status = StatusSuspended
+		e.log(ctx, slog.LevelInfo, "session suspended", slog.String("reason", decision.Reason))
 		e.emit(EventEngineSuspend, Event{Type: EventEngineSuspend, Reason: decision.Reason, NodeID: e.currentNodeID})
 		return StepResult{Status: StepSuspended, Reason: decision.Reason}, nil
 	}
@@ -226,6 +304,7 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 	}
 	if hasOutgoing {
 		e.status = StatusSuspended
+		e.log(ctx, slog.LevelWarn, "complete at non-terminal node")
 		e.emit(EventEngineError, Event{
 			Type:   EventEngineError,
 			NodeID: e.currentNodeID,
@@ -233,59 +312,102 @@ func (e *Engine) Step(ctx context.Context) (StepResult, error) {
 		})
 		return StepResult{Status: StepSuspended, Reason: "complete at non-terminal node"}, nil
 	}
+	// The parent must still be registered to return to it; check before
+	// anything is written so the step can be retried.
+	if len(e.stack) > 0 {
+		ref := workflowRef(e.stack[0].WorkflowID, e.stack[0].WorkflowVersion)
+		if _, ok := e.registry.Get(ref); !ok {
+			return e.missingWorkflow(ctx, ref), nil
+		}
+	}
+	if err := checkWrites(decision.Writes, e.options.ValueMode); err != nil {
+		return e.rejectDecision(ctx, err, "invalid blackboard write"), nil
+	}
+	if e.options.StrictContracts {
+		if err := e.checkContract(w, node, decision.Writes); err != nil {
+			return e.rejectDecision(ctx, err, "contract violation"), nil
+		}
+	}
 
 	if len(decision.Writes) > 0 {
 		source := BlackboardSource{WorkflowID: e.currentWorkflowID, NodeID: e.currentNodeID, StackDepth: len(e.stack)}
-		newEntries := e.currentBlackboard.Append(decision.Writes, source)
-		e.emit(EventBlackboardWrite, Event{Type: EventBlackboardWrite, Entries: newEntries, WorkflowID: e.currentWorkflowID})
+		newEntries := e.currentBlackboard.Append(markSensitive(decision.Writes, w.SensitiveKeys), source)
+		e.emit(EventBlackboardWrite, Event{Type: EventBlackboardWrite, Entries: newEntries, WorkflowID: e.currentWorkflowID, WorkflowVersion: e.currentVersion})
 	}
 
 	// Root workflow complete
 	if len(e.stack) == 0 {
 		e.status = StatusCompleted
-		e.emit(EventEngineComplete, Event{Type: EventEngineComplete, WorkflowID: e.currentWorkflowID})
+		e.log(ctx, slog.LevelInfo, "session completed")
+		e.emit(EventEngineComplete, Event{Type: EventEngineComplete, WorkflowID: e.currentWorkflowID, WorkflowVersion: e.currentVersion})
 		return StepResult{Status: StepCompleted}, nil
 	}
 
 	// -- Stack pop: sub-workflow done, return to parent --
 	childBB := e.currentBlackboard
+	childInvocationID := e.currentInvocationID
 	frame := e.stack[0]
+	childDepth := len(e.stack)
 	e.stack = e.stack[1:]
 
-	parentBB := NewBlackboard(frame.Blackboard...)
+	parentBB := NewBlackboardWithMode(e.options.ValueMode, frame.Blackboard...)
 	returnSource := BlackboardSource{
 		WorkflowID: frame.WorkflowID,
 		NodeID:     frame.CurrentNodeID,
 		StackDepth: len(e.stack),
 	}
 
-	// Execute returnMap
-	childReader := childBB.Reader()
+	// Execute returnMap. Promoted entries keep the child entry they came
+	// from so provenance survives the child scope being discarded. A key
+	// the parent declares sensitive makes the promoted value sensitive
+	// wherever it came from.
+	parentW, _ := e.registry.Get(workflowRef(frame.WorkflowID, frame.WorkflowVersion))
+	var sensitiveChildKeys []string
 	for _, mapping := range frame.ReturnMap {
-		val, ok := childReader.Get(mapping.ChildKey)
+		childEntry, ok := childBB.latest(mapping.ChildKey)
 		if ok {
-			newEntries := parentBB.Append(
-				[]BlackboardWrite{{Key: mapping.ParentKey, Value: val}},
-				returnSource,
-			)
-			parentW, _ := e.registry.Get(frame.WorkflowID)
+			sensitive := isSensitiveKey(parentW.SensitiveKeys, mapping.ParentKey)
+			if sensitive {
+				sensitiveChildKeys = append(sensitiveChildKeys, mapping.ChildKey)
+			}
+			promoted := parentBB.promote(mapping.ParentKey, childEntry, returnSource, sensitive)
 			e.emit(EventBlackboardWrite, Event{
-				Type: EventBlackboardWrite, Entries: newEntries,
-				WorkflowID: parentW.ID,
+				Type: EventBlackboardWrite, Entries: []BlackboardEntry{promoted},
+				WorkflowID: parentW.ID, WorkflowVersion: parentW.Version,
 			})
 		}
 	}
 
+	if e.options.ArchiveScopes {
+		entries := childBB.Entries()
+		for i := range entries {
+			if slices.Contains(sensitiveChildKeys, entries[i].Key) {
+				entries[i] = markLineageSensitive(entries[i])
+			}
+		}
+		e.archive = append(e.archive, ArchivedScope{
+			InvocationID:       childInvocationID,
+			WorkflowID:         e.currentWorkflowID,
+			ParentWorkflowID:   frame.WorkflowID,
+			InvokingNodeID:     frame.CurrentNodeID,
+			ParentInvocationID: frame.InvocationID,
+			StackDepth:         childDepth,
+			Entries:            entries,
+		})
+	}
+
 	e.currentWorkflowID = frame.WorkflowID
+	e.currentVersion = frame.WorkflowVersion
 	e.currentNodeID = frame.CurrentNodeID
 	e.currentBlackboard = parentBB
+	e.currentInvocationID = frame.InvocationID
 	e.skipInvocation = true
+	e.log(ctx, slog.LevelDebug, "sub-workflow returned", slog.String("invocation_id", childInvocationID))
 
-	parentW, _ := e.registry.Get(frame.WorkflowID)
 	invokingNode := parentW.Nodes[frame.CurrentNodeID]
 
-	e.emit(EventWorkflowPop, Event{Type: EventWorkflowPop, WorkflowID: parentW.ID})
-	e.emit(EventNodeEnter, Event{Type: EventNodeEnter, NodeID: invokingNode.ID, WorkflowID: parentW.ID})
+	e.emit(EventWorkflowPop, Event{Type: EventWorkflowPop, WorkflowID: parentW.ID, WorkflowVersion: parentW.Version, InvocationID: childInvocationID})
+	e.emit(EventNodeEnter, Event{Type: EventNodeEnter, NodeID: invokingNode.ID, WorkflowID: parentW.ID, WorkflowVersion: parentW.Version})
 
 	return StepResult{Status: StepPopped, Workflow: parentW, Node: invokingNode}, nil
 }
@@ -324,19 +446,21 @@ func (e *Engine) CurrentNode() *Node {
 	if e.currentWorkflowID == "" || e.currentNodeID == "" {
 		return nil
 	}
-	w, ok := e.registry.Get(e.currentWorkflowID)
-	if !ok {
+	w := e.CurrentWorkflow()
+	if w == nil {
 		return nil
 	}
 	return w.Nodes[e.currentNodeID]
 }
 
 // CurrentWorkflow returns the current workflow, or nil if not initialized.
+// This is the version the session entered, even if a newer one has since
+// been registered.
 func (e *Engine) CurrentWorkflow() *Workflow {
 	if e.currentWorkflowID == "" {
 		return nil
 	}
-	w, _ := e.registry.Get(e.currentWorkflowID)
+	w, _ := e.registry.Get(workflowRef(e.currentWorkflowID, e.currentVersion))
 	return w
 }
 
@@ -345,11 +469,66 @@ func (e *Engine) Blackboard() BlackboardReader {
 	return e.buildBlackboardReader()
 }
 
-// Stack returns a snapshot of the call stack.
+// Stack returns a snapshot of the call stack. Sensitive entries in the
+// frames' blackboards are redacted.
 func (e *Engine) Stack() []StackFrame {
 	return e.stackSnapshot()
 }
 
+// SessionsIn implements SessionTracker for this engine's session. It reports
+// the session while it is running or suspended with workflowID@version as its
+// current workflow or in any stack frame.
+func (e *Engine) SessionsIn(workflowID string, version int) []string {
+	if e.status != StatusRunning && e.status != StatusSuspended {
+		return nil
+	}
+	if e.currentWorkflowID == workflowID && e.currentVersion == version {
+		return []string{e.sessionID}
+	}
+	for _, frame := range e.stack {
+		if frame.WorkflowID == workflowID && frame.WorkflowVersion == version {
+			return []string{e.sessionID}
+		}
+	}
+	return nil
+}
+
+// Archive returns the scopes of completed sub-workflow instances in the order
+// they popped. Empty unless EngineOptions.ArchiveScopes is set. Sensitive
+// values are redacted.
+func (e *Engine) Archive() []ArchivedScope {
+	cp := make([]ArchivedScope, len(e.archive))
+	for i, scope := range e.archive {
+		scope.Entries = e.exportEntries(scope.Entries)
+		cp[i] = scope
+	}
+	return cp
+}
+
+// ArchivedScope returns the archived scope for a completed invocation.
+// Sensitive values are redacted.
+func (e *Engine) ArchivedScope(invocationID string) (ArchivedScope, bool) {
+	for _, scope := range e.archive {
+		if scope.InvocationID == invocationID {
+			scope.Entries = e.exportEntries(scope.Entries)
+			return scope, true
+		}
+	}
+	return ArchivedScope{}, false
+}
+
+// Fingerprints returns the Workflow.Fingerprint of every workflow version the
+// session has entered, keyed by ref ("id@version"), as it was when first
+// entered. Persist it with the session and pass it to
+// Registry.VerifyFingerprints on restore to detect definitions that changed.
+func (e *Engine) Fingerprints() map[string]string {
+	cp := make(map[string]string, len(e.fingerprints))
+	for ref, fp := range e.fingerprints {
+		cp[ref] = fp
+	}
+	return cp
+}
+
 // ValidEdges returns the currently valid outgoing edges.
 func (e *Engine) ValidEdges() []Edge {
 	w := e.CurrentWorkflow()
@@ -366,9 +545,46 @@ func (e *Engine) ValidEdges() []Edge {
 
 func (e *Engine) emit(eventType EventType, event Event) {
 	event.SessionID = e.sessionID
+	event.Entries = RedactEntries(event.Entries)
 	for _, h := range e.handlers[eventType] {
 		h(event)
 	}
+	for _, h := range e.allHandlers {
+		h(event)
+	}
+}
+
+// rejectDecision suspends the engine with an engine:error for a decision that
+// failed the ValueMode or contract checks. Nothing is written.
+func (e *Engine) rejectDecision(ctx context.Context, err error, reason string) StepResult {
+	e.status = StatusSuspended
+	e.log(ctx, slog.LevelWarn, "decision rejected", slog.String("reason", reason), slog.Any("error", err))
+	e.emit(EventEngineError, Event{Type: EventEngineError, NodeID: e.currentNodeID, Reason: err.Error(), Error: err})
+	return StepResult{Status: StepSuspended, Reason: reason}
+}
+
+// missingWorkflow suspends the session with an engine:error because the
+// workflow at ref was unregistered while the session was inside it.
+func (e *Engine) missingWorkflow(ctx context.Context, ref string) StepResult {
+	err := &EngineError{Message: fmt.Sprintf("workflow '%s' is no longer registered", ref)}
+	e.status = StatusSuspended
+	e.log(ctx, slog.LevelError, "workflow not registered", slog.String("workflow", ref))
+	e.emit(EventEngineError, Event{Type: EventEngineError, NodeID: e.currentNodeID, Reason: err.Error(), Error: err})
+	return StepResult{Status: StepSuspended, Reason: err.Error()}
+}
+
+// recordFingerprint remembers w's fingerprint the first time the session
+// enters it. Workflows that cannot be fingerprinted are logged and skipped.
+func (e *Engine) recordFingerprint(w *Workflow) {
+	if _, ok := e.fingerprints[w.Ref()]; ok {
+		return
+	}
+	fp, err := w.Fingerprint()
+	if err != nil {
+		e.logger.Warn("cannot fingerprint workflow", "workflow", w.ID, "version", w.Version, "error", err)
+		return
+	}
+	e.fingerprints[w.Ref()] = fp
 }
 
 func (e *Engine) buildBlackboardReader() BlackboardReader {
@@ -384,12 +600,30 @@ func (e *Engine) buildBlackboardReader() BlackboardReader {
 	return e.currentBlackboard.Reader(parentScopes...)
 }
 
+// stackSnapshot copies the call stack for callers outside the engine, with
+// each frame's blackboard passed through exportEntries. Parent scopes are read
+// unredacted through Blackboard.
 func (e *Engine) stackSnapshot() []StackFrame {
 	cp := make([]StackFrame, len(e.stack))
 	copy(cp, e.stack)
+	for i := range cp {
+		cp[i].Blackboard = e.exportEntries(cp[i].Blackboard)
+	}
 	return cp
 }
 
+// exportEntries prepares stored entries for callers outside the engine:
+// sensitive values are redacted and, unless the ValueMode is
+// ValueModeReference, the remaining values are copied so that mutating them
+// does not rewrite history.
+func (e *Engine) exportEntries(entries []BlackboardEntry) []BlackboardEntry {
+	redacted := RedactEntries(entries)
+	if e.options.ValueMode == ValueModeReference || redacted == nil {
+		return redacted
+	}
+	return copyEntries(redacted)
+}
+
 func generateUUID() string {
 	b := make([]byte, 16)
 	_, _ = rand.Read(b)
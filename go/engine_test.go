@@ -662,6 +662,106 @@ func TestEngineBlackboardScoping(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Scope archive — popped child scopes are kept for audit
+// ---------------------------------------------------------------------------
+
+func setupArchive(opts ...EngineOptions) *Engine {
+	r := NewRegistry()
+	_ = r.Register(&Workflow{
+		ID: "child", Entry: "C1",
+		Nodes: map[string]*Node{
+			"C1":   {ID: "C1", Spec: NodeSpec{}},
+			"CEND": {ID: "CEND", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "ec1", From: "C1", To: "CEND", Event: "NEXT"}},
+	})
+	_ = r.Register(&Workflow{
+		ID: "parent", Entry: "P1",
+		Nodes: map[string]*Node{
+			"P1": {ID: "P1", Spec: NodeSpec{}, Invokes: &InvocationSpec{WorkflowID: "child"}},
+			"P2": {ID: "P2", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "ep1", From: "P1", To: "P2", Event: "NEXT"}},
+	})
+	agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+		if dc.Node.ID == "C1" {
+			return Decision{Type: DecisionAdvance, Edge: "ec1",
+				Writes: []BlackboardWrite{{Key: "child_key", Value: "child_val"}}}, nil
+		}
+		if len(dc.ValidEdges) == 0 {
+			return Decision{Type: DecisionComplete}, nil
+		}
+		return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID}, nil
+	})
+	return NewEngine(r, agent, opts...)
+}
+
+func TestEngineArchiveScopes(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		e := setupArchive()
+		_, _ = e.Init("parent")
+		_, _ = e.Run(context.Background())
+		if len(e.Archive()) != 0 {
+			t.Errorf("expected empty archive, got %d scopes", len(e.Archive()))
+		}
+	})
+
+	t.Run("keeps popped child scope keyed by invocation", func(t *testing.T) {
+		e := setupArchive(EngineOptions{ArchiveScopes: true})
+
+		var pushID, popID string
+		e.On(EventWorkflowPush, func(ev Event) { pushID = ev.InvocationID })
+		e.On(EventWorkflowPop, func(ev Event) { popID = ev.InvocationID })
+
+		_, _ = e.Init("parent")
+		res, _ := e.Run(context.Background())
+		if res.Status != StepCompleted {
+			t.Fatalf("expected completed, got %s", res.Status)
+		}
+
+		if pushID == "" || pushID != popID {
+			t.Fatalf("expected matching push/pop invocation IDs, got %q and %q", pushID, popID)
+		}
+		archive := e.Archive()
+		if len(archive) != 1 {
+			t.Fatalf("expected 1 archived scope, got %d", len(archive))
+		}
+		scope, ok := e.ArchivedScope(pushID)
+		if !ok {
+			t.Fatal("expected archived scope for invocation")
+		}
+		if scope.WorkflowID != "child" || scope.ParentWorkflowID != "parent" || scope.InvokingNodeID != "P1" {
+			t.Errorf("unexpected scope identity: %+v", scope)
+		}
+		if scope.StackDepth != 1 {
+			t.Errorf("expected stackDepth=1, got %d", scope.StackDepth)
+		}
+		if len(scope.Entries) != 1 || scope.Entries[0].Key != "child_key" {
+			t.Errorf("expected child_key in archived entries, got %v", scope.Entries)
+		}
+	})
+
+	t.Run("archived entries stay invisible to parent reads", func(t *testing.T) {
+		e := setupArchive(EngineOptions{ArchiveScopes: true})
+		_, _ = e.Init("parent")
+		_, _ = e.Run(context.Background())
+		if e.Blackboard().Has("child_key") {
+			t.Error("child_key should not be visible after pop")
+		}
+	})
+
+	t.Run("init clears the archive", func(t *testing.T) {
+		e := setupArchive(EngineOptions{ArchiveScopes: true})
+		_, _ = e.Init("parent")
+		_, _ = e.Run(context.Background())
+		_, _ = e.Init("parent")
+		if len(e.Archive()) != 0 {
+			t.Error("expected archive reset by Init")
+		}
+	})
+}
+
 // ---------------------------------------------------------------------------
 // Step called before init
 // ---------------------------------------------------------------------------
@@ -720,6 +820,301 @@ func TestEngineBlackboardWrites(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Value modes — copy preserves history, strict rejects non-JSON values
+// ---------------------------------------------------------------------------
+
+func TestEngineValueModes(t *testing.T) {
+	t.Run("copy mode isolates writes from later mutation", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+
+		stats := map[string]any{"hp": 8}
+		agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+			if dc.Node.ID == "A" {
+				return Decision{Type: DecisionAdvance, Edge: "e1",
+					Writes: []BlackboardWrite{{Key: "stats", Value: stats}}}, nil
+			}
+			stats["hp"] = 0 // mutate after the write
+			if len(dc.ValidEdges) == 0 {
+				return Decision{Type: DecisionComplete}, nil
+			}
+			return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID}, nil
+		})
+
+		e := NewEngine(r, agent, EngineOptions{ValueMode: ValueModeCopy})
+		_, _ = e.Init("wf")
+		_, _ = e.Run(context.Background())
+
+		v, _ := e.Blackboard().Get("stats")
+		if v.(map[string]any)["hp"] != 8 {
+			t.Errorf("expected hp=8 in history, got %v", v)
+		}
+	})
+
+	t.Run("strict mode rejects a func write", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+			return Decision{Type: DecisionAdvance, Edge: "e1",
+				Writes: []BlackboardWrite{{Key: "callback", Value: func() {}}}}, nil
+		})
+
+		e := NewEngine(r, agent, EngineOptions{ValueMode: ValueModeStrict})
+		var errEvents []Event
+		e.On(EventEngineError, func(ev Event) { errEvents = append(errEvents, ev) })
+		_, _ = e.Init("wf")
+
+		res, _ := e.Step(context.Background())
+		if res.Status != StepSuspended {
+			t.Fatalf("expected suspended, got %s", res.Status)
+		}
+		if len(errEvents) != 1 || errEvents[0].NodeID != "A" {
+			t.Fatalf("expected engine:error at A, got %v", errEvents)
+		}
+		if e.Blackboard().Has("callback") {
+			t.Error("rejected write should not reach the blackboard")
+		}
+		if e.CurrentNode().ID != "A" {
+			t.Errorf("expected to remain at A, got %s", e.CurrentNode().ID)
+		}
+	})
+
+	t.Run("copy mode rejects a cyclic write", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		cyclic := map[string]any{}
+		cyclic["self"] = cyclic
+		agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+			return Decision{Type: DecisionAdvance, Edge: "e1",
+				Writes: []BlackboardWrite{{Key: "graph", Value: cyclic}}}, nil
+		})
+
+		e := NewEngine(r, agent, EngineOptions{ValueMode: ValueModeCopy})
+		_, _ = e.Init("wf")
+		res, _ := e.Step(context.Background())
+		if res.Status != StepSuspended || e.Blackboard().Has("graph") {
+			t.Errorf("expected the write rejected, got %s", res.Status)
+		}
+	})
+
+	t.Run("copy mode hands events a copy", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		e := NewEngine(r, autoAdvanceAgent(), EngineOptions{ValueMode: ValueModeCopy})
+		e.On(EventBlackboardWrite, func(ev Event) {
+			ev.Entries[0].Value.(map[string]any)["hp"] = 0
+		})
+		_, _ = e.Init("wf", InitOptions{Blackboard: []BlackboardWrite{{Key: "stats", Value: map[string]any{"hp": 8}}}})
+		if v, _ := e.Blackboard().Get("stats"); v.(map[string]any)["hp"] != 8 {
+			t.Errorf("expected hp=8 in history, got %v", v)
+		}
+	})
+
+	t.Run("copy mode hands stack frames and the archive copies", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("child"))
+		_ = r.Register(invokerWorkflow("parent", "child"))
+		var e *Engine
+		agent := agentFunc(func(ctx context.Context, dc DecisionContext) (Decision, error) {
+			if len(dc.Stack) > 0 {
+				dc.Stack[0].Blackboard[0].Value.(map[string]any)["hp"] = 0
+				e.Stack()[0].Blackboard[0].Value.(map[string]any)["hp"] = 0
+				if dc.Node.ID == "A" {
+					return Decision{Type: DecisionAdvance, Edge: "e1",
+						Writes: []BlackboardWrite{{Key: "loot", Value: map[string]any{"gold": 5}}}}, nil
+				}
+			}
+			return autoAdvanceAgent().Resolve(ctx, dc)
+		})
+		e = NewEngine(r, agent, EngineOptions{ValueMode: ValueModeCopy, ArchiveScopes: true})
+		_, _ = e.Init("parent", InitOptions{Blackboard: []BlackboardWrite{{Key: "stats", Value: map[string]any{"hp": 8}}}})
+		if res, err := e.Run(context.Background()); err != nil || res.Status != StepCompleted {
+			t.Fatalf("expected completion, got %+v, %v", res, err)
+		}
+		if v, _ := e.Blackboard().Get("stats"); v.(map[string]any)["hp"] != 8 {
+			t.Errorf("expected hp=8 in the parent's history, got %v", v)
+		}
+
+		archived := e.Archive()[0]
+		archived.Entries[0].Value.(map[string]any)["gold"] = 0
+		scope, _ := e.ArchivedScope(archived.InvocationID)
+		scope.Entries[0].Value.(map[string]any)["gold"] = 0
+		if v := e.Archive()[0].Entries[0].Value; v.(map[string]any)["gold"] != 5 {
+			t.Errorf("expected gold=5 in the archive, got %v", v)
+		}
+	})
+
+	t.Run("strict mode rejects non-JSON seed values", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		e := NewEngine(r, autoAdvanceAgent(), EngineOptions{ValueMode: ValueModeStrict})
+		_, err := e.Init("wf", InitOptions{Blackboard: []BlackboardWrite{{Key: "ch", Value: make(chan int)}}})
+		if err == nil {
+			t.Error("expected init error")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Sensitive entries — real values for readers, redacted in events
+// ---------------------------------------------------------------------------
+
+func TestEngineSensitiveEntries(t *testing.T) {
+	newSensitiveEngine := func(opts ...EngineOptions) (*Engine, *string) {
+		r := NewRegistry()
+		_ = r.Register(&Workflow{
+			ID: "child", Entry: "C1",
+			Nodes: map[string]*Node{
+				"C1":   {ID: "C1", Spec: NodeSpec{}},
+				"CEND": {ID: "CEND", Spec: NodeSpec{}},
+			},
+			Edges: []Edge{{ID: "ec1", From: "C1", To: "CEND", Event: "NEXT"}},
+		})
+		_ = r.Register(&Workflow{
+			ID: "parent", Entry: "P1",
+			Nodes: map[string]*Node{
+				"P1": {ID: "P1", Spec: NodeSpec{}},
+				"P2": {ID: "P2", Spec: NodeSpec{}, Invokes: &InvocationSpec{
+					WorkflowID: "child",
+					ReturnMap:  []ReturnMapping{{ParentKey: "session", ChildKey: "token"}},
+				}},
+				"P3": {ID: "P3", Spec: NodeSpec{}},
+			},
+			Edges: []Edge{
+				{ID: "ep1", From: "P1", To: "P2", Event: "NEXT"},
+				{ID: "ep2", From: "P2", To: "P3", Event: "NEXT"},
+			},
+			SensitiveKeys: []string{"api_*"},
+		})
+
+		var seen string
+		agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+			switch dc.Node.ID {
+			case "P1":
+				return Decision{Type: DecisionAdvance, Edge: "ep1", Writes: []BlackboardWrite{
+					{Key: "api_key", Value: "sk-123"},
+					{Key: "user", Value: "alice"},
+				}}, nil
+			case "C1":
+				v, _ := dc.Blackboard.Get("api_key")
+				seen, _ = v.(string)
+				return Decision{Type: DecisionAdvance, Edge: "ec1", Writes: []BlackboardWrite{
+					{Key: "token", Value: "tok-456", Sensitive: true},
+				}}, nil
+			}
+			if len(dc.ValidEdges) == 0 {
+				return Decision{Type: DecisionComplete}, nil
+			}
+			return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID}, nil
+		})
+		return NewEngine(r, agent, opts...), &seen
+	}
+
+	t.Run("agents read real values, events carry placeholders", func(t *testing.T) {
+		e, seen := newSensitiveEngine()
+		written := map[string]any{}
+		e.On(EventBlackboardWrite, func(ev Event) {
+			for _, entry := range ev.Entries {
+				written[entry.Key] = entry.Value
+			}
+		})
+		_, _ = e.Init("parent")
+		res, _ := e.Run(context.Background())
+		if res.Status != StepCompleted {
+			t.Fatalf("expected completed, got %s", res.Status)
+		}
+
+		if *seen != "sk-123" {
+			t.Errorf("expected agent to read real api_key, got %q", *seen)
+		}
+		if written["api_key"] != RedactedValue {
+			t.Errorf("expected api_key redacted by pattern, got %v", written["api_key"])
+		}
+		if written["token"] != RedactedValue {
+			t.Errorf("expected token redacted per write, got %v", written["token"])
+		}
+		if written["user"] != "alice" {
+			t.Errorf("expected user unredacted, got %v", written["user"])
+		}
+		if written["session"] != RedactedValue {
+			t.Errorf("expected promoted session to stay sensitive, got %v", written["session"])
+		}
+		if v, _ := e.Blackboard().Get("session"); v != "tok-456" {
+			t.Errorf("expected real promoted value on blackboard, got %v", v)
+		}
+	})
+
+	t.Run("archived scopes are redacted", func(t *testing.T) {
+		e, _ := newSensitiveEngine(EngineOptions{ArchiveScopes: true})
+		_, _ = e.Init("parent")
+		_, _ = e.Run(context.Background())
+		archive := e.Archive()
+		if len(archive) != 1 || len(archive[0].Entries) != 1 {
+			t.Fatalf("expected one archived entry, got %v", archive)
+		}
+		if archive[0].Entries[0].Value != RedactedValue {
+			t.Errorf("expected redacted token, got %v", archive[0].Entries[0].Value)
+		}
+	})
+
+	t.Run("stack frames are redacted", func(t *testing.T) {
+		e, _ := newSensitiveEngine()
+		_, _ = e.Init("parent")
+		for e.CurrentWorkflow().ID != "child" {
+			_, _ = e.Step(context.Background())
+		}
+		frame := e.Stack()[0]
+		for _, entry := range frame.Blackboard {
+			if entry.Key == "api_key" && entry.Value != RedactedValue {
+				t.Errorf("expected api_key redacted in the stack frame, got %v", entry.Value)
+			}
+		}
+		if v, _ := e.Blackboard().Get("api_key"); v != "sk-123" {
+			t.Errorf("expected the reader to keep the real value, got %v", v)
+		}
+	})
+}
+
+func TestEnginePromotedSensitiveKey(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(linearWorkflow("child"))
+	parent := invokerWorkflow("parent", "child")
+	parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "secret", ChildKey: "result"}}
+	parent.SensitiveKeys = []string{"secret"}
+	_ = r.Register(parent)
+
+	agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+		if len(dc.ValidEdges) == 0 {
+			return Decision{Type: DecisionComplete}, nil
+		}
+		var writes []BlackboardWrite
+		if dc.Workflow.ID == "child" && dc.Node.ID == "A" {
+			writes = []BlackboardWrite{{Key: "result", Value: "tok-789"}}
+		}
+		return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID, Writes: writes}, nil
+	})
+	e := NewEngine(r, agent, EngineOptions{ArchiveScopes: true})
+	var promoted []BlackboardEntry
+	e.On(EventBlackboardWrite, func(ev Event) {
+		if ev.WorkflowID == "parent" {
+			promoted = append(promoted, ev.Entries...)
+		}
+	})
+	_, _ = e.Init("parent")
+	_, _ = e.Run(context.Background())
+
+	if len(promoted) != 1 || promoted[0].Value != RedactedValue || promoted[0].PromotedFrom.Value != RedactedValue {
+		t.Errorf("expected the promoted value and its origin redacted, got %+v", promoted)
+	}
+	if archived := e.Archive()[0].Entries[0]; archived.Value != RedactedValue {
+		t.Errorf("expected the archived child entry redacted, got %v", archived.Value)
+	}
+	if v, _ := e.Blackboard().Get("secret"); v != "tok-789" {
+		t.Errorf("expected the real value on the blackboard, got %v", v)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Nested sub-workflows (2 levels deep)
 // ---------------------------------------------------------------------------
@@ -777,6 +1172,175 @@ func TestEngineNestedSubWorkflows(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// ReturnMap promotion preserves lineage
+// ---------------------------------------------------------------------------
+
+func TestEnginePromotionLineage(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(&Workflow{
+		ID: "grandchild", Entry: "GC1",
+		Nodes: map[string]*Node{
+			"GC1":    {ID: "GC1", Spec: NodeSpec{}},
+			"GC_END": {ID: "GC_END", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "egc1", From: "GC1", To: "GC_END", Event: "NEXT"}},
+	})
+	_ = r.Register(&Workflow{
+		ID: "child", Entry: "C_INVOKE",
+		Nodes: map[string]*Node{
+			"C_INVOKE": {ID: "C_INVOKE", Spec: NodeSpec{}, Invokes: &InvocationSpec{
+				WorkflowID: "grandchild",
+				ReturnMap:  []ReturnMapping{{ParentKey: "mid", ChildKey: "out"}},
+			}},
+			"C_END": {ID: "C_END", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "ec1", From: "C_INVOKE", To: "C_END", Event: "NEXT"}},
+	})
+	_ = r.Register(&Workflow{
+		ID: "parent", Entry: "P_INVOKE",
+		Nodes: map[string]*Node{
+			"P_INVOKE": {ID: "P_INVOKE", Spec: NodeSpec{}, Invokes: &InvocationSpec{
+				WorkflowID: "child",
+				ReturnMap:  []ReturnMapping{{ParentKey: "result", ChildKey: "mid"}},
+			}},
+			"P_END": {ID: "P_END", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "ep1", From: "P_INVOKE", To: "P_END", Event: "NEXT"}},
+	})
+
+	agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+		if len(dc.ValidEdges) > 0 {
+			return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID}, nil
+		}
+		if dc.Node.ID == "GC_END" {
+			return Decision{Type: DecisionComplete, Writes: []BlackboardWrite{{Key: "out", Value: 7}}}, nil
+		}
+		return Decision{Type: DecisionComplete}, nil
+	})
+
+	e := NewEngine(r, agent)
+	_, _ = e.Init("parent")
+	res, err := e.Run(context.Background())
+	if err != nil || res.Status != StepCompleted {
+		t.Fatalf("expected completed, got %s err=%v", res.Status, err)
+	}
+
+	lineage := e.Blackboard().(LineageReader).Lineage("result")
+	if len(lineage) != 3 {
+		t.Fatalf("expected 3 lineage entries, got %d", len(lineage))
+	}
+	want := []BlackboardSource{
+		{WorkflowID: "parent", NodeID: "P_INVOKE", StackDepth: 0},
+		{WorkflowID: "child", NodeID: "C_INVOKE", StackDepth: 1},
+		{WorkflowID: "grandchild", NodeID: "GC_END", StackDepth: 2},
+	}
+	wantKeys := []string{"result", "mid", "out"}
+	for i := range want {
+		if lineage[i].Source != want[i] {
+			t.Errorf("lineage[%d]: expected source %+v, got %+v", i, want[i], lineage[i].Source)
+		}
+		if lineage[i].Key != wantKeys[i] {
+			t.Errorf("lineage[%d]: expected key %s, got %s", i, wantKeys[i], lineage[i].Key)
+		}
+		if lineage[i].Value != 7 {
+			t.Errorf("lineage[%d]: expected value 7, got %v", i, lineage[i].Value)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Versioned workflows — sessions stay pinned
+// ---------------------------------------------------------------------------
+
+func TestEngineVersionPinning(t *testing.T) {
+	versioned := func(id string, version int, terminal string) *Workflow {
+		return &Workflow{
+			ID: id, Version: version, Entry: "START",
+			Nodes: map[string]*Node{
+				"START":  {ID: "START", Spec: NodeSpec{}},
+				terminal: {ID: terminal, Spec: NodeSpec{}},
+			},
+			Edges: []Edge{{ID: "e1", From: "START", To: terminal, Event: "NEXT"}},
+		}
+	}
+
+	t.Run("running session keeps its version", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(versioned("wf", 1, "END_V1"))
+		e := NewEngine(r, autoAdvanceAgent())
+		_, _ = e.Init("wf")
+
+		_ = r.Register(versioned("wf", 2, "END_V2"))
+
+		res, _ := e.Step(context.Background())
+		if res.Node.ID != "END_V1" {
+			t.Fatalf("expected END_V1, got %s", res.Node.ID)
+		}
+		if e.CurrentWorkflow().Version != 1 {
+			t.Errorf("expected version 1, got %d", e.CurrentWorkflow().Version)
+		}
+	})
+
+	t.Run("init and invocation accept pinned refs", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(versioned("child", 1, "END_V1"))
+		_ = r.Register(versioned("child", 2, "END_V2"))
+		_ = r.Register(&Workflow{
+			ID: "parent", Entry: "INVOKE",
+			Nodes: map[string]*Node{
+				"INVOKE": {ID: "INVOKE", Spec: NodeSpec{}, Invokes: &InvocationSpec{WorkflowID: "child@1"}},
+				"END":    {ID: "END", Spec: NodeSpec{}},
+			},
+			Edges: []Edge{{ID: "ep1", From: "INVOKE", To: "END", Event: "NEXT"}},
+		})
+
+		var visited []string
+		e := NewEngine(r, autoAdvanceAgent())
+		e.On(EventNodeEnter, func(ev Event) { visited = append(visited, ev.NodeID) })
+		_, _ = e.Init("parent")
+		res, _ := e.Run(context.Background())
+		if res.Status != StepCompleted {
+			t.Fatalf("expected completed, got %s", res.Status)
+		}
+		found := false
+		for _, id := range visited {
+			if id == "END_V2" {
+				t.Error("pinned invocation entered version 2")
+			}
+			found = found || id == "END_V1"
+		}
+		if !found {
+			t.Errorf("expected child@1 to run, visited %v", visited)
+		}
+
+		_, err := e.Init("child@2")
+		if err != nil || e.CurrentWorkflow().Version != 2 {
+			t.Errorf("expected init at child@2, got err=%v", err)
+		}
+	})
+
+	t.Run("stack frames record the parent version", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(versioned("child", 1, "END"))
+		parent := &Workflow{
+			ID: "parent", Version: 7, Entry: "INVOKE",
+			Nodes: map[string]*Node{
+				"INVOKE": {ID: "INVOKE", Spec: NodeSpec{}, Invokes: &InvocationSpec{WorkflowID: "child"}},
+				"END":    {ID: "END", Spec: NodeSpec{}},
+			},
+			Edges: []Edge{{ID: "ep1", From: "INVOKE", To: "END", Event: "NEXT"}},
+		}
+		_ = r.Register(parent)
+		e := NewEngine(r, autoAdvanceAgent())
+		_, _ = e.Init("parent")
+		_, _ = e.Step(context.Background())
+		if stack := e.Stack(); len(stack) != 1 || stack[0].WorkflowVersion != 7 {
+			t.Errorf("expected frame at parent version 7, got %+v", stack)
+		}
+	})
+}
+
 // ---------------------------------------------------------------------------
 // Full pipeline test — 5 node linear workflow
 // ---------------------------------------------------------------------------
@@ -1028,3 +1592,115 @@ func TestEngineInitSeedBlackboard(t *testing.T) {
 		}
 	})
 }
+
+// writingAgent advances along the first valid edge, or completes, writing
+// writes[node ID].
+func writingAgent(writes map[string][]BlackboardWrite) DecisionAgent {
+	return agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+		if len(dc.ValidEdges) == 0 {
+			return Decision{Type: DecisionComplete, Writes: writes[dc.Node.ID]}, nil
+		}
+		return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID, Writes: writes[dc.Node.ID]}, nil
+	})
+}
+
+func TestEngineStrictContracts(t *testing.T) {
+	setup := func(writes map[string][]BlackboardWrite) (*Engine, *[]Event) {
+		w := linearWorkflow("wf")
+		w.Nodes["A"].Outputs = []NodeOutput{{Key: "plan", Guaranteed: true}, {Key: "note"}}
+		w.Nodes["C"].Outputs = []NodeOutput{{Key: "result", Guaranteed: true}}
+		r := NewRegistry()
+		_ = r.Register(w)
+		e := NewEngine(r, writingAgent(writes), EngineOptions{StrictContracts: true})
+		var errEvents []Event
+		e.On(EventEngineError, func(ev Event) { errEvents = append(errEvents, ev) })
+		_, _ = e.Init("wf")
+		return e, &errEvents
+	}
+
+	t.Run("declared writes pass", func(t *testing.T) {
+		e, errEvents := setup(map[string][]BlackboardWrite{
+			"A": {{Key: "plan", Value: 1}},
+			"C": {{Key: "result", Value: "ok"}},
+		})
+		res, _ := e.Run(context.Background())
+		if res.Status != StepCompleted || len(*errEvents) != 0 {
+			t.Errorf("expected completion, got %s with %v", res.Status, *errEvents)
+		}
+	})
+
+	t.Run("undeclared write is rejected", func(t *testing.T) {
+		e, errEvents := setup(map[string][]BlackboardWrite{
+			"A": {{Key: "plan", Value: 1}, {Key: "rogue", Value: true}},
+		})
+		res, _ := e.Step(context.Background())
+		if res.Status != StepSuspended || res.Reason != "contract violation" {
+			t.Fatalf("expected contract violation, got %s %q", res.Status, res.Reason)
+		}
+		var ce *ContractError
+		if len(*errEvents) != 1 || !errors.As((*errEvents)[0].Error, &ce) {
+			t.Fatalf("expected engine:error with ContractError, got %v", *errEvents)
+		}
+		if ce.Violation != ViolationUndeclaredWrite || ce.NodeID != "A" || ce.Key != "rogue" {
+			t.Errorf("unexpected violation %+v", ce)
+		}
+		if e.Blackboard().Has("plan") {
+			t.Error("rejected decision must write nothing")
+		}
+	})
+
+	t.Run("missing guaranteed output on advance", func(t *testing.T) {
+		e, errEvents := setup(map[string][]BlackboardWrite{"A": {{Key: "note", Value: "x"}}})
+		_, _ = e.Step(context.Background())
+		var ce *ContractError
+		if len(*errEvents) != 1 || !errors.As((*errEvents)[0].Error, &ce) {
+			t.Fatalf("expected ContractError, got %v", *errEvents)
+		}
+		if ce.Violation != ViolationMissingOutput || ce.Key != "plan" {
+			t.Errorf("unexpected violation %+v", ce)
+		}
+	})
+
+	t.Run("nodes without declared outputs are not checked", func(t *testing.T) {
+		e, errEvents := setup(map[string][]BlackboardWrite{
+			"A": {{Key: "plan", Value: 1}},
+			"B": {{Key: "scratch", Value: true}},
+			"C": {{Key: "result", Value: "ok"}},
+		})
+		res, _ := e.Run(context.Background())
+		if res.Status != StepCompleted || len(*errEvents) != 0 {
+			t.Errorf("expected B's write allowed, got %s with %v", res.Status, *errEvents)
+		}
+	})
+
+	t.Run("missing guaranteed output on complete", func(t *testing.T) {
+		e, errEvents := setup(map[string][]BlackboardWrite{"A": {{Key: "plan", Value: 1}}})
+		res, _ := e.Run(context.Background())
+		if res.Status != StepSuspended {
+			t.Fatalf("expected suspended, got %s", res.Status)
+		}
+		var ce *ContractError
+		if len(*errEvents) != 1 || !errors.As((*errEvents)[0].Error, &ce) || ce.NodeID != "C" || ce.Key != "result" {
+			t.Errorf("expected missing 'result' at C, got %v", *errEvents)
+		}
+	})
+
+	t.Run("returnMap satisfies an invocation node's outputs", func(t *testing.T) {
+		r := NewRegistry()
+		child := linearWorkflow("child")
+		child.Nodes["C"].Outputs = []NodeOutput{{Key: "out", Guaranteed: true}}
+		parent := invokerWorkflow("parent", "child")
+		parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "result", ChildKey: "out"}}
+		parent.Nodes["A"].Outputs = []NodeOutput{{Key: "result", Guaranteed: true}}
+		_ = r.Register(child)
+		_ = r.Register(parent)
+
+		e := NewEngine(r, writingAgent(map[string][]BlackboardWrite{"C": {{Key: "out", Value: 42}}}),
+			EngineOptions{StrictContracts: true})
+		_, _ = e.Init("parent")
+		res, _ := e.Run(context.Background())
+		if res.Status != StepCompleted {
+			t.Errorf("expected completion, got %s %q", res.Status, res.Reason)
+		}
+	})
+}
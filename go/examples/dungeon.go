@@ -2,6 +2,8 @@ package examples
 
 import reflex "github.com/corpus-relica/reflex/go"
 
+//go:generate go run ./internal/gendungeon
+
 // ---------------------------------------------------------------------------
 // Dungeon Crawl — Go port of reflex-dungeon
 //
@@ -11,17 +13,35 @@ import reflex "github.com/corpus-relica/reflex/go"
 // Root workflow: dungeon-crawl (simplified — one path per wing, no A/B slots)
 //   ENTRANCE → ANTECHAMBER → (WEST or EAST wing) → GREAT_HALL → (BOSS or ESCAPE)
 //
-// Sub-workflows: combat (reusable), puzzle-riddle
+// Sub-workflows: combat (template, one instance per enemy), puzzle-riddle
 // ---------------------------------------------------------------------------
 
-// CombatWorkflow returns the reusable combat sub-workflow.
+// CombatTemplate returns the reusable combat template, parameterized by the
+// enemy's name and HP.
 // 6 nodes: ENCOUNTER → PLAYER_TURN → RESOLVE → CHECK → (VICTORY_C or DEFEAT_C)
+func CombatTemplate() *reflex.Template {
+	return &reflex.Template{
+		ID: "combat",
+		Params: []reflex.TemplateParam{
+			{Name: "enemy_name", Default: "Tomb Guard"},
+			{Name: "enemy_hp", Default: 3},
+		},
+		Body: combatBody(),
+	}
+}
+
+// CombatWorkflow returns the combat template instantiated with its defaults
+// as a standalone workflow.
 func CombatWorkflow() *reflex.Workflow {
+	w, _ := CombatTemplate().Apply("combat", nil)
+	return w
+}
+
+func combatBody() *reflex.Workflow {
 	return &reflex.Workflow{
-		ID:    "combat",
 		Entry: "ENCOUNTER",
 		Nodes: map[string]*reflex.Node{
-			"ENCOUNTER":      {ID: "ENCOUNTER", Spec: reflex.NodeSpec{"type": "encounter"}},
+			"ENCOUNTER":      {ID: "ENCOUNTER", Spec: reflex.NodeSpec{"type": "encounter", "enemyName": reflex.Param("enemy_name"), "enemyHp": reflex.Param("enemy_hp")}},
 			"PLAYER_TURN":    {ID: "PLAYER_TURN", Spec: reflex.NodeSpec{"type": "player_turn", "suspend": true, "writeKey": "action"}},
 			"RESOLVE_ATTACK": {ID: "RESOLVE_ATTACK", Spec: reflex.NodeSpec{"type": "resolve"}},
 			"CHECK_OUTCOME":  {ID: "CHECK_OUTCOME", Spec: reflex.NodeSpec{"type": "check"}},
@@ -79,6 +99,7 @@ func DungeonCrawlWorkflow() *reflex.Workflow {
 						{ParentKey: "guard_combat_result", ChildKey: "combat_result"},
 						{ParentKey: "player_hp", ChildKey: "player_hp"},
 					},
+					Params: map[string]any{"enemy_name": "Tomb Guard", "enemy_hp": 3},
 				}},
 			"WEST_SEAL": {ID: "WEST_SEAL", Spec: reflex.NodeSpec{"type": "loot", "autoWriteKey": "has_west_seal", "writeValue": true}},
 			// East wing
@@ -100,6 +121,7 @@ func DungeonCrawlWorkflow() *reflex.Workflow {
 						{ParentKey: "boss_combat_result", ChildKey: "combat_result"},
 						{ParentKey: "player_hp", ChildKey: "player_hp"},
 					},
+					Params: map[string]any{"enemy_name": "The Guardian of Echoes", "enemy_hp": 5},
 				}},
 			"SIDE_EXIT": {ID: "SIDE_EXIT", Spec: reflex.NodeSpec{"type": "narrative"}},
 			"THRONE":    {ID: "THRONE", Spec: reflex.NodeSpec{"type": "loot", "autoWriteKey": "has_crown", "writeValue": true}},
@@ -39,20 +39,56 @@ func (a *DungeonAgent) consumeChoice(expectedKey string) (string, bool) {
 }
 
 // Resolve implements reflex.DecisionAgent.
+// Template instances are dispatched by the template they came from.
 func (a *DungeonAgent) Resolve(_ context.Context, dc reflex.DecisionContext) (reflex.Decision, error) {
-	switch dc.Workflow.ID {
-	case "combat":
+	workflowID := dc.Workflow.ID
+	if template, ok := dc.Workflow.Metadata["template"].(string); ok {
+		workflowID = template
+	}
+	switch WorkflowID(workflowID) {
+	case WorkflowCombat:
 		return a.resolveCombat(dc)
-	case "puzzle-riddle":
+	case WorkflowPuzzleRiddle:
 		return a.resolvePuzzle(dc)
 	default:
 		return a.resolveDungeon(dc)
 	}
 }
 
+// ValidateSpec implements reflex.SpecValidator.
+func (a *DungeonAgent) ValidateSpec(node *reflex.Node) error {
+	spec := node.Spec
+	if err := reflex.CheckSpecKeys(spec, "type", "suspend", "writeKey", "prompt", "autoWriteKey", "writeValue", "ending",
+		"enemyName", "enemyHp"); err != nil {
+		return err
+	}
+	for _, key := range []string{"type", "writeKey", "prompt", "autoWriteKey", "ending", "enemyName"} {
+		if v, ok := spec[key]; ok {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("'%s' must be a string, got %T", key, v)
+			}
+		}
+	}
+	if v, ok := spec["enemyHp"]; ok {
+		if _, ok := v.(int); !ok {
+			return fmt.Errorf("'enemyHp' must be an int, got %T", v)
+		}
+	}
+	if v, ok := spec["suspend"]; ok {
+		suspend, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("'suspend' must be a bool, got %T", v)
+		}
+		if _, ok := spec["writeKey"]; suspend && !ok {
+			return fmt.Errorf("suspending node needs a 'writeKey' for the player's choice")
+		}
+	}
+	return nil
+}
+
 func (a *DungeonAgent) resolveDungeon(dc reflex.DecisionContext) (reflex.Decision, error) {
 	spec := dc.Node.Spec
-	nodeID := dc.Node.ID
+	nodeID := DungeonCrawlNode(dc.Node.ID)
 
 	// Terminal nodes
 	if spec["type"] == "terminal" || len(dc.ValidEdges) == 0 {
@@ -74,24 +110,24 @@ func (a *DungeonAgent) resolveDungeon(dc reflex.DecisionContext) (reflex.Decisio
 		var writes []reflex.BlackboardWrite
 
 		switch {
-		case nodeID == "ARMORY":
+		case nodeID == DungeonCrawlNodeArmory:
 			writes = append(writes, reflex.BlackboardWrite{Key: "armory_choice", Value: choice})
 			if choice == "take" {
 				writes = append(writes, reflex.BlackboardWrite{Key: "has_sword", Value: true})
 			}
-		case nodeID == "ARCHIVES":
+		case nodeID == DungeonCrawlNodeArchives:
 			writes = append(writes, reflex.BlackboardWrite{Key: "archives_choice", Value: choice})
 			if choice == "take" {
 				writes = append(writes, reflex.BlackboardWrite{Key: "has_potion", Value: true})
 			}
-		case nodeID == "GREAT_HALL":
+		case nodeID == DungeonCrawlNodeGreatHall:
 			writes = append(writes, reflex.BlackboardWrite{Key: "hall_choice", Value: choice})
 			if choice == "boss" {
-				if edge := findEdge(dc.ValidEdges, "e-hall-boss"); edge != nil {
+				if edge := findEdge(dc.ValidEdges, string(DungeonCrawlEdgeEHallBoss)); edge != nil {
 					return reflex.Decision{Type: reflex.DecisionAdvance, Edge: edge.ID, Writes: writes}, nil
 				}
 			}
-			if edge := findEdge(dc.ValidEdges, "e-hall-escape"); edge != nil {
+			if edge := findEdge(dc.ValidEdges, string(DungeonCrawlEdgeEHallEscape)); edge != nil {
 				return reflex.Decision{Type: reflex.DecisionAdvance, Edge: edge.ID, Writes: writes}, nil
 			}
 		default:
@@ -111,22 +147,14 @@ func (a *DungeonAgent) resolveDungeon(dc reflex.DecisionContext) (reflex.Decisio
 }
 
 func (a *DungeonAgent) resolveCombat(dc reflex.DecisionContext) (reflex.Decision, error) {
-	nodeID := dc.Node.ID
+	nodeID := CombatNode(dc.Node.ID)
 	bb := dc.Blackboard
 
 	switch nodeID {
-	case "ENCOUNTER":
-		// Determine enemy from parent stack
-		isGuardian := false
-		if len(dc.Stack) > 0 {
-			isGuardian = dc.Stack[0].CurrentNodeID == "BOSS_LAIR"
-		}
-		enemyName := "Tomb Guard"
-		enemyHp := 3
-		if isGuardian {
-			enemyName = "The Guardian of Echoes"
-			enemyHp = 5
-		}
+	case CombatNodeEncounter:
+		// The enemy comes from the combat template's parameters
+		enemyName, _ := dc.Node.Spec["enemyName"].(string)
+		enemyHp, _ := dc.Node.Spec["enemyHp"].(int)
 		playerHp := 8
 		if v, ok := bb.Get("player_hp"); ok {
 			if hp, ok := v.(int); ok {
@@ -140,7 +168,7 @@ func (a *DungeonAgent) resolveCombat(dc reflex.DecisionContext) (reflex.Decision
 		}
 		return reflex.Decision{Type: reflex.DecisionAdvance, Edge: dc.ValidEdges[0].ID, Writes: writes}, nil
 
-	case "PLAYER_TURN":
+	case CombatNodePlayerTurn:
 		action, ok := a.consumeChoice("action")
 		if !ok {
 			return reflex.Decision{Type: reflex.DecisionSuspend, Reason: "Choose your action"}, nil
@@ -148,19 +176,19 @@ func (a *DungeonAgent) resolveCombat(dc reflex.DecisionContext) (reflex.Decision
 		writes := []reflex.BlackboardWrite{{Key: "action", Value: action}}
 		return reflex.Decision{Type: reflex.DecisionAdvance, Edge: dc.ValidEdges[0].ID, Writes: writes}, nil
 
-	case "RESOLVE_ATTACK":
+	case CombatNodeResolveAttack:
 		return a.resolveAttack(dc)
 
-	case "CHECK_OUTCOME":
+	case CombatNodeCheckOutcome:
 		if len(dc.ValidEdges) > 0 {
 			return reflex.Decision{Type: reflex.DecisionAdvance, Edge: dc.ValidEdges[0].ID}, nil
 		}
 		return reflex.Decision{}, fmt.Errorf("no valid edges at CHECK_OUTCOME")
 
-	case "VICTORY_C":
+	case CombatNodeVictoryC:
 		return reflex.Decision{Type: reflex.DecisionComplete,
 			Writes: []reflex.BlackboardWrite{{Key: "combat_result", Value: "victory"}}}, nil
-	case "DEFEAT_C":
+	case CombatNodeDefeatC:
 		return reflex.Decision{Type: reflex.DecisionComplete,
 			Writes: []reflex.BlackboardWrite{{Key: "combat_result", Value: "defeat"}}}, nil
 	}
@@ -234,33 +262,33 @@ func (a *DungeonAgent) resolveAttack(dc reflex.DecisionContext) (reflex.Decision
 }
 
 func (a *DungeonAgent) resolvePuzzle(dc reflex.DecisionContext) (reflex.Decision, error) {
-	nodeID := dc.Node.ID
+	nodeID := PuzzleRiddleNode(dc.Node.ID)
 
 	switch nodeID {
-	case "EXAMINE":
+	case PuzzleRiddleNodeExamine:
 		return reflex.Decision{Type: reflex.DecisionAdvance, Edge: dc.ValidEdges[0].ID}, nil
-	case "ATTEMPT":
+	case PuzzleRiddleNodeAttempt:
 		answer, ok := a.consumeChoice("answer")
 		if !ok {
 			return reflex.Decision{Type: reflex.DecisionSuspend, Reason: "Choose your answer"}, nil
 		}
 		writes := []reflex.BlackboardWrite{{Key: "answer", Value: answer}}
 		if answer == "correct" {
-			edge := findEdge(dc.ValidEdges, "e-attempt-solved")
+			edge := findEdge(dc.ValidEdges, string(PuzzleRiddleEdgeEAttemptSolved))
 			if edge == nil {
 				edge = &dc.ValidEdges[0]
 			}
 			return reflex.Decision{Type: reflex.DecisionAdvance, Edge: edge.ID, Writes: writes}, nil
 		}
-		edge := findEdge(dc.ValidEdges, "e-attempt-failed")
+		edge := findEdge(dc.ValidEdges, string(PuzzleRiddleEdgeEAttemptFailed))
 		if edge == nil {
 			edge = &dc.ValidEdges[0]
 		}
 		return reflex.Decision{Type: reflex.DecisionAdvance, Edge: edge.ID, Writes: writes}, nil
-	case "SOLVED":
+	case PuzzleRiddleNodeSolved:
 		return reflex.Decision{Type: reflex.DecisionComplete,
 			Writes: []reflex.BlackboardWrite{{Key: "puzzle_solved", Value: true}}}, nil
-	case "FAILED":
+	case PuzzleRiddleNodeFailed:
 		return reflex.Decision{Type: reflex.DecisionComplete,
 			Writes: []reflex.BlackboardWrite{{Key: "puzzle_solved", Value: false}}}, nil
 	}
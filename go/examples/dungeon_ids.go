@@ -0,0 +1,100 @@
+// Code generated from reflex workflow definitions. DO NOT EDIT.
+// Source: internal/gendungeon
+
+package examples
+
+// WorkflowID identifies a workflow, or the template of a template instance.
+type WorkflowID string
+
+const (
+	WorkflowCombat       WorkflowID = "combat"
+	WorkflowDungeonCrawl WorkflowID = "dungeon-crawl"
+	WorkflowPuzzleRiddle WorkflowID = "puzzle-riddle"
+)
+
+// CombatNode is a node ID of combat.
+type CombatNode string
+
+const (
+	CombatNodeCheckOutcome  CombatNode = "CHECK_OUTCOME"
+	CombatNodeDefeatC       CombatNode = "DEFEAT_C"
+	CombatNodeEncounter     CombatNode = "ENCOUNTER"
+	CombatNodePlayerTurn    CombatNode = "PLAYER_TURN"
+	CombatNodeResolveAttack CombatNode = "RESOLVE_ATTACK"
+	CombatNodeVictoryC      CombatNode = "VICTORY_C"
+)
+
+// CombatEdge is an edge ID of combat.
+type CombatEdge string
+
+const (
+	CombatEdgeECheckDefeat  CombatEdge = "e-check-defeat"
+	CombatEdgeECheckVictory CombatEdge = "e-check-victory"
+	CombatEdgeEEncTurn      CombatEdge = "e-enc-turn"
+	CombatEdgeEResolveCheck CombatEdge = "e-resolve-check"
+	CombatEdgeETurnResolve  CombatEdge = "e-turn-resolve"
+)
+
+// DungeonCrawlNode is a node ID of dungeon-crawl.
+type DungeonCrawlNode string
+
+const (
+	DungeonCrawlNodeAntechamber DungeonCrawlNode = "ANTECHAMBER"
+	DungeonCrawlNodeArchives    DungeonCrawlNode = "ARCHIVES"
+	DungeonCrawlNodeArmory      DungeonCrawlNode = "ARMORY"
+	DungeonCrawlNodeBossDoor    DungeonCrawlNode = "BOSS_DOOR"
+	DungeonCrawlNodeBossLair    DungeonCrawlNode = "BOSS_LAIR"
+	DungeonCrawlNodeEastSeal    DungeonCrawlNode = "EAST_SEAL"
+	DungeonCrawlNodeEastWing    DungeonCrawlNode = "EAST_WING"
+	DungeonCrawlNodeEntrance    DungeonCrawlNode = "ENTRANCE"
+	DungeonCrawlNodeEscape      DungeonCrawlNode = "ESCAPE"
+	DungeonCrawlNodeGreatHall   DungeonCrawlNode = "GREAT_HALL"
+	DungeonCrawlNodeGuardRoom   DungeonCrawlNode = "GUARD_ROOM"
+	DungeonCrawlNodeLibrary     DungeonCrawlNode = "LIBRARY"
+	DungeonCrawlNodeSideExit    DungeonCrawlNode = "SIDE_EXIT"
+	DungeonCrawlNodeThrone      DungeonCrawlNode = "THRONE"
+	DungeonCrawlNodeVictory     DungeonCrawlNode = "VICTORY"
+	DungeonCrawlNodeWestSeal    DungeonCrawlNode = "WEST_SEAL"
+	DungeonCrawlNodeWestWing    DungeonCrawlNode = "WEST_WING"
+)
+
+// DungeonCrawlEdge is an edge ID of dungeon-crawl.
+type DungeonCrawlEdge string
+
+const (
+	DungeonCrawlEdgeEAnteWest        DungeonCrawlEdge = "e-ante-west"
+	DungeonCrawlEdgeEArchivesSeal    DungeonCrawlEdge = "e-archives-seal"
+	DungeonCrawlEdgeEArmoryGuard     DungeonCrawlEdge = "e-armory-guard"
+	DungeonCrawlEdgeEBossLair        DungeonCrawlEdge = "e-boss-lair"
+	DungeonCrawlEdgeEEntrance        DungeonCrawlEdge = "e-entrance"
+	DungeonCrawlEdgeEEsealHall       DungeonCrawlEdge = "e-eseal-hall"
+	DungeonCrawlEdgeEEwLibrary       DungeonCrawlEdge = "e-ew-library"
+	DungeonCrawlEdgeEGuardSeal       DungeonCrawlEdge = "e-guard-seal"
+	DungeonCrawlEdgeEHallBoss        DungeonCrawlEdge = "e-hall-boss"
+	DungeonCrawlEdgeEHallEscape      DungeonCrawlEdge = "e-hall-escape"
+	DungeonCrawlEdgeELairThrone      DungeonCrawlEdge = "e-lair-throne"
+	DungeonCrawlEdgeELibraryArchives DungeonCrawlEdge = "e-library-archives"
+	DungeonCrawlEdgeESideEscape      DungeonCrawlEdge = "e-side-escape"
+	DungeonCrawlEdgeEThroneVictory   DungeonCrawlEdge = "e-throne-victory"
+	DungeonCrawlEdgeEWsealEast       DungeonCrawlEdge = "e-wseal-east"
+	DungeonCrawlEdgeEWwArmory        DungeonCrawlEdge = "e-ww-armory"
+)
+
+// PuzzleRiddleNode is a node ID of puzzle-riddle.
+type PuzzleRiddleNode string
+
+const (
+	PuzzleRiddleNodeAttempt PuzzleRiddleNode = "ATTEMPT"
+	PuzzleRiddleNodeExamine PuzzleRiddleNode = "EXAMINE"
+	PuzzleRiddleNodeFailed  PuzzleRiddleNode = "FAILED"
+	PuzzleRiddleNodeSolved  PuzzleRiddleNode = "SOLVED"
+)
+
+// PuzzleRiddleEdge is an edge ID of puzzle-riddle.
+type PuzzleRiddleEdge string
+
+const (
+	PuzzleRiddleEdgeEAttemptFailed PuzzleRiddleEdge = "e-attempt-failed"
+	PuzzleRiddleEdgeEAttemptSolved PuzzleRiddleEdge = "e-attempt-solved"
+	PuzzleRiddleEdgeEExamAttempt   PuzzleRiddleEdge = "e-exam-attempt"
+)
@@ -7,12 +7,13 @@ import (
 	reflex "github.com/corpus-relica/reflex/go"
 )
 
-// setupDungeon creates a registry with all three workflows and a fresh engine.
+// setupDungeon creates a registry with the combat template, the other two
+// workflows and a fresh engine.
 func setupDungeon(t *testing.T) (*reflex.Engine, *DungeonAgent) {
 	t.Helper()
 	registry := reflex.CreateRegistry()
+	registry.RegisterTemplate(CombatTemplate())
 	registry.Register(DungeonCrawlWorkflow())
-	registry.Register(CombatWorkflow())
 	registry.Register(PuzzleWorkflow())
 
 	agent := &DungeonAgent{}
@@ -320,6 +321,9 @@ func TestDungeonCombatReadsParentBlackboard(t *testing.T) {
 
 func TestDungeonWorkflowRegistration(t *testing.T) {
 	registry := reflex.CreateRegistry()
+	if err := registry.RegisterTemplate(CombatTemplate()); err != nil {
+		t.Fatalf("combat template registration: %v", err)
+	}
 	if err := registry.Register(DungeonCrawlWorkflow()); err != nil {
 		t.Fatalf("dungeon-crawl registration: %v", err)
 	}
@@ -0,0 +1,77 @@
+package examples
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	reflex "github.com/corpus-relica/reflex/go"
+)
+
+func TestExportDungeonRegistry(t *testing.T) {
+	engine, agent := setupDungeon(t)
+	trace := reflex.NewTrace()
+	engine.OnAll(trace.Record)
+	agent.SetChoice("armory_choice", "take")
+	advanceUntilSuspend(t, engine)
+
+	registry := reflex.CreateRegistry()
+	registry.RegisterTemplate(CombatTemplate())
+	registry.Register(DungeonCrawlWorkflow())
+	registry.Register(PuzzleWorkflow())
+	guard, err := registry.Instantiate("combat", map[string]any{"enemy_name": "Tomb Guard", "enemy_hp": 3})
+	if err != nil {
+		t.Fatalf("instantiate: %v", err)
+	}
+
+	dot := registry.ExportDOT(reflex.ExportOptions{Trace: trace})
+	for _, want := range []string{
+		fmt.Sprintf(`subgraph "cluster_%s" {`, guard.Ref()),
+		`subgraph "cluster_dungeon-crawl@0" {`,
+		`subgraph "cluster_puzzle-riddle@0" {`,
+		fmt.Sprintf(`"dungeon-crawl@0/GUARD_ROOM" -> "%s/ENCOUNTER" [style=dashed, label="guard_combat_result <- combat_result, player_hp <- player_hp"];`, guard.Ref()),
+		`"dungeon-crawl@0/LIBRARY" -> "puzzle-riddle@0/EXAMINE" [style=dashed, label="library_puzzle_solved <- puzzle_solved"];`,
+		`label="VICTORY\n[enemy_defeated == true]"`,
+		`"dungeon-crawl@0/ARMORY" [label="ARMORY", style="rounded,filled", fillcolor="#ffe08a"];`,
+		`shape=component`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("expected %q in DOT output", want)
+		}
+	}
+
+	mermaid := registry.ExportMermaid()
+	for _, want := range []string{
+		`subgraph dungeon_2dcrawl_400 ["dungeon-crawl@0"]`,
+		fmt.Sprintf(`dungeon_2dcrawl_400_2fGUARD__ROOM -.->|"guard_combat_result <- combat_result, player_hp <- player_hp"| %s_400_2fENCOUNTER`, strings.ReplaceAll(guard.ID, "#", "_23")),
+		`dungeon_2dcrawl_400_2fGUARD__ROOM[["GUARD_ROOM<br/>invokes combat<br/>`,
+		fmt.Sprintf(`%s_400_2fVICTORY__C(["VICTORY_C"])`, strings.ReplaceAll(guard.ID, "#", "_23")),
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("expected %q in Mermaid output", want)
+		}
+	}
+}
+
+func TestFlattenDungeon(t *testing.T) {
+	registry := reflex.CreateRegistry()
+	registry.RegisterTemplate(CombatTemplate())
+	registry.Register(PuzzleWorkflow())
+	registry.Register(DungeonCrawlWorkflow())
+
+	flat, err := registry.Flatten("dungeon-crawl")
+	if err != nil {
+		t.Fatalf("flatten: %v", err)
+	}
+	for _, nodeID := range []string{"GUARD_ROOM/ENCOUNTER", "GUARD_ROOM:return", "BOSS_LAIR/VICTORY_C", "LIBRARY/EXAMINE"} {
+		if _, ok := flat.Nodes[nodeID]; !ok {
+			t.Errorf("expected inlined node %s", nodeID)
+		}
+	}
+	if enemy := flat.Nodes["BOSS_LAIR/ENCOUNTER"].Spec["enemyName"]; enemy != "The Guardian of Echoes" {
+		t.Errorf("expected the boss instance inlined, got %v", enemy)
+	}
+	if problems := reflex.NewRegistry().Check(flat).Errors(); len(problems) > 0 {
+		t.Errorf("expected the flattened dungeon to validate, got %v", problems)
+	}
+}
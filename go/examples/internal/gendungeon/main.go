@@ -0,0 +1,34 @@
+// Command gendungeon regenerates dungeon_ids.go from the dungeon workflows.
+// Run it with go generate in the examples directory.
+package main
+
+import (
+	"log"
+
+	reflex "github.com/corpus-relica/reflex/go"
+	"github.com/corpus-relica/reflex/go/codegen"
+	"github.com/corpus-relica/reflex/go/examples"
+)
+
+func main() {
+	registry := reflex.NewRegistry()
+	if err := registry.RegisterTemplate(examples.CombatTemplate()); err != nil {
+		log.Fatal(err)
+	}
+	for _, w := range []*reflex.Workflow{examples.PuzzleWorkflow(), examples.DungeonCrawlWorkflow()} {
+		if err := registry.Register(w); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	src, err := codegen.Generate(codegen.Workflows(registry), codegen.Options{
+		Package: "examples",
+		Source:  "internal/gendungeon",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := codegen.WriteFile("dungeon_ids.go", src); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -45,6 +45,58 @@ func (a *RuleAgent) Resolve(_ context.Context, dc reflex.DecisionContext) (refle
 	return reflex.Decision{Type: reflex.DecisionAdvance, Edge: edgeID, Writes: parseWrites(spec)}, nil
 }
 
+// ValidateSpec implements reflex.SpecValidator. "autoAdvance" is accepted as
+// a marker; without "edge" the agent advances along the single valid edge.
+func (a *RuleAgent) ValidateSpec(node *reflex.Node) error {
+	spec := node.Spec
+	if err := reflex.CheckSpecKeys(spec, "suspend", "complete", "edge", "writes", "autoAdvance"); err != nil {
+		return err
+	}
+	if v, ok := spec["suspend"]; ok {
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("'suspend' must be a reason string, got %T", v)
+		}
+	}
+	for _, key := range []string{"complete", "autoAdvance"} {
+		if v, ok := spec[key]; ok {
+			if _, ok := v.(bool); !ok {
+				return fmt.Errorf("'%s' must be a bool, got %T", key, v)
+			}
+		}
+	}
+	if v, ok := spec["edge"]; ok {
+		switch e := v.(type) {
+		case string, []string:
+		case []any:
+			for _, candidate := range e {
+				if _, ok := candidate.(string); !ok {
+					return fmt.Errorf("'edge' entries must be strings, got %T", candidate)
+				}
+			}
+		default:
+			return fmt.Errorf("'edge' must be a string or list of strings, got %T", v)
+		}
+	}
+	if v, ok := spec["writes"]; ok {
+		switch w := v.(type) {
+		case []reflex.BlackboardWrite:
+		case []any:
+			for _, item := range w {
+				m, ok := item.(map[string]any)
+				if !ok {
+					return fmt.Errorf("'writes' entries must be objects, got %T", item)
+				}
+				if _, ok := m["key"].(string); !ok {
+					return fmt.Errorf("'writes' entries need a string 'key'")
+				}
+			}
+		default:
+			return fmt.Errorf("'writes' must be a list of writes, got %T", v)
+		}
+	}
+	return nil
+}
+
 func resolveEdge(spec reflex.NodeSpec, validEdges []reflex.Edge) (string, error) {
 	edge := spec["edge"]
 	switch e := edge.(type) {
@@ -0,0 +1,134 @@
+package examples
+
+import (
+	"errors"
+	"testing"
+
+	reflex "github.com/corpus-relica/reflex/go"
+)
+
+func codesOf(problems reflex.ValidationErrors) []reflex.ValidationErrorCode {
+	var codes []reflex.ValidationErrorCode
+	for _, p := range problems {
+		codes = append(codes, p.Code)
+	}
+	return codes
+}
+
+func TestValidationCleanWorkflowsHaveNoProblems(t *testing.T) {
+	r := reflex.NewRegistry()
+	parent, child := ParentChildWorkflows()
+	_ = r.Register(child)
+	for _, w := range []*reflex.Workflow{LinearWorkflow("linear"), parent, SuspensionWorkflow(), PuzzleWorkflow()} {
+		if problems := r.Check(w); len(problems) != 0 {
+			t.Errorf("%s: expected no problems, got %v", w.ID, codesOf(problems))
+		}
+	}
+}
+
+func TestValidationPotentialDeadEnd(t *testing.T) {
+	r := reflex.NewRegistry()
+
+	problems := r.Check(CombatWorkflow())
+	if problems.HasErrors() {
+		t.Fatalf("expected only warnings, got %v", problems.Errors())
+	}
+	warnings := problems.Warnings()
+	if len(warnings) != 1 || warnings[0].Code != reflex.ErrPotentialDeadEnd {
+		t.Fatalf("expected one dead-end warning, got %v", codesOf(warnings))
+	}
+	if warnings[0].Details["nodeId"] != "CHECK_OUTCOME" {
+		t.Errorf("expected CHECK_OUTCOME, got %v", warnings[0].Details["nodeId"])
+	}
+
+	warnings = r.Check(BranchingWorkflow()).Warnings()
+	if len(warnings) != 1 || warnings[0].Details["nodeId"] != "DECIDE" {
+		t.Errorf("expected dead-end warning on DECIDE, got %v", warnings)
+	}
+
+	if err := r.Register(CombatWorkflow()); err != nil {
+		t.Errorf("warnings must not block registration, got %v", err)
+	}
+
+	gated := LinearWorkflow("gated")
+	gated.Edges[0].Guard = reflex.Exists("ready")
+	if warnings := r.Check(gated).Warnings(); len(warnings) != 0 {
+		t.Errorf("expected a single guarded edge not reported, got %v", codesOf(warnings))
+	}
+}
+
+func TestValidationUnreachableNode(t *testing.T) {
+	w := LinearWorkflow("linear")
+	w.Nodes["ORPHAN"] = &reflex.Node{ID: "ORPHAN", Spec: reflex.NodeSpec{}}
+
+	warnings := reflex.NewRegistry().Check(w).Warnings()
+	if len(warnings) != 1 || warnings[0].Code != reflex.ErrUnreachableNode || warnings[0].Details["nodeId"] != "ORPHAN" {
+		t.Errorf("expected ORPHAN unreachable, got %v", warnings)
+	}
+}
+
+func TestValidationEdgeIDs(t *testing.T) {
+	w := DungeonCrawlWorkflow()
+	w.Edges[1].ID = w.Edges[0].ID
+	w.Edges[2].ID = ""
+
+	r := reflex.NewRegistry(reflex.RegistryOptions{ValidationMode: reflex.ValidateAll})
+	err := r.Register(w)
+	var problems reflex.ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	errs := problems.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", codesOf(errs))
+	}
+	if errs[0].Code != reflex.ErrDuplicateEdgeID || errs[0].Details["edgeId"] != w.Edges[0].ID {
+		t.Errorf("expected duplicate %s, got %s %v", w.Edges[0].ID, errs[0].Code, errs[0].Details)
+	}
+	if errs[1].Code != reflex.ErrEmptyEdgeID || errs[1].Details["index"] != 2 {
+		t.Errorf("expected empty edge ID at index 2, got %s %v", errs[1].Code, errs[1].Details)
+	}
+}
+
+func TestRuleAgentSpecLint(t *testing.T) {
+	r := reflex.NewRegistry()
+	r.AddValidator(reflex.SpecLinter(NewRuleAgent()))
+
+	parent, child := ParentChildWorkflows()
+	for _, w := range []*reflex.Workflow{LinearWorkflow("linear"), BranchingWorkflow(), child, parent, SuspensionWorkflow()} {
+		if err := r.Register(w); err != nil {
+			t.Errorf("%s: %v", w.ID, err)
+		}
+	}
+
+	typo := LinearWorkflow("typo")
+	typo.Nodes["C"].Spec = reflex.NodeSpec{"compelte": true}
+	err := r.Register(typo)
+	var ve *reflex.ValidationError
+	if !errors.As(err, &ve) || ve.Code != reflex.ErrInvalidNodeSpec || ve.Details["nodeId"] != "C" {
+		t.Fatalf("expected INVALID_NODE_SPEC at C, got %v", err)
+	}
+
+	wrongType := LinearWorkflow("wrong-type")
+	wrongType.Nodes["A"].Spec = reflex.NodeSpec{"suspend": true}
+	if err := r.Register(wrongType); err == nil {
+		t.Error("expected non-string suspend reason to be rejected")
+	}
+}
+
+func TestDungeonAgentSpecLint(t *testing.T) {
+	r := reflex.NewRegistry()
+	r.AddValidator(reflex.SpecLinter(&DungeonAgent{}))
+	for _, w := range []*reflex.Workflow{CombatWorkflow(), PuzzleWorkflow(), DungeonCrawlWorkflow()} {
+		if err := r.Register(w); err != nil {
+			t.Errorf("%s: %v", w.ID, err)
+		}
+	}
+
+	bad := PuzzleWorkflow()
+	bad.ID = "bad-puzzle"
+	bad.Nodes["ATTEMPT"].Spec = reflex.NodeSpec{"type": "attempt", "suspend": true, "writekey": "answer"}
+	if err := r.Register(bad); err == nil {
+		t.Error("expected misspelled writeKey to be rejected")
+	}
+}
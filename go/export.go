@@ -0,0 +1,396 @@
+package reflex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Graphviz DOT and Mermaid export
+// ---------------------------------------------------------------------------
+
+// ExportOptions configures ExportDOT and ExportMermaid.
+type ExportOptions struct {
+	// Trace, if set, highlights the nodes a session visited and the edges it
+	// traversed.
+	Trace *Trace
+}
+
+// Trace records the path of a session for export overlays. Register its
+// Record method on an engine:
+//
+//	trace := reflex.NewTrace()
+//	engine.OnAll(trace.Record)
+type Trace struct {
+	nodes map[string]map[string]bool // workflow ref → node ID
+	edges map[string]map[string]bool // workflow ref → edge ID
+}
+
+// NewTrace returns an empty trace.
+func NewTrace() *Trace {
+	return &Trace{nodes: map[string]map[string]bool{}, edges: map[string]map[string]bool{}}
+}
+
+// Record adds node:enter and edge:traverse events to the trace. Other events
+// are ignored.
+func (t *Trace) Record(ev Event) {
+	switch ev.Type {
+	case EventNodeEnter:
+		mark(t.nodes, workflowRef(ev.WorkflowID, ev.WorkflowVersion), ev.NodeID)
+	case EventEdgeTraverse:
+		mark(t.edges, workflowRef(ev.WorkflowID, ev.WorkflowVersion), ev.EdgeID)
+	}
+}
+
+// Visited reports whether the session entered the node of the workflow
+// version ref ("id@version"), so that versions sharing node IDs are told
+// apart.
+func (t *Trace) Visited(ref, nodeID string) bool {
+	return t != nil && t.nodes[ref][nodeID]
+}
+
+// Traversed reports whether the session took the edge of the workflow
+// version ref ("id@version").
+func (t *Trace) Traversed(ref, edgeID string) bool {
+	return t != nil && t.edges[ref][edgeID]
+}
+
+func mark(sets map[string]map[string]bool, ref, id string) {
+	if sets[ref] == nil {
+		sets[ref] = map[string]bool{}
+	}
+	sets[ref][id] = true
+}
+
+// DescribeGuard returns a short human-readable form of a guard, such as
+// "hp == 0" or "exists(has_sword)". Custom guards are described by Name.
+func DescribeGuard(g Guard) string {
+	switch guard := g.(type) {
+	case nil:
+		return ""
+	case *BuiltinGuard:
+		switch guard.Type {
+		case GuardExists:
+			return fmt.Sprintf("exists(%s)", guard.Key)
+		case GuardNotExists:
+			return fmt.Sprintf("!exists(%s)", guard.Key)
+		case GuardEquals:
+			return fmt.Sprintf("%s == %v", guard.Key, guard.Value)
+		case GuardNotEquals:
+			return fmt.Sprintf("%s != %v", guard.Key, guard.Value)
+		}
+		return string(guard.Type)
+	case *CustomGuardFunc:
+		if guard.Name != "" {
+			return "custom: " + guard.Name
+		}
+		return "custom"
+	default:
+		return fmt.Sprintf("%T", g)
+	}
+}
+
+// ExportDOT renders a workflow as a Graphviz digraph. The entry node is
+// marked by an arrow from a start point, terminal nodes have a double border,
+// invocation nodes use the component shape and name their sub-workflow and
+// returnMap. Edges are labelled with their event and guard.
+func ExportDOT(w *Workflow, opts ...ExportOptions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(w.ID))
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	writeDOTWorkflow(&b, w, "", "  ", exportOptions(opts))
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ExportMermaid renders a workflow as a Mermaid flowchart, with the same
+// conventions as ExportDOT.
+func ExportMermaid(w *Workflow, opts ...ExportOptions) string {
+	m := &mermaidWriter{trace: exportOptions(opts).Trace}
+	m.b.WriteString("flowchart TD\n")
+	m.writeWorkflow(w, "", "  ")
+	m.finish()
+	return m.b.String()
+}
+
+// ExportDOT renders every registered workflow version as a cluster and links
+// each invocation node to the entry of the sub-workflow it resolves to, with
+// the returnMap as label.
+func (r *Registry) ExportDOT(opts ...ExportOptions) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	o := exportOptions(opts)
+
+	var b strings.Builder
+	b.WriteString("digraph registry {\n")
+	b.WriteString("  compound=true;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	for _, w := range r.allWorkflows() {
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotQuote("cluster_"+w.Ref()))
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(w.Ref()))
+		writeDOTWorkflow(&b, w, w.Ref()+"/", "    ", o)
+		b.WriteString("  }\n")
+	}
+	for _, link := range r.invocationLinks() {
+		fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=%s];\n",
+			dotQuote(link.from.Ref()+"/"+link.nodeID),
+			dotQuote(link.to.Ref()+"/"+link.to.Entry),
+			dotQuote(describeReturnMap(link.returnMap)))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ExportMermaid renders every registered workflow version as a subgraph,
+// linked as in Registry.ExportDOT.
+func (r *Registry) ExportMermaid(opts ...ExportOptions) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m := &mermaidWriter{trace: exportOptions(opts).Trace}
+	m.b.WriteString("flowchart TD\n")
+	for _, w := range r.allWorkflows() {
+		fmt.Fprintf(&m.b, "  subgraph %s [%s]\n", mermaidID(w.Ref()), mermaidQuote(w.Ref()))
+		m.writeWorkflow(w, w.Ref()+"/", "    ")
+		m.b.WriteString("  end\n")
+	}
+	for _, link := range r.invocationLinks() {
+		m.link(mermaidID(link.from.Ref()+"/"+link.nodeID), "-.->", describeReturnMap(link.returnMap),
+			mermaidID(link.to.Ref()+"/"+link.to.Entry), false)
+	}
+	m.finish()
+	return m.b.String()
+}
+
+func exportOptions(opts []ExportOptions) ExportOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return ExportOptions{}
+}
+
+// allWorkflows returns every registered version in ID, then version, order.
+// Caller must hold r.mu.
+func (r *Registry) allWorkflows() []*Workflow {
+	var result []*Workflow
+	for _, id := range r.sortedIDs() {
+		result = append(result, r.workflows[id]...)
+	}
+	return result
+}
+
+type invocationLink struct {
+	from      *Workflow
+	nodeID    string
+	to        *Workflow
+	returnMap []ReturnMapping
+}
+
+// invocationLinks returns the resolvable invocations of every registered
+// workflow. Caller must hold r.mu.
+func (r *Registry) invocationLinks() []invocationLink {
+	var links []invocationLink
+	for _, w := range r.allWorkflows() {
+		for _, nodeID := range sortedNodeIDs(w) {
+			node := w.Nodes[nodeID]
+			if node.Invokes == nil {
+				continue
+			}
+			if child, ok := r.invocationTarget(node.Invokes); ok {
+				links = append(links, invocationLink{from: w, nodeID: nodeID, to: child, returnMap: node.Invokes.ReturnMap})
+			}
+		}
+	}
+	return links
+}
+
+func describeReturnMap(returnMap []ReturnMapping) string {
+	parts := make([]string, len(returnMap))
+	for i, m := range returnMap {
+		parts[i] = m.ParentKey + " <- " + m.ChildKey
+	}
+	return strings.Join(parts, ", ")
+}
+
+func nodeLabel(node *Node) string {
+	if info, ok := node.Spec[FlattenKey].(*FlattenInfo); ok {
+		return flattenedNodeLabel(node.ID, info)
+	}
+	if node.Invokes == nil {
+		return node.ID
+	}
+	label := node.ID + "\ninvokes " + node.Invokes.WorkflowID
+	if len(node.Invokes.ReturnMap) > 0 {
+		label += "\n" + describeReturnMap(node.Invokes.ReturnMap)
+	}
+	return label
+}
+
+// flattenedNodeLabel labels the nodes Flatten annotates.
+func flattenedNodeLabel(id string, info *FlattenInfo) string {
+	var label string
+	switch info.Kind {
+	case FlattenCall:
+		return id + "\ninlines " + info.Workflow
+	case FlattenTruncated:
+		label = id + "\ninvokes " + info.Workflow + " (not inlined)"
+	default:
+		label = id + "\nreturns from " + info.Workflow
+	}
+	if len(info.ReturnMap) > 0 {
+		label += "\n" + describeReturnMap(info.ReturnMap)
+	}
+	return label
+}
+
+func edgeLabel(edge Edge) string {
+	if edge.Guard == nil {
+		return edge.Event
+	}
+	return edge.Event + "\n[" + DescribeGuard(edge.Guard) + "]"
+}
+
+func isTerminal(w *Workflow, nodeID string) bool {
+	return len(w.Successors(nodeID)) == 0
+}
+
+// ---------------------------------------------------------------------------
+// DOT
+// ---------------------------------------------------------------------------
+
+func writeDOTWorkflow(b *strings.Builder, w *Workflow, prefix, indent string, o ExportOptions) {
+	start := dotQuote(prefix + "__start")
+	fmt.Fprintf(b, "%s%s [shape=point];\n", indent, start)
+	fmt.Fprintf(b, "%s%s -> %s;\n", indent, start, dotQuote(prefix+w.Entry))
+
+	for _, nodeID := range sortedNodeIDs(w) {
+		node := w.Nodes[nodeID]
+		attrs := []string{"label=" + dotQuote(nodeLabel(node))}
+		if node.Invokes != nil {
+			attrs = append(attrs, "shape=component")
+		}
+		if isTerminal(w, nodeID) {
+			attrs = append(attrs, "peripheries=2")
+		}
+		if o.Trace.Visited(w.Ref(), nodeID) {
+			attrs = append(attrs, `style="rounded,filled"`, `fillcolor="#ffe08a"`)
+		}
+		fmt.Fprintf(b, "%s%s [%s];\n", indent, dotQuote(prefix+nodeID), strings.Join(attrs, ", "))
+	}
+	for _, edge := range w.Edges {
+		attrs := []string{"label=" + dotQuote(edgeLabel(edge))}
+		if o.Trace.Traversed(w.Ref(), edge.ID) {
+			attrs = append(attrs, `color="#d9480f"`, "penwidth=2.5")
+		}
+		fmt.Fprintf(b, "%s%s -> %s [%s];\n", indent,
+			dotQuote(prefix+edge.From), dotQuote(prefix+edge.To), strings.Join(attrs, ", "))
+	}
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// ---------------------------------------------------------------------------
+// Mermaid
+// ---------------------------------------------------------------------------
+
+type mermaidWriter struct {
+	b         strings.Builder
+	trace     *Trace
+	links     int
+	taken     []string
+	visited   []string
+	terminals []string
+	invokes   []string
+}
+
+func (m *mermaidWriter) writeWorkflow(w *Workflow, prefix, indent string) {
+	start := mermaidID(prefix) + "_start"
+	fmt.Fprintf(&m.b, "%s%s((\" \"))\n", indent, start)
+	m.link(start, "-->", "", mermaidID(prefix+w.Entry), false)
+
+	for _, nodeID := range sortedNodeIDs(w) {
+		node := w.Nodes[nodeID]
+		id := mermaidID(prefix + nodeID)
+		label := mermaidQuote(nodeLabel(node))
+		switch {
+		case node.Invokes != nil:
+			fmt.Fprintf(&m.b, "%s%s[[%s]]\n", indent, id, label)
+			m.invokes = append(m.invokes, id)
+		case isTerminal(w, nodeID):
+			fmt.Fprintf(&m.b, "%s%s([%s])\n", indent, id, label)
+		default:
+			fmt.Fprintf(&m.b, "%s%s[%s]\n", indent, id, label)
+		}
+		if isTerminal(w, nodeID) {
+			m.terminals = append(m.terminals, id)
+		}
+		if m.trace.Visited(w.Ref(), nodeID) {
+			m.visited = append(m.visited, id)
+		}
+	}
+	for _, edge := range w.Edges {
+		m.link(mermaidID(prefix+edge.From), "-->", edgeLabel(edge), mermaidID(prefix+edge.To),
+			m.trace.Traversed(w.Ref(), edge.ID))
+	}
+}
+
+// link writes a link and tracks its index, which linkStyle refers to.
+func (m *mermaidWriter) link(from, arrow, label, to string, taken bool) {
+	if label == "" {
+		fmt.Fprintf(&m.b, "  %s %s %s\n", from, arrow, to)
+	} else {
+		fmt.Fprintf(&m.b, "  %s %s|%s| %s\n", from, arrow, mermaidQuote(label), to)
+	}
+	if taken {
+		m.taken = append(m.taken, fmt.Sprint(m.links))
+	}
+	m.links++
+}
+
+func (m *mermaidWriter) finish() {
+	m.b.WriteString("  classDef terminal stroke-width:3px\n")
+	m.b.WriteString("  classDef invocation fill:#e7f0fd,stroke:#4a6fa5\n")
+	m.b.WriteString("  classDef visited fill:#ffe08a,stroke:#b8860b\n")
+	for _, class := range []struct {
+		name string
+		ids  []string
+	}{{"terminal", m.terminals}, {"invocation", m.invokes}, {"visited", m.visited}} {
+		if len(class.ids) > 0 {
+			fmt.Fprintf(&m.b, "  class %s %s\n", strings.Join(class.ids, ","), class.name)
+		}
+	}
+	if len(m.taken) > 0 {
+		fmt.Fprintf(&m.b, "  linkStyle %s stroke:#d9480f,stroke-width:3px\n", strings.Join(m.taken, ","))
+	}
+}
+
+// mermaidID turns s into a Mermaid node ID. Letters and digits are kept, "_"
+// becomes "__" and every other byte "_" and two hex digits, so distinct
+// strings such as "e-hall" and "e_hall" never share an ID. A suffix starting
+// with "_" and a letter past "f" cannot be produced, which is how the writer
+// names its synthetic start nodes.
+func mermaidID(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9':
+			b.WriteByte(c)
+		case c == '_':
+			b.WriteString("__")
+		default:
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return `"` + s + `"`
+}
@@ -0,0 +1,184 @@
+package reflex
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDescribeGuard(t *testing.T) {
+	cases := []struct {
+		guard Guard
+		want  string
+	}{
+		{&BuiltinGuard{Type: GuardExists, Key: "k"}, "exists(k)"},
+		{&BuiltinGuard{Type: GuardNotExists, Key: "k"}, "!exists(k)"},
+		{&BuiltinGuard{Type: GuardEquals, Key: "hp", Value: 0}, "hp == 0"},
+		{&BuiltinGuard{Type: GuardNotEquals, Key: "mode", Value: "x"}, "mode != x"},
+		{&CustomGuardFunc{Name: "hasBothSeals"}, "custom: hasBothSeals"},
+		{&CustomGuardFunc{}, "custom"},
+	}
+	for _, tc := range cases {
+		if got := DescribeGuard(tc.guard); got != tc.want {
+			t.Errorf("expected %q, got %q", tc.want, got)
+		}
+	}
+}
+
+func branchingExportWorkflow() *Workflow {
+	return &Workflow{
+		ID:    "branch",
+		Entry: "START",
+		Nodes: map[string]*Node{
+			"START": {ID: "START", Spec: NodeSpec{}},
+			"LEFT":  {ID: "LEFT", Spec: NodeSpec{}},
+			"RIGHT": {ID: "RIGHT", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{
+			{ID: "e-left", From: "START", To: "LEFT", Event: "GO",
+				Guard: &BuiltinGuard{Type: GuardEquals, Key: "dir", Value: "left"}},
+			{ID: "e-right", From: "START", To: "RIGHT", Event: "GO"},
+		},
+	}
+}
+
+func TestExportDOT(t *testing.T) {
+	out := ExportDOT(branchingExportWorkflow())
+	for _, want := range []string{
+		`digraph "branch" {`,
+		`"__start" -> "START";`,
+		`"LEFT" [label="LEFT", peripheries=2];`,
+		`"START" [label="START"];`,
+		`"START" -> "LEFT" [label="GO\n[dir == left]"];`,
+		`"START" -> "RIGHT" [label="GO"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestExportMermaid(t *testing.T) {
+	out := ExportMermaid(branchingExportWorkflow())
+	for _, want := range []string{
+		"flowchart TD\n",
+		`_start __> START`,
+		`LEFT(["LEFT"])`,
+		`START["START"]`,
+		`START -->|"GO<br/>[dir == left]"| LEFT`,
+		"class LEFT,RIGHT terminal",
+	} {
+		want = strings.ReplaceAll(want, "__>", "-->")
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "linkStyle") {
+		t.Error("no trace given, expected no highlighted links")
+	}
+}
+
+func TestExportTraceOverlay(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(branchingExportWorkflow())
+	e := NewEngine(r, autoAdvanceAgent())
+	trace := NewTrace()
+	e.OnAll(trace.Record)
+	_, _ = e.Init("branch")
+	_, _ = e.Run(context.Background())
+
+	if !trace.Visited("branch@0", "RIGHT") || trace.Visited("branch@0", "LEFT") || !trace.Traversed("branch@0", "e-right") {
+		t.Fatal("trace did not record the session path")
+	}
+
+	dot := ExportDOT(mustGet(t, r, "branch"), ExportOptions{Trace: trace})
+	if !strings.Contains(dot, `"RIGHT" [label="RIGHT", peripheries=2, style="rounded,filled", fillcolor="#ffe08a"];`) {
+		t.Errorf("expected RIGHT highlighted in:\n%s", dot)
+	}
+	if !strings.Contains(dot, `"START" -> "RIGHT" [label="GO", color="#d9480f", penwidth=2.5];`) {
+		t.Errorf("expected e-right highlighted in:\n%s", dot)
+	}
+
+	mermaid := ExportMermaid(mustGet(t, r, "branch"), ExportOptions{Trace: trace})
+	// Link 0 is the start arrow, 1 e-left, 2 e-right.
+	if !strings.Contains(mermaid, "class RIGHT,START visited") || !strings.Contains(mermaid, "linkStyle 2 ") {
+		t.Errorf("expected trace overlay in:\n%s", mermaid)
+	}
+}
+
+func TestMermaidIDInjective(t *testing.T) {
+	seen := map[string]string{}
+	for _, s := range []string{"e-hall", "e_hall", "e.hall", "e__hall", "e_2dhall", "_start", ""} {
+		id := mermaidID(s)
+		if other, ok := seen[id]; ok {
+			t.Errorf("%q and %q both map to %q", s, other, id)
+		}
+		seen[id] = s
+	}
+	if id := mermaidID(""); id+"_start" == mermaidID("_start") {
+		t.Error("start node collides with a node named _start")
+	}
+}
+
+func TestExportTraceVersions(t *testing.T) {
+	r := NewRegistry()
+	v1 := branchingExportWorkflow()
+	v1.Version = 1
+	_ = r.Register(v1)
+	e := NewEngine(r, autoAdvanceAgent())
+	trace := NewTrace()
+	e.OnAll(trace.Record)
+	_, _ = e.Init("branch@1")
+	_, _ = e.Run(context.Background())
+
+	v2 := branchingExportWorkflow()
+	v2.Version = 2
+	_ = r.Register(v2)
+	if !trace.Visited("branch@1", "RIGHT") || trace.Visited("branch@2", "RIGHT") {
+		t.Error("expected the trace to be kept per version")
+	}
+	if strings.Contains(ExportMermaid(v2, ExportOptions{Trace: trace}), " visited\n") {
+		t.Error("expected no overlay on a version the session did not run")
+	}
+}
+
+func TestExportTraceInvocationVersions(t *testing.T) {
+	r := NewRegistry()
+	child := linearWorkflow("child")
+	child.Version = 2
+	parent := invokerWorkflow("parent", "child")
+	parent.Version = 3
+	for _, w := range []*Workflow{child, parent} {
+		if err := r.Register(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := NewEngine(r, autoAdvanceAgent())
+	trace := NewTrace()
+	e.OnAll(trace.Record)
+	_, _ = e.Init("parent")
+	if result, err := e.Run(context.Background()); err != nil || result.Status != StepCompleted {
+		t.Fatalf("expected completion, got %+v, %v", result, err)
+	}
+
+	// The child's entry is entered on push and the invoking node again on
+	// pop; both must be recorded under the running versions.
+	if !trace.Visited("child@2", "A") || !trace.Visited("parent@3", "A") {
+		t.Error("expected the child entry and the invoking node recorded under their versions")
+	}
+	if trace.Visited("child@0", "A") || trace.Visited("parent@0", "A") {
+		t.Error("expected nothing recorded under version 0")
+	}
+	if dot := ExportDOT(child, ExportOptions{Trace: trace}); !strings.Contains(dot, `"A" [label="A", style="rounded,filled", fillcolor="#ffe08a"];`) {
+		t.Errorf("expected the child entry highlighted in:\n%s", dot)
+	}
+}
+
+func mustGet(t *testing.T, r *Registry, ref string) *Workflow {
+	t.Helper()
+	w, ok := r.Get(ref)
+	if !ok {
+		t.Fatalf("workflow %s not registered", ref)
+	}
+	return w
+}
@@ -0,0 +1,183 @@
+package reflex
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ---------------------------------------------------------------------------
+// Workflow extension
+// ---------------------------------------------------------------------------
+
+// Extension derives a workflow from a registered base by overlaying changes.
+// They are applied in field order: removals first, then added or replaced
+// nodes and edges, then spec merges. The result is an ordinary workflow.
+type Extension struct {
+	ID      string
+	Version int
+	// Base references the workflow to derive from, as "id" (latest) or
+	// "id@version".
+	Base string
+	// Entry replaces the base's entry node when set.
+	Entry string
+	// RemoveNodes drops base nodes together with every edge from or to them.
+	RemoveNodes []string
+	// RemoveEdges drops base edges by ID.
+	RemoveEdges []string
+	// Nodes adds nodes, or replaces the base node with the same ID.
+	Nodes map[string]*Node
+	// Edges adds edges, or replaces the base edge with the same ID in place.
+	Edges []Edge
+	// MergeSpecs sets keys in the NodeSpec of existing nodes. A nil value
+	// deletes the key.
+	MergeSpecs map[string]NodeSpec
+	// Metadata is merged over the base's metadata. The "template" and
+	// "params" keys of a template instance are not inherited: the result is
+	// not an instance.
+	Metadata map[string]any
+}
+
+// Extend resolves ext against its registered base and registers the result,
+// which is validated like any other workflow. The result's Metadata records
+// the base's ref under "base" and its fingerprint under "baseFingerprint".
+// Returns an ErrWorkflowNotFound error if the base is not registered and
+// ErrInvalidExtension errors for overlays naming nodes or edges that do not
+// exist.
+func (r *Registry) Extend(ext *Extension) (*Workflow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	base, ok := r.lookup(ext.Base)
+	if !ok {
+		return nil, newValidationError(ErrWorkflowNotFound, ext.ID,
+			fmt.Sprintf("cannot extend: base workflow '%s' is not registered", ext.Base))
+	}
+	w, err := ext.Apply(base)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.register(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Apply derives the workflow from base without registering it.
+// Registry.Extend is the usual entry point.
+func (ext *Extension) Apply(base *Workflow) (*Workflow, error) {
+	fingerprint, err := base.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workflow{
+		ID:            ext.ID,
+		Entry:         base.Entry,
+		Nodes:         make(map[string]*Node, len(base.Nodes)+len(ext.Nodes)),
+		Metadata:      make(map[string]any),
+		Version:       ext.Version,
+		SensitiveKeys: slices.Clone(base.SensitiveKeys),
+		Outputs:       slices.Clone(base.Outputs),
+	}
+	if ext.Entry != "" {
+		w.Entry = ext.Entry
+	}
+	maps.Copy(w.Metadata, base.Metadata)
+	delete(w.Metadata, "template")
+	delete(w.Metadata, "params")
+	maps.Copy(w.Metadata, ext.Metadata)
+	w.Metadata["base"] = base.Ref()
+	w.Metadata["baseFingerprint"] = fingerprint
+
+	var problems ValidationErrors
+	removed := make(map[string]bool, len(ext.RemoveNodes))
+	for _, nodeID := range ext.RemoveNodes {
+		if _, ok := base.Nodes[nodeID]; !ok {
+			problems = append(problems, ext.problem("nodeId", nodeID,
+				fmt.Sprintf("workflow '%s': cannot remove node '%s', not in base '%s'", ext.ID, nodeID, base.Ref())))
+		}
+		removed[nodeID] = true
+	}
+	for nodeID, node := range base.Nodes {
+		if !removed[nodeID] {
+			w.Nodes[nodeID] = cloneNode(node)
+		}
+	}
+
+	droppedEdges := make(map[string]bool, len(ext.RemoveEdges))
+	for _, edgeID := range ext.RemoveEdges {
+		if !slices.ContainsFunc(base.Edges, func(e Edge) bool { return e.ID == edgeID }) {
+			problems = append(problems, ext.problem("edgeId", edgeID,
+				fmt.Sprintf("workflow '%s': cannot remove edge '%s', not in base '%s'", ext.ID, edgeID, base.Ref())))
+		}
+		droppedEdges[edgeID] = true
+	}
+	for _, edge := range base.Edges {
+		if !droppedEdges[edge.ID] && !removed[edge.From] && !removed[edge.To] {
+			w.Edges = append(w.Edges, edge)
+		}
+	}
+
+	for _, nodeID := range sortedKeys(ext.Nodes) {
+		node := cloneNode(ext.Nodes[nodeID])
+		if node.ID == "" {
+			node.ID = nodeID
+		}
+		w.Nodes[nodeID] = node
+	}
+	for _, edge := range ext.Edges {
+		if i := slices.IndexFunc(w.Edges, func(e Edge) bool { return e.ID == edge.ID }); i >= 0 {
+			w.Edges[i] = edge
+		} else {
+			w.Edges = append(w.Edges, edge)
+		}
+	}
+
+	for _, nodeID := range sortedKeys(ext.MergeSpecs) {
+		node, ok := w.Nodes[nodeID]
+		if !ok {
+			problems = append(problems, ext.problem("nodeId", nodeID,
+				fmt.Sprintf("workflow '%s': cannot merge spec into node '%s', which does not exist", ext.ID, nodeID)))
+			continue
+		}
+		if node.Spec == nil {
+			node.Spec = NodeSpec{}
+		}
+		for k, v := range ext.MergeSpecs[nodeID] {
+			if v == nil {
+				delete(node.Spec, k)
+			} else {
+				node.Spec[k] = v
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return w, nil
+}
+
+func (ext *Extension) problem(detail, id, msg string) *ValidationError {
+	err := newValidationError(ErrInvalidExtension, ext.ID, msg)
+	err.Details = map[string]any{detail: id}
+	return err
+}
+
+// cloneNode copies a node deeply enough that changing the copy's spec,
+// invocation or contracts leaves the original untouched. Spec values are
+// shared.
+func cloneNode(node *Node) *Node {
+	cp := *node
+	cp.Spec = maps.Clone(node.Spec)
+	if node.Invokes != nil {
+		inv := *node.Invokes
+		inv.ReturnMap = slices.Clone(inv.ReturnMap)
+		inv.Params = maps.Clone(inv.Params)
+		cp.Invokes = &inv
+	}
+	cp.Inputs = slices.Clone(node.Inputs)
+	cp.Outputs = slices.Clone(node.Outputs)
+	return &cp
+}
@@ -0,0 +1,145 @@
+package reflex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRegistryExtend(t *testing.T) {
+	r := NewRegistry()
+	base := linearWorkflow("base")
+	base.Nodes["B"].Spec = NodeSpec{"prompt": "review", "retries": 1}
+	base.Metadata = map[string]any{"owner": "ops"}
+	if err := r.Register(base); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := r.Extend(&Extension{
+		ID:   "approval",
+		Base: "base",
+		Nodes: map[string]*Node{
+			"APPROVE": {Spec: NodeSpec{"type": "approval"}},
+		},
+		Edges: []Edge{
+			{ID: "e1", From: "A", To: "APPROVE", Event: "NEXT"},
+			{ID: "e3", From: "APPROVE", To: "B", Event: "APPROVED", Guard: Equals("approved", true)},
+			{ID: "e4", From: "APPROVE", To: "C", Event: "NEXT"},
+		},
+		MergeSpecs: map[string]NodeSpec{"B": {"prompt": "final review", "retries": nil}},
+		Metadata:   map[string]any{"team": "finance"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Has("approval") {
+		t.Error("expected the derived workflow to be registered")
+	}
+	if w.Nodes["APPROVE"].ID != "APPROVE" {
+		t.Errorf("expected the node ID filled from its key, got %q", w.Nodes["APPROVE"].ID)
+	}
+	if got := w.Successors("A"); !reflect.DeepEqual(got, []string{"APPROVE"}) {
+		t.Errorf("expected e1 replaced in place, got successors %v", got)
+	}
+	if len(w.Edges) != 4 || w.Edges[0].To != "APPROVE" {
+		t.Errorf("unexpected edges %+v", w.Edges)
+	}
+	if !reflect.DeepEqual(w.Nodes["B"].Spec, NodeSpec{"prompt": "final review"}) {
+		t.Errorf("expected merged spec, got %v", w.Nodes["B"].Spec)
+	}
+	if !reflect.DeepEqual(base.Nodes["B"].Spec, NodeSpec{"prompt": "review", "retries": 1}) || base.Edges[0].To != "B" {
+		t.Error("extending must not modify the base")
+	}
+
+	fingerprint, _ := base.Fingerprint()
+	if w.Metadata["base"] != "base@0" || w.Metadata["baseFingerprint"] != fingerprint {
+		t.Errorf("expected lineage metadata, got %v", w.Metadata)
+	}
+	if w.Metadata["owner"] != "ops" || w.Metadata["team"] != "finance" {
+		t.Errorf("expected merged metadata, got %v", w.Metadata)
+	}
+}
+
+func TestRegistryExtendTemplateInstance(t *testing.T) {
+	r := NewRegistry()
+	if err := r.RegisterTemplate(enemyTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	instance, err := r.Instantiate("enemy", map[string]any{"name": "Orc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance.Metadata["lair"] = "cave"
+
+	w, err := r.Extend(&Extension{
+		ID:         "orc-boss",
+		Base:       instance.ID,
+		MergeSpecs: map[string]NodeSpec{"A": {"name": "Orc Chief"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.Metadata["template"]; ok {
+		t.Errorf("expected the template key not to be inherited, got %v", w.Metadata)
+	}
+	if _, ok := w.Metadata["params"]; ok {
+		t.Errorf("expected the params key not to be inherited, got %v", w.Metadata)
+	}
+	if w.Metadata["lair"] != "cave" || w.Metadata["base"] != instance.Ref() {
+		t.Errorf("expected other metadata and lineage kept, got %v", w.Metadata)
+	}
+	if instance.Metadata["template"] != "enemy" {
+		t.Error("extending must not modify the instance")
+	}
+}
+
+func TestRegistryExtendRemove(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(linearWorkflow("base"))
+
+	w, err := r.Extend(&Extension{ID: "short", Base: "base@0", RemoveNodes: []string{"C"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.Nodes["C"]; ok || len(w.Edges) != 1 || w.Edges[0].ID != "e1" {
+		t.Errorf("expected C and its edge removed, got %v %+v", sortedNodeIDs(w), w.Edges)
+	}
+
+	w, err = r.Extend(&Extension{ID: "rerouted", Base: "base", Entry: "B", RemoveEdges: []string{"e1"}, RemoveNodes: []string{"A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Entry != "B" || len(w.Nodes) != 2 {
+		t.Errorf("expected entry B with two nodes, got %s %v", w.Entry, sortedNodeIDs(w))
+	}
+}
+
+func TestRegistryExtendErrors(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(linearWorkflow("base"))
+
+	tests := []struct {
+		name string
+		ext  *Extension
+		code ValidationErrorCode
+	}{
+		{"missing base", &Extension{ID: "x", Base: "nope"}, ErrWorkflowNotFound},
+		{"unknown node removed", &Extension{ID: "x", Base: "base", RemoveNodes: []string{"Z"}}, ErrInvalidExtension},
+		{"unknown edge removed", &Extension{ID: "x", Base: "base", RemoveEdges: []string{"e9"}}, ErrInvalidExtension},
+		{"spec merged into missing node", &Extension{ID: "x", Base: "base", MergeSpecs: map[string]NodeSpec{"Z": {"k": 1}}}, ErrInvalidExtension},
+		{"result fails validation", &Extension{ID: "x", Base: "base", Edges: []Edge{{ID: "e3", From: "C", To: "Z", Event: "NEXT"}}}, ErrInvalidEdge},
+		{"same ID as base", &Extension{ID: "base", Base: "base"}, ErrDuplicateWorkflowID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Extend(tt.ext)
+			var ve *ValidationError
+			if !errors.As(err, &ve) || ve.Code != tt.code {
+				t.Errorf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+	if r.Has("x") {
+		t.Error("expected no failed extension to be registered")
+	}
+}
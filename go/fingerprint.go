@@ -0,0 +1,310 @@
+package reflex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Canonical form and fingerprints
+// ---------------------------------------------------------------------------
+
+// canonicalWorkflow is the serialized form hashed by Fingerprint. Nodes are
+// keyed by ID and edges sorted by ID, so declaration order does not matter.
+// Version, Description and Metadata are left out: they do not change how a
+// session executes.
+type canonicalWorkflow struct {
+	ID            string                   `json:"id"`
+	Entry         string                   `json:"entry"`
+	Nodes         map[string]canonicalNode `json:"nodes"`
+	Edges         []canonicalEdge          `json:"edges"`
+	SensitiveKeys []string                 `json:"sensitiveKeys,omitempty"`
+	Outputs       []string                 `json:"outputs,omitempty"`
+}
+
+// canonicalNode holds the spec in its typed form (see typedValue), like the
+// invocation's params.
+type canonicalNode struct {
+	Spec    any                  `json:"spec"`
+	Invokes *canonicalInvocation `json:"invokes,omitempty"`
+	Inputs  []NodeInput          `json:"inputs,omitempty"`
+	Outputs []NodeOutput         `json:"outputs,omitempty"`
+}
+
+type canonicalInvocation struct {
+	WorkflowID string          `json:"workflowId"`
+	ReturnMap  []ReturnMapping `json:"returnMap"`
+	Params     any             `json:"params"`
+}
+
+type canonicalEdge struct {
+	ID    string          `json:"id"`
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Event string          `json:"event"`
+	Guard *canonicalGuard `json:"guard,omitempty"`
+}
+
+// canonicalGuard uses the workflow file form of a guard, with the value in
+// its typed form (see typedValue). Custom guards are identified by name only,
+// so changing a custom guard's function without renaming it does not change
+// the fingerprint.
+type canonicalGuard struct {
+	Type  GuardType `json:"type"`
+	Key   string    `json:"key,omitempty"`
+	Value any       `json:"value"`
+	Name  string    `json:"name,omitempty"`
+}
+
+// CanonicalJSON serializes everything that affects execution: entry, nodes
+// with their specs, invocations and contracts, edges with their guards,
+// sensitive keys and outputs. Node and edge order are normalized, as are
+// the order of SensitiveKeys, Outputs and each node's Inputs and Outputs.
+// Spec, invocation parameter and guard values keep their Go types, since
+// agents and equality guards tell them apart: int 0 and float64 0 fingerprint
+// differently. It fails if one of those values cannot be encoded.
+func (w *Workflow) CanonicalJSON() ([]byte, error) {
+	c := canonicalWorkflow{
+		ID:            w.ID,
+		Entry:         w.Entry,
+		Nodes:         make(map[string]canonicalNode, len(w.Nodes)),
+		Edges:         make([]canonicalEdge, len(w.Edges)),
+		SensitiveKeys: sortedCopy(w.SensitiveKeys),
+		Outputs:       sortedCopy(w.Outputs),
+	}
+	for id, node := range w.Nodes {
+		spec := node.Spec
+		if spec == nil {
+			spec = NodeSpec{}
+		}
+		typedSpec, err := typedValue(reflect.ValueOf(spec), make(visiting))
+		if err != nil {
+			return nil, fmt.Errorf("workflow '%s': node '%s': spec: %w", w.Ref(), id, err)
+		}
+		var invokes *canonicalInvocation
+		if inv := node.Invokes; inv != nil {
+			params, err := typedValue(reflect.ValueOf(inv.Params), make(visiting))
+			if err != nil {
+				return nil, fmt.Errorf("workflow '%s': node '%s': params: %w", w.Ref(), id, err)
+			}
+			invokes = &canonicalInvocation{WorkflowID: inv.WorkflowID, ReturnMap: inv.ReturnMap, Params: params}
+		}
+		c.Nodes[id] = canonicalNode{
+			Spec:    typedSpec,
+			Invokes: invokes,
+			Inputs:  sortedBy(node.Inputs, func(in NodeInput) string { return in.Key }),
+			Outputs: sortedBy(node.Outputs, func(out NodeOutput) string { return out.Key }),
+		}
+	}
+	for i, edge := range w.Edges {
+		guard, err := canonicalizeGuard(edge.Guard)
+		if err != nil {
+			return nil, fmt.Errorf("workflow '%s': edge '%s': %w", w.Ref(), edge.ID, err)
+		}
+		c.Edges[i] = canonicalEdge{ID: edge.ID, From: edge.From, To: edge.To, Event: edge.Event, Guard: guard}
+	}
+	sort.SliceStable(c.Edges, func(i, j int) bool { return c.Edges[i].ID < c.Edges[j].ID })
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("workflow '%s': %w", w.Ref(), err)
+	}
+	return data, nil
+}
+
+// Fingerprint returns the hex SHA-256 of CanonicalJSON. Two definitions with
+// the same fingerprint execute identically, given the same agent and custom
+// guards.
+func (w *Workflow) Fingerprint() (string, error) {
+	data, err := w.CanonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func canonicalizeGuard(g Guard) (*canonicalGuard, error) {
+	switch guard := g.(type) {
+	case nil:
+		return nil, nil
+	case *BuiltinGuard:
+		var value any
+		if guard.Value != nil {
+			var err error
+			if value, err = typedValue(reflect.ValueOf(guard.Value), make(visiting)); err != nil {
+				return nil, fmt.Errorf("guard value: %w", err)
+			}
+		}
+		return &canonicalGuard{Type: guard.Type, Key: guard.Key, Value: value}, nil
+	case *CustomGuardFunc:
+		return &canonicalGuard{Type: customGuardType, Name: guard.Name}, nil
+	default:
+		return &canonicalGuard{Type: customGuardType, Name: fmt.Sprintf("%T", g)}, nil
+	}
+}
+
+// typedValue encodes v as a [type, payload] pair, recursively, so that two
+// values share an encoding only if reflect.DeepEqual considers them equal
+// (NaNs and funcs aside). The payload of a nil map, slice or pointer is null;
+// map entries are [key, value] pairs ordered by their encoding; struct
+// fields, unexported ones included, are [name, value] pairs in declaration
+// order.
+func typedValue(v reflect.Value, seen visiting) (any, error) {
+	typ := v.Type().String()
+	switch v.Kind() {
+	case reflect.Bool:
+		return []any{typ, v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []any{typ, v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return []any{typ, v.Uint()}, nil
+	case reflect.Float32, reflect.Float64:
+		return []any{typ, v.Float()}, nil
+	case reflect.String:
+		return []any{typ, v.String()}, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return []any{typ, nil}, nil
+		}
+		return typedValue(v.Elem(), seen)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return []any{typ, nil}, nil
+		}
+		if !seen.enter(v) {
+			return nil, cycleError(v)
+		}
+		defer seen.leave(v)
+		elem, err := typedValue(v.Elem(), seen)
+		return []any{typ, elem}, err
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return []any{typ, nil}, nil
+			}
+			if v.Len() > 0 {
+				if !seen.enter(v) {
+					return nil, cycleError(v)
+				}
+				defer seen.leave(v)
+			}
+		}
+		elems := make([]any, v.Len())
+		for i := range elems {
+			elem, err := typedValue(v.Index(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return []any{typ, elems}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return []any{typ, nil}, nil
+		}
+		if !seen.enter(v) {
+			return nil, cycleError(v)
+		}
+		defer seen.leave(v)
+		type entry struct {
+			sortKey string
+			pair    []any
+		}
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := typedValue(iter.Key(), seen)
+			if err != nil {
+				return nil, err
+			}
+			val, err := typedValue(iter.Value(), seen)
+			if err != nil {
+				return nil, err
+			}
+			sortKey, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry{string(sortKey), []any{key, val}})
+		}
+		slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.sortKey, b.sortKey) })
+		pairs := make([]any, len(entries))
+		for i, e := range entries {
+			pairs[i] = e.pair
+		}
+		return []any{typ, pairs}, nil
+	case reflect.Struct:
+		fields := make([]any, v.NumField())
+		for i := range fields {
+			field, err := typedValue(v.Field(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = []any{v.Type().Field(i).Name, field}
+		}
+		return []any{typ, fields}, nil
+	default:
+		return nil, fmt.Errorf("%s value cannot be encoded", v.Kind())
+	}
+}
+
+// sortedBy returns a copy of s ordered by key, or nil if s is empty.
+func sortedBy[T any](s []T, key func(T) string) []T {
+	if len(s) == 0 {
+		return nil
+	}
+	cp := slices.Clone(s)
+	slices.SortStableFunc(cp, func(a, b T) int { return strings.Compare(key(a), key(b)) })
+	return cp
+}
+
+func sortedCopy(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	cp := append([]string(nil), s...)
+	sort.Strings(cp)
+	return cp
+}
+
+// VerifyFingerprints compares fingerprints recorded by Engine.Fingerprints,
+// keyed by workflow ref, with the registered definitions. It returns nil or a
+// ValidationErrors holding an ErrFingerprintMismatch for every changed
+// workflow and an ErrWorkflowNotFound for every missing one, ordered by ref.
+// Callers restoring a session can refuse on any error or log them and carry
+// on.
+func (r *Registry) VerifyFingerprints(fingerprints map[string]string) error {
+	refs := make([]string, 0, len(fingerprints))
+	for ref := range fingerprints {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	var errs ValidationErrors
+	for _, ref := range refs {
+		id, _, _, _ := parseWorkflowRef(ref)
+		w, ok := r.Get(ref)
+		if !ok {
+			err := newValidationError(ErrWorkflowNotFound, id,
+				fmt.Sprintf("workflow '%s' is no longer registered", ref))
+			err.Details = map[string]any{"ref": ref}
+			errs = append(errs, err)
+			continue
+		}
+		current, err := w.Fingerprint()
+		if err != nil || current != fingerprints[ref] {
+			problem := newValidationError(ErrFingerprintMismatch, id,
+				fmt.Sprintf("workflow '%s' changed since its fingerprint was recorded", ref))
+			problem.Details = map[string]any{"ref": ref, "recorded": fingerprints[ref], "current": current}
+			errs = append(errs, problem)
+		}
+	}
+	return errs.Err()
+}
@@ -0,0 +1,154 @@
+package reflex
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func fingerprint(t *testing.T, w *Workflow) string {
+	t.Helper()
+	fp, err := w.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+func TestFingerprintIgnoresOrderAndCosmetics(t *testing.T) {
+	a := linearWorkflow("wf")
+	b := linearWorkflow("wf")
+	b.Edges[0], b.Edges[1] = b.Edges[1], b.Edges[0]
+	b.Version = 3
+	b.Metadata = map[string]any{"author": "someone"}
+	b.Nodes["A"].Description = "start here"
+	b.Nodes["B"].Spec = nil
+
+	if fingerprint(t, a) != fingerprint(t, b) {
+		t.Error("expected reordered edges and cosmetic fields not to change the fingerprint")
+	}
+}
+
+func TestFingerprintDetectsChanges(t *testing.T) {
+	base := fingerprint(t, linearWorkflow("wf"))
+	changes := map[string]func(w *Workflow){
+		"spec":   func(w *Workflow) { w.Nodes["A"].Spec["x"] = 1 },
+		"event":  func(w *Workflow) { w.Edges[0].Event = "GO" },
+		"guard":  func(w *Workflow) { w.Edges[0].Guard = Equals("k", 0) },
+		"invoke": func(w *Workflow) { w.Nodes["B"].Invokes = &InvocationSpec{WorkflowID: "child"} },
+		"entry":  func(w *Workflow) { w.Entry = "B" },
+	}
+	seen := map[string]string{base: "base"}
+	for name, change := range changes {
+		w := linearWorkflow("wf")
+		change(w)
+		fp := fingerprint(t, w)
+		if other, dup := seen[fp]; dup {
+			t.Errorf("%s change has the same fingerprint as %s", name, other)
+		}
+		seen[fp] = name
+	}
+
+	zero := linearWorkflow("wf")
+	zero.Edges[0].Guard = Equals("k", false)
+	if _, dup := seen[fingerprint(t, zero)]; dup {
+		t.Error("expected guard values 0 and false to fingerprint differently")
+	}
+}
+
+func TestFingerprintGuardValueTypes(t *testing.T) {
+	withGuard := func(value any) string {
+		w := linearWorkflow("wf")
+		w.Edges[0].Guard = Equals("k", value)
+		return fingerprint(t, w)
+	}
+	// Equals compares with reflect.DeepEqual, so these guards behave
+	// differently and must not share a fingerprint.
+	pairs := [][2]any{
+		{0, 0.0},
+		{[]any{1}, []any{1.0}},
+		{map[string]any{"n": int64(1)}, map[string]any{"n": 1}},
+		{[]any(nil), []any{}},
+	}
+	for _, pair := range pairs {
+		if withGuard(pair[0]) == withGuard(pair[1]) {
+			t.Errorf("expected %#v and %#v to fingerprint differently", pair[0], pair[1])
+		}
+	}
+	if withGuard(map[string]any{"a": 1, "b": "x"}) != withGuard(map[string]any{"b": "x", "a": 1}) {
+		t.Error("expected equal maps to fingerprint the same")
+	}
+}
+
+func TestFingerprintSpecValueTypes(t *testing.T) {
+	withSpec := func(hp any) string {
+		w := linearWorkflow("wf")
+		w.Nodes["A"].Spec["enemyHp"] = hp
+		return fingerprint(t, w)
+	}
+	if withSpec(3) == withSpec(3.0) {
+		t.Error("expected spec values 3 and 3.0 to fingerprint differently")
+	}
+	withParams := func(hp any) string {
+		w := linearWorkflow("wf")
+		w.Nodes["B"].Invokes = &InvocationSpec{WorkflowID: "enemy", Params: map[string]any{"hp": hp}}
+		return fingerprint(t, w)
+	}
+	if withParams(3) == withParams(3.0) {
+		t.Error("expected invocation params 3 and 3.0 to fingerprint differently")
+	}
+}
+
+func TestFingerprintIgnoresContractOrder(t *testing.T) {
+	a := linearWorkflow("wf")
+	a.Nodes["B"].Inputs = []NodeInput{{Key: "x", Required: true}, {Key: "y"}}
+	a.Nodes["B"].Outputs = []NodeOutput{{Key: "p", Guaranteed: true}, {Key: "q"}}
+	b := linearWorkflow("wf")
+	b.Nodes["B"].Inputs = []NodeInput{{Key: "y"}, {Key: "x", Required: true}}
+	b.Nodes["B"].Outputs = []NodeOutput{{Key: "q"}, {Key: "p", Guaranteed: true}}
+	if fingerprint(t, a) != fingerprint(t, b) {
+		t.Error("expected declaration order of inputs and outputs not to change the fingerprint")
+	}
+}
+
+func TestFingerprintUnencodableSpec(t *testing.T) {
+	w := linearWorkflow("wf")
+	w.Nodes["A"].Spec["fn"] = func() {}
+	if _, err := w.Fingerprint(); err == nil {
+		t.Error("expected a NodeSpec holding a func to fail")
+	}
+}
+
+func TestEngineRecordsFingerprints(t *testing.T) {
+	r := NewRegistry(RegistryOptions{RemovalPolicy: RemovalWarn, Logger: discardLogger})
+	_ = r.Register(linearWorkflow("child"))
+	_ = r.Register(invokerWorkflow("parent", "child"))
+	e := NewEngine(r, autoAdvanceAgent())
+	_, _ = e.Init("parent")
+	if _, err := e.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := e.Fingerprints()
+	if len(recorded) != 2 || recorded["child@0"] != fingerprint(t, linearWorkflow("child")) {
+		t.Fatalf("unexpected fingerprints: %v", recorded)
+	}
+	if err := r.VerifyFingerprints(recorded); err != nil {
+		t.Fatalf("expected no drift: %v", err)
+	}
+
+	changed := linearWorkflow("child")
+	changed.Edges[1].Event = "FINISH"
+	if err := r.Replace(changed); err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Unregister("parent")
+	errs := validationErrors(t, r.VerifyFingerprints(recorded))
+	if len(errs) != 2 || errs[0].Code != ErrFingerprintMismatch || errs[1].Code != ErrWorkflowNotFound {
+		t.Fatalf("expected a mismatch for child and parent missing, got %v", errs)
+	}
+	var ve *ValidationError
+	if !errors.As(r.VerifyFingerprints(recorded), &ve) || ve.Details["ref"] != "child@0" {
+		t.Errorf("expected child@0 first, got %v", ve)
+	}
+}
@@ -0,0 +1,206 @@
+package reflex
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ---------------------------------------------------------------------------
+// Flattening invocations
+// ---------------------------------------------------------------------------
+
+// DefaultFlattenDepth is the invocation nesting depth Flatten inlines when
+// FlattenOptions.MaxDepth is zero.
+const DefaultFlattenDepth = 8
+
+// FlattenKey is the NodeSpec key under which Flatten annotates invocation
+// nodes and the return nodes it adds. Its value is a *FlattenInfo.
+const FlattenKey = "reflex.flatten"
+
+// Events of the edges Flatten adds around an inlined workflow.
+const (
+	FlattenCallEvent   = "INVOKE"
+	FlattenReturnEvent = "RETURN"
+)
+
+// FlattenKind identifies the role of an annotated node.
+type FlattenKind string
+
+const (
+	// FlattenCall marks an invocation node followed by the inlined workflow.
+	FlattenCall FlattenKind = "call"
+	// FlattenReturn marks the synthetic node every terminal of the inlined
+	// workflow leads to. The invocation node's outgoing edges leave from it.
+	FlattenReturn FlattenKind = "return"
+	// FlattenTruncated marks an invocation node below MaxDepth, left as is.
+	FlattenTruncated FlattenKind = "truncated"
+)
+
+// FlattenInfo annotates a node of a flattened workflow.
+type FlattenInfo struct {
+	Kind FlattenKind `json:"kind"`
+	// Path is the ID of the invocation node in the flattened workflow; the
+	// inlined nodes are prefixed with it.
+	Path string `json:"path"`
+	// Workflow is the ref of the invoked workflow.
+	Workflow string `json:"workflow"`
+	// ReturnMap is the invocation's returnMap, applied when the session
+	// reaches the return node.
+	ReturnMap []ReturnMapping `json:"returnMap,omitempty"`
+}
+
+// FlattenOptions configures Flatten.
+type FlattenOptions struct {
+	// MaxDepth is how many times one workflow is inlined along a single
+	// invocation chain, which bounds recursive invocations; further
+	// invocation nodes of that workflow are marked FlattenTruncated. Chains
+	// without recursion are inlined completely. Zero means
+	// DefaultFlattenDepth.
+	MaxDepth int
+}
+
+// Flatten returns a single workflow equivalent to ref with every invocation
+// inlined, for static analysis and export. It is not registered and has no
+// InvocationSpecs, so the engine never runs it.
+//
+// Each invocation node N is followed by an N/<entry> edge with event
+// FlattenCallEvent, the invoked workflow's nodes and edges with IDs prefixed
+// "N/", and a synthetic "N:return" node that every terminal leads to with
+// event FlattenReturnEvent. N's outgoing edges leave from "N:return". Nested
+// invocations extend the prefix ("N/M/..."). Annotations are stored under
+// FlattenKey. Metadata["flattened"] holds ref's resolved form. Returns an
+// error if ref or an invocation target is not registered, and an
+// ErrDuplicateNodeID or ErrDuplicateEdgeID error if a real ID clashes with a
+// generated one (a node "N:return" next to an invocation node N, say).
+func (r *Registry) Flatten(ref string, opts ...FlattenOptions) (*Workflow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	root, ok := r.lookup(ref)
+	if !ok {
+		return nil, newValidationError(ErrWorkflowNotFound, ref,
+			fmt.Sprintf("cannot flatten: workflow '%s' is not registered", ref))
+	}
+	maxDepth := DefaultFlattenDepth
+	if len(opts) > 0 && opts[0].MaxDepth > 0 {
+		maxDepth = opts[0].MaxDepth
+	}
+
+	f := &Workflow{
+		ID:            root.ID,
+		Entry:         root.Entry,
+		Nodes:         make(map[string]*Node),
+		Metadata:      maps.Clone(root.Metadata),
+		Version:       root.Version,
+		SensitiveKeys: slices.Clone(root.SensitiveKeys),
+		Outputs:       slices.Clone(root.Outputs),
+	}
+	if f.Metadata == nil {
+		f.Metadata = make(map[string]any)
+	}
+	f.Metadata["flattened"] = root.Ref()
+	if _, err := r.inline(f, root, "", make(map[string]int), maxDepth); err != nil {
+		return nil, err
+	}
+	edgeIDs := make(map[string]bool, len(f.Edges))
+	for _, edge := range f.Edges {
+		if edgeIDs[edge.ID] {
+			err := newValidationError(ErrDuplicateEdgeID, root.ID,
+				fmt.Sprintf("cannot flatten: workflow '%s': edge ID '%s' clashes with a generated edge ID", root.ID, edge.ID))
+			err.Details = map[string]any{"edgeId": edge.ID}
+			return nil, err
+		}
+		edgeIDs[edge.ID] = true
+	}
+	return f, nil
+}
+
+// inline copies w into f under path and returns, for each of w's nodes, the
+// flattened node its outgoing edges leave from. expanded counts the
+// workflows inlined on the invocation chain leading to w. Caller must hold
+// r.mu.
+func (r *Registry) inline(f, w *Workflow, path string, expanded map[string]int, maxDepth int) (map[string]string, error) {
+	prefixed := func(id string) string {
+		if path == "" {
+			return id
+		}
+		return path + "/" + id
+	}
+
+	exits := make(map[string]string, len(w.Nodes))
+	for _, nodeID := range sortedNodeIDs(w) {
+		orig := w.Nodes[nodeID]
+		node := cloneNode(orig)
+		node.ID = prefixed(nodeID)
+		node.Invokes = nil
+		if err := addFlattenedNode(f, node); err != nil {
+			return nil, err
+		}
+		exits[nodeID] = node.ID
+		if orig.Invokes == nil {
+			continue
+		}
+
+		child, ok := r.invocationTarget(orig.Invokes)
+		if !ok {
+			err := newValidationError(ErrInvocationNotFound, w.ID,
+				fmt.Sprintf("cannot flatten: workflow '%s', node '%s': invokes '%s' which is not registered",
+					w.ID, nodeID, orig.Invokes.WorkflowID))
+			err.Details = map[string]any{"nodeId": nodeID, "target": orig.Invokes.WorkflowID}
+			return nil, err
+		}
+		info := &FlattenInfo{Kind: FlattenCall, Path: node.ID, Workflow: child.Ref(), ReturnMap: orig.Invokes.ReturnMap}
+		if node.Spec == nil {
+			node.Spec = NodeSpec{}
+		}
+		node.Spec[FlattenKey] = info
+		if expanded[child.Ref()] >= maxDepth {
+			info.Kind = FlattenTruncated
+			continue
+		}
+
+		expanded[child.Ref()]++
+		childExits, err := r.inline(f, child, node.ID, expanded, maxDepth)
+		expanded[child.Ref()]--
+		if err != nil {
+			return nil, err
+		}
+		ret := &Node{ID: node.ID + ":return", Spec: NodeSpec{
+			FlattenKey: &FlattenInfo{Kind: FlattenReturn, Path: node.ID, Workflow: child.Ref(), ReturnMap: orig.Invokes.ReturnMap},
+		}}
+		if err := addFlattenedNode(f, ret); err != nil {
+			return nil, err
+		}
+		f.Edges = append(f.Edges, Edge{ID: node.ID + ":call", From: node.ID, To: node.ID + "/" + child.Entry, Event: FlattenCallEvent})
+		for _, terminal := range child.Terminals() {
+			from := childExits[terminal]
+			f.Edges = append(f.Edges, Edge{ID: from + ":return", From: from, To: ret.ID, Event: FlattenReturnEvent})
+		}
+		exits[nodeID] = ret.ID
+	}
+
+	for _, edge := range w.Edges {
+		f.Edges = append(f.Edges, Edge{
+			ID:    prefixed(edge.ID),
+			From:  exits[edge.From],
+			To:    prefixed(edge.To),
+			Event: edge.Event,
+			Guard: edge.Guard,
+		})
+	}
+	return exits, nil
+}
+
+// addFlattenedNode adds node to f, refusing to replace a node already added
+// under the same ID.
+func addFlattenedNode(f *Workflow, node *Node) error {
+	if _, ok := f.Nodes[node.ID]; ok {
+		err := newValidationError(ErrDuplicateNodeID, f.ID,
+			fmt.Sprintf("cannot flatten: workflow '%s': node ID '%s' clashes with a generated node ID", f.ID, node.ID))
+		err.Details = map[string]any{"nodeId": node.ID}
+		return err
+	}
+	f.Nodes[node.ID] = node
+	return nil
+}
@@ -0,0 +1,144 @@
+package reflex
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistryFlatten(t *testing.T) {
+	r := NewRegistry()
+	mid := invokerWorkflow("mid", "leaf")
+	mid.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "m", ChildKey: "l"}}
+	for _, w := range []*Workflow{linearWorkflow("leaf"), mid, invokerWorkflow("top", "mid")} {
+		if err := r.Register(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := r.Flatten("top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPath := []string{"A", "A/A", "A/A/A", "A/A/B", "A/A/C", "A/A:return", "A/B", "A:return", "B"}
+	if paths := f.Paths(); len(paths) != 1 || !reflect.DeepEqual(paths[0], wantPath) {
+		t.Errorf("expected the single path %v, got %v", wantPath, paths)
+	}
+	if problems := NewRegistry().Check(f).Errors(); len(problems) > 0 {
+		t.Errorf("expected a valid workflow, got %v", problems)
+	}
+	if f.Metadata["flattened"] != "top@0" {
+		t.Errorf("expected flattened metadata, got %v", f.Metadata)
+	}
+
+	for _, node := range f.Nodes {
+		if node.Invokes != nil {
+			t.Errorf("expected no invocations left, found one on %s", node.ID)
+		}
+	}
+	call := f.Nodes["A/A"].Spec[FlattenKey].(*FlattenInfo)
+	if call.Kind != FlattenCall || call.Workflow != "leaf@0" {
+		t.Errorf("unexpected call annotation %+v", call)
+	}
+	ret := f.Nodes["A/A:return"].Spec[FlattenKey].(*FlattenInfo)
+	if ret.Kind != FlattenReturn || ret.Path != "A/A" || !reflect.DeepEqual(ret.ReturnMap, mid.Nodes["A"].Invokes.ReturnMap) {
+		t.Errorf("unexpected return annotation %+v", ret)
+	}
+
+	edges := edgesByID(f)
+	for id, want := range map[string]Edge{
+		"A:call":       {ID: "A:call", From: "A", To: "A/A", Event: FlattenCallEvent},
+		"A/A/e2":       {ID: "A/A/e2", From: "A/A/B", To: "A/A/C", Event: "NEXT"},
+		"A/A/C:return": {ID: "A/A/C:return", From: "A/A/C", To: "A/A:return", Event: FlattenReturnEvent},
+		"A/e1":         {ID: "A/e1", From: "A/A:return", To: "A/B", Event: "NEXT"},
+		"A/B:return":   {ID: "A/B:return", From: "A/B", To: "A:return", Event: FlattenReturnEvent},
+		"e1":           {ID: "e1", From: "A:return", To: "B", Event: "NEXT"},
+	} {
+		if !reflect.DeepEqual(edges[id], want) {
+			t.Errorf("edge %s: expected %+v, got %+v", id, want, edges[id])
+		}
+	}
+
+	if orig, _ := r.Get("mid"); orig.Nodes["A"].Invokes == nil || len(orig.Nodes["A"].Spec) != 0 {
+		t.Error("flattening must not modify registered workflows")
+	}
+	if dot := ExportDOT(f); !strings.Contains(dot, `label="A/A:return\nreturns from leaf@0\nm <- l"`) {
+		t.Errorf("expected the return node labelled in DOT output:\n%s", dot)
+	}
+}
+
+func TestRegistryFlattenRecursion(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(invokerWorkflow("loop", "loop"))
+
+	f, err := r.Flatten("loop", FlattenOptions{MaxDepth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Nodes) != 8 {
+		t.Errorf("expected two expansions, got nodes %v", sortedNodeIDs(f))
+	}
+	info := f.Nodes["A/A/A"].Spec[FlattenKey].(*FlattenInfo)
+	if info.Kind != FlattenTruncated || info.Workflow != "loop@0" {
+		t.Errorf("expected the third level truncated, got %+v", info)
+	}
+	if _, err := f.TopologicalOrder(); err != nil {
+		t.Errorf("expected an acyclic result, got %v", err)
+	}
+
+	f, _ = r.Flatten("loop")
+	if _, ok := f.Nodes[strings.Repeat("A/", DefaultFlattenDepth)+"A"]; !ok {
+		t.Errorf("expected expansion down to DefaultFlattenDepth, got %d nodes", len(f.Nodes))
+	}
+}
+
+func TestRegistryFlattenDepthPerWorkflow(t *testing.T) {
+	r := NewRegistry()
+	for _, w := range []*Workflow{linearWorkflow("leaf"), invokerWorkflow("mid", "leaf"), invokerWorkflow("top", "mid")} {
+		if err := r.Register(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := r.Flatten("top", FlattenOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := f.Nodes["A/A"].Spec[FlattenKey].(*FlattenInfo); info.Kind != FlattenCall {
+		t.Errorf("expected a chain without recursion inlined completely, got %+v", info)
+	}
+	if _, ok := f.Nodes["A/A/C"]; !ok {
+		t.Errorf("expected leaf inlined, got nodes %v", sortedNodeIDs(f))
+	}
+}
+
+func TestRegistryFlattenErrors(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(invokerWorkflow("parent", "missing"))
+
+	var ve *ValidationError
+	if _, err := r.Flatten("nope"); !errors.As(err, &ve) || ve.Code != ErrWorkflowNotFound {
+		t.Errorf("expected %s, got %v", ErrWorkflowNotFound, err)
+	}
+	if _, err := r.Flatten("parent"); !errors.As(err, &ve) || ve.Code != ErrInvocationNotFound || ve.Details["nodeId"] != "A" {
+		t.Errorf("expected %s on node A, got %v", ErrInvocationNotFound, err)
+	}
+
+	_ = r.Register(linearWorkflow("child"))
+	nodeClash := invokerWorkflow("node-clash", "child")
+	nodeClash.Nodes["A:return"] = &Node{ID: "A:return"}
+	nodeClash.Edges = append(nodeClash.Edges, Edge{ID: "e2", From: "B", To: "A:return", Event: "NEXT"})
+	edgeClash := invokerWorkflow("edge-clash", "child")
+	edgeClash.Edges[0].ID = "A:call"
+	for _, w := range []*Workflow{nodeClash, edgeClash} {
+		if err := r.Register(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Flatten("node-clash"); !errors.As(err, &ve) || ve.Code != ErrDuplicateNodeID || ve.Details["nodeId"] != "A:return" {
+		t.Errorf("expected %s on A:return, got %v", ErrDuplicateNodeID, err)
+	}
+	if _, err := r.Flatten("edge-clash"); !errors.As(err, &ve) || ve.Code != ErrDuplicateEdgeID || ve.Details["edgeId"] != "A:call" {
+		t.Errorf("expected %s on A:call, got %v", ErrDuplicateEdgeID, err)
+	}
+}
@@ -0,0 +1,386 @@
+package reflex
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ---------------------------------------------------------------------------
+// Workflow graph algorithms
+// ---------------------------------------------------------------------------
+
+// Successors returns the distinct targets of nodeID's outgoing edges, in edge
+// order.
+func (w *Workflow) Successors(nodeID string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, edge := range w.Edges {
+		if edge.From == nodeID && !seen[edge.To] {
+			seen[edge.To] = true
+			result = append(result, edge.To)
+		}
+	}
+	return result
+}
+
+// Predecessors returns the distinct sources of nodeID's incoming edges, in
+// edge order.
+func (w *Workflow) Predecessors(nodeID string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, edge := range w.Edges {
+		if edge.To == nodeID && !seen[edge.From] {
+			seen[edge.From] = true
+			result = append(result, edge.From)
+		}
+	}
+	return result
+}
+
+// Terminals returns the nodes without outgoing edges, sorted by ID.
+func (w *Workflow) Terminals() []string {
+	var result []string
+	for _, id := range sortedNodeIDs(w) {
+		if len(w.Successors(id)) == 0 {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// TopologicalOrder returns every node so that each edge points forward,
+// breaking ties by node ID. Edges to unknown nodes are ignored. If w has a
+// cycle the error is a *ValidationError with code ErrCycleDetected naming the
+// nodes on or behind it in Details["nodeIds"].
+func (w *Workflow) TopologicalOrder() ([]string, error) {
+	order, err := topologicalOrder(w)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Paths returns every simple path from Entry to a terminal node. The number
+// of paths can grow exponentially with the number of branches.
+func (w *Workflow) Paths() [][]string {
+	if _, ok := w.Nodes[w.Entry]; !ok {
+		return nil
+	}
+	var paths [][]string
+	onPath := make(map[string]bool)
+	var path []string
+	var visit func(nodeID string)
+	visit = func(nodeID string) {
+		onPath[nodeID] = true
+		path = append(path, nodeID)
+		next := w.Successors(nodeID)
+		if len(next) == 0 {
+			paths = append(paths, append([]string(nil), path...))
+		}
+		for _, n := range next {
+			if !onPath[n] {
+				visit(n)
+			}
+		}
+		path = path[:len(path)-1]
+		delete(onPath, nodeID)
+	}
+	visit(w.Entry)
+	return paths
+}
+
+// Dominators returns, for each node reachable from Entry, the nodes every
+// path from Entry to it passes through, ordered from Entry down to the node
+// itself. A linter can use it to check that, say, an approval node dominates
+// every terminal. Returns nil if w has no valid entry or has a cycle.
+func (w *Workflow) Dominators() map[string][]string {
+	if _, ok := w.Nodes[w.Entry]; !ok {
+		return nil
+	}
+	order, err := topologicalOrder(w)
+	if err != nil {
+		return nil
+	}
+	reached := reachable(w)
+
+	// On a DAG a node's immediate dominator is the nearest common ancestor,
+	// in the dominator tree, of its reachable predecessors.
+	idom := map[string]string{w.Entry: ""}
+	depth := map[string]int{w.Entry: 0}
+	for _, nodeID := range order {
+		if nodeID == w.Entry || !reached[nodeID] {
+			continue
+		}
+		dom := ""
+		for _, p := range w.Predecessors(nodeID) {
+			if !reached[p] {
+				continue
+			}
+			if dom == "" {
+				dom = p
+				continue
+			}
+			for dom != p {
+				for depth[dom] > depth[p] {
+					dom = idom[dom]
+				}
+				for depth[p] > depth[dom] {
+					p = idom[p]
+				}
+				if dom != p {
+					dom, p = idom[dom], idom[p]
+				}
+			}
+		}
+		idom[nodeID] = dom
+		depth[nodeID] = depth[dom] + 1
+	}
+
+	result := make(map[string][]string, len(idom))
+	for nodeID := range idom {
+		chain := make([]string, depth[nodeID]+1)
+		for n, i := nodeID, depth[nodeID]; i >= 0; n, i = idom[n], i-1 {
+			chain[i] = n
+		}
+		result[nodeID] = chain
+	}
+	return result
+}
+
+// topologicalOrder runs Kahn's algorithm with a sorted ready set.
+func topologicalOrder(w *Workflow) ([]string, *ValidationError) {
+	inDegree := make(map[string]int, len(w.Nodes))
+	for id := range w.Nodes {
+		inDegree[id] = 0
+	}
+	for _, edge := range w.Edges {
+		if _, ok := w.Nodes[edge.From]; ok {
+			if _, ok := inDegree[edge.To]; ok {
+				inDegree[edge.To]++
+			}
+		}
+	}
+
+	var ready []string
+	for id, deg := range inDegree {
+		if deg == 0 {
+			ready = append(ready, id)
+		}
+	}
+	order := make([]string, 0, len(w.Nodes))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		node := ready[0]
+		ready = ready[1:]
+		order = append(order, node)
+		for _, edge := range w.Edges {
+			if edge.From != node {
+				continue
+			}
+			if _, ok := inDegree[edge.To]; !ok {
+				continue
+			}
+			inDegree[edge.To]--
+			if inDegree[edge.To] == 0 {
+				ready = append(ready, edge.To)
+			}
+		}
+	}
+
+	if len(order) != len(w.Nodes) {
+		var cycleNodes []string
+		for id, deg := range inDegree {
+			if deg > 0 {
+				cycleNodes = append(cycleNodes, id)
+			}
+		}
+		sort.Strings(cycleNodes)
+		err := newValidationError(ErrCycleDetected, w.ID,
+			fmt.Sprintf("workflow '%s' contains a cycle involving nodes: %v", w.ID, cycleNodes))
+		err.Details = map[string]any{"nodeIds": cycleNodes}
+		return nil, err
+	}
+	return order, nil
+}
+
+// reachable returns the set of nodes some path from Entry reaches.
+func reachable(w *Workflow) map[string]bool {
+	reached := map[string]bool{w.Entry: true}
+	queue := []string{w.Entry}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range w.Successors(node) {
+			if !reached[next] {
+				reached[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return reached
+}
+
+// reachableOrder returns the nodes reachable from Entry in topological order.
+// w must be a registered (acyclic) workflow.
+func reachableOrder(w *Workflow) []string {
+	order, _ := topologicalOrder(w)
+	reached := reachable(w)
+	var result []string
+	for _, nodeID := range order {
+		if reached[nodeID] {
+			result = append(result, nodeID)
+		}
+	}
+	return result
+}
+
+// ---------------------------------------------------------------------------
+// Invocation call graph
+// ---------------------------------------------------------------------------
+
+// UnboundedDepth is the nesting depth reported for workflows that can invoke
+// themselves, directly or indirectly.
+const UnboundedDepth = -1
+
+// Call is one invocation edge of a CallGraph.
+type Call struct {
+	// Caller is the ref ("id@version") of the invoking workflow.
+	Caller string
+	// NodeID is the invoking node.
+	NodeID string
+	// Target is the reference as written in the InvocationSpec.
+	Target string
+	// Callee is the ref Target resolves to, or "" if it is not registered.
+	Callee string
+}
+
+// CallGraph is a snapshot of the invocations between registered workflow
+// versions, taken by Registry.CallGraph.
+type CallGraph struct {
+	// Workflows lists every registered version's ref, in ID then version
+	// order.
+	Workflows []string
+	// Calls lists every invocation, ordered by caller then node ID.
+	Calls []Call
+}
+
+// CallGraph returns the invocation graph of every registered workflow
+// version. Bare-ID targets resolve to the latest version at the time of the
+// call.
+func (r *Registry) CallGraph() *CallGraph {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g := &CallGraph{}
+	for _, w := range r.allWorkflows() {
+		g.Workflows = append(g.Workflows, w.Ref())
+		for _, nodeID := range sortedNodeIDs(w) {
+			node := w.Nodes[nodeID]
+			if node.Invokes == nil {
+				continue
+			}
+			call := Call{Caller: w.Ref(), NodeID: nodeID, Target: node.Invokes.WorkflowID}
+			if child, ok := r.invocationTarget(node.Invokes); ok {
+				call.Callee = child.Ref()
+			}
+			g.Calls = append(g.Calls, call)
+		}
+	}
+	return g
+}
+
+// Callees returns the distinct refs ref invokes, in call order. Unresolved
+// calls are skipped.
+func (g *CallGraph) Callees(ref string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, c := range g.Calls {
+		if c.Caller == ref && c.Callee != "" && !seen[c.Callee] {
+			seen[c.Callee] = true
+			result = append(result, c.Callee)
+		}
+	}
+	return result
+}
+
+// Callers returns the distinct refs that invoke ref, in call order.
+func (g *CallGraph) Callers(ref string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, c := range g.Calls {
+		if c.Callee == ref && !seen[c.Caller] {
+			seen[c.Caller] = true
+			result = append(result, c.Caller)
+		}
+	}
+	return result
+}
+
+// Roots returns the workflows no other workflow invokes, the usual entry
+// points for sessions.
+func (g *CallGraph) Roots() []string {
+	var result []string
+	for _, ref := range g.Workflows {
+		if len(g.Callers(ref)) == 0 {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// Depth returns the deepest call stack a session started at ref can reach,
+// counting ref's own frame: 1 for a workflow that invokes nothing. Returns
+// UnboundedDepth if a recursive invocation is reachable from ref, and 0 if
+// ref is not in the graph.
+func (g *CallGraph) Depth(ref string) int {
+	return g.depth(ref, make(map[string]int), make(map[string]bool))
+}
+
+// MaxDepth returns the largest Depth of any workflow in the graph, or
+// UnboundedDepth if any workflow can recurse.
+func (g *CallGraph) MaxDepth() int {
+	memo := make(map[string]int)
+	maxDepth := 0
+	for _, ref := range g.Workflows {
+		d := g.depth(ref, memo, make(map[string]bool))
+		if d == UnboundedDepth {
+			return UnboundedDepth
+		}
+		maxDepth = max(maxDepth, d)
+	}
+	return maxDepth
+}
+
+func (g *CallGraph) depth(ref string, memo map[string]int, active map[string]bool) int {
+	if d, ok := memo[ref]; ok {
+		return d
+	}
+	known := false
+	for _, w := range g.Workflows {
+		if w == ref {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return 0
+	}
+
+	active[ref] = true
+	defer delete(active, ref)
+	deepest := 0
+	for _, callee := range g.Callees(ref) {
+		if active[callee] {
+			memo[ref] = UnboundedDepth
+			return UnboundedDepth
+		}
+		d := g.depth(callee, memo, active)
+		if d == UnboundedDepth {
+			memo[ref] = UnboundedDepth
+			return UnboundedDepth
+		}
+		deepest = max(deepest, d)
+	}
+	memo[ref] = deepest + 1
+	return deepest + 1
+}
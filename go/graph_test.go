@@ -0,0 +1,127 @@
+package reflex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// diamondWorkflow: A -> B, A -> C, B -> D, C -> D, D -> E, A -> F.
+func diamondWorkflow() *Workflow {
+	w, _ := NewWorkflow("diamond").
+		Node("A", nil).Node("B", nil).Node("C", nil).Node("D", nil).Node("E", nil).Node("F", nil).
+		Edge("A", "C").Edge("A", "B").Edge("B", "D").Edge("C", "D").Edge("D", "E").Edge("A", "F").
+		Edge("A", "F").On("SKIP").
+		Build()
+	return w
+}
+
+func TestWorkflowNeighbours(t *testing.T) {
+	w := diamondWorkflow()
+	if got := w.Successors("A"); !reflect.DeepEqual(got, []string{"C", "B", "F"}) {
+		t.Errorf("unexpected successors of A: %v", got)
+	}
+	if got := w.Predecessors("D"); !reflect.DeepEqual(got, []string{"B", "C"}) {
+		t.Errorf("unexpected predecessors of D: %v", got)
+	}
+	if got := w.Terminals(); !reflect.DeepEqual(got, []string{"E", "F"}) {
+		t.Errorf("unexpected terminals: %v", got)
+	}
+}
+
+func TestWorkflowTopologicalOrder(t *testing.T) {
+	order, err := diamondWorkflow().TopologicalOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A", "B", "C", "D", "E", "F"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+
+	cyclic := linearWorkflow("cyclic")
+	cyclic.Edges = append(cyclic.Edges, Edge{ID: "e3", From: "C", To: "B", Event: "BACK"})
+	_, err = cyclic.TopologicalOrder()
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code != ErrCycleDetected {
+		t.Fatalf("expected %s, got %v", ErrCycleDetected, err)
+	}
+	if !reflect.DeepEqual(ve.Details["nodeIds"], []string{"B", "C"}) {
+		t.Errorf("unexpected cycle nodes: %v", ve.Details["nodeIds"])
+	}
+}
+
+func TestWorkflowPaths(t *testing.T) {
+	want := [][]string{
+		{"A", "C", "D", "E"},
+		{"A", "B", "D", "E"},
+		{"A", "F"},
+	}
+	if got := diamondWorkflow().Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWorkflowDominators(t *testing.T) {
+	w := diamondWorkflow()
+	w.Nodes["X"] = &Node{ID: "X", Spec: NodeSpec{}}
+	dom := w.Dominators()
+	want := map[string][]string{
+		"A": {"A"},
+		"B": {"A", "B"},
+		"C": {"A", "C"},
+		"D": {"A", "D"},
+		"E": {"A", "D", "E"},
+		"F": {"A", "F"},
+	}
+	if !reflect.DeepEqual(dom, want) {
+		t.Errorf("expected %v, got %v", want, dom)
+	}
+}
+
+func TestRegistryCallGraph(t *testing.T) {
+	r := NewRegistry()
+	for _, w := range []*Workflow{
+		linearWorkflow("leaf"),
+		invokerWorkflow("middle", "leaf"),
+		invokerWorkflow("top", "middle"),
+		invokerWorkflow("dangling", "missing"),
+	} {
+		if err := r.Register(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := r.CallGraph()
+	if want := []string{"dangling@0", "leaf@0", "middle@0", "top@0"}; !reflect.DeepEqual(g.Workflows, want) {
+		t.Errorf("expected workflows %v, got %v", want, g.Workflows)
+	}
+	if len(g.Calls) != 3 || g.Calls[0].Target != "missing" || g.Calls[0].Callee != "" {
+		t.Errorf("unexpected calls: %+v", g.Calls)
+	}
+	if got := g.Callees("top@0"); !reflect.DeepEqual(got, []string{"middle@0"}) {
+		t.Errorf("unexpected callees: %v", got)
+	}
+	if got := g.Callers("leaf@0"); !reflect.DeepEqual(got, []string{"middle@0"}) {
+		t.Errorf("unexpected callers: %v", got)
+	}
+	if got := g.Roots(); !reflect.DeepEqual(got, []string{"dangling@0", "top@0"}) {
+		t.Errorf("unexpected roots: %v", got)
+	}
+	if g.Depth("top@0") != 3 || g.Depth("leaf@0") != 1 || g.Depth("nope@0") != 0 {
+		t.Errorf("unexpected depths: top %d, leaf %d", g.Depth("top@0"), g.Depth("leaf@0"))
+	}
+	if g.MaxDepth() != 3 {
+		t.Errorf("expected max depth 3, got %d", g.MaxDepth())
+	}
+
+	if err := r.Register(invokerWorkflow("loop", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	g = r.CallGraph()
+	if g.Depth("loop@0") != UnboundedDepth || g.MaxDepth() != UnboundedDepth {
+		t.Errorf("expected recursion to be unbounded, got %d", g.Depth("loop@0"))
+	}
+	if g.Depth("top@0") != 3 {
+		t.Errorf("recursion elsewhere must not affect top, got %d", g.Depth("top@0"))
+	}
+}
@@ -5,6 +5,28 @@ import (
 	"reflect"
 )
 
+// Exists returns a guard that passes when key is set in any visible scope.
+func Exists(key string) *BuiltinGuard {
+	return &BuiltinGuard{Type: GuardExists, Key: key}
+}
+
+// NotExists returns a guard that passes when key is not set in any visible
+// scope.
+func NotExists(key string) *BuiltinGuard {
+	return &BuiltinGuard{Type: GuardNotExists, Key: key}
+}
+
+// Equals returns a guard that passes when key is set to value.
+func Equals(key string, value any) *BuiltinGuard {
+	return &BuiltinGuard{Type: GuardEquals, Key: key, Value: value}
+}
+
+// NotEquals returns a guard that passes when key is unset or differs from
+// value.
+func NotEquals(key string, value any) *BuiltinGuard {
+	return &BuiltinGuard{Type: GuardNotEquals, Key: key, Value: value}
+}
+
 // Evaluate implements the Guard interface for BuiltinGuard.
 // Uses strict equality (reflect.DeepEqual) for equals/not-equals comparisons.
 // Guards read from the full scope chain (local → parent → grandparent).
@@ -0,0 +1,256 @@
+package reflex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"sort"
+)
+
+// ---------------------------------------------------------------------------
+// Loading workflow files (ROADMAP-v1 M7)
+// ---------------------------------------------------------------------------
+
+// customGuardType marks a guard reference resolved through
+// RegistryOptions.Guards.
+const customGuardType GuardType = "custom"
+
+// LoadError reports a problem with one workflow file. It unwraps to the
+// underlying *ValidationError or decoding error.
+type LoadError struct {
+	Path       string
+	WorkflowID string
+	Err        error
+}
+
+func (e *LoadError) Error() string {
+	if e.WorkflowID != "" {
+		return fmt.Sprintf("%s: workflow '%s': %v", e.Path, e.WorkflowID, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *LoadError) Unwrap() error { return e.Err }
+
+// LoadFS registers every workflow in the files of fsys matching pattern
+// (fs.Glob syntax, e.g. "workflows/*.json"). Works with os.DirFS and
+// embed.FS. A file holds one workflow object, a JSON array of workflows, or a
+// bundle object {"workflows": [...]}.
+//
+// Guards are JSON objects: {"type": "equals", "key": "k", "value": v} for the
+// built-in types, {"type": "custom", "name": "n"} for a guard from
+// RegistryOptions.Guards. JSON numbers decode as float64, so an equals guard
+// on a number matches float64 blackboard values only.
+//
+// Workflows are registered in dependency order, children before the
+// workflows that invoke them. Loading is atomic: if any file fails to decode
+// or any workflow fails validation, nothing is registered and the returned
+// error joins a *LoadError per problem.
+func (r *Registry) LoadFS(fsys fs.FS, pattern string) error {
+	paths, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("load %q: no files match", pattern)
+	}
+
+	var errs []error
+	var loaded []loadedWorkflow
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			errs = append(errs, &LoadError{Path: path, Err: err})
+			continue
+		}
+		workflows, err := r.decodeWorkflows(data)
+		if err != nil {
+			errs = append(errs, &LoadError{Path: path, Err: err})
+			continue
+		}
+		for _, w := range workflows {
+			loaded = append(loaded, loadedWorkflow{path: path, workflow: w})
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// register replaces version slices instead of appending to them, so
+	// copying the map is enough to restore it. Warnings are held back until
+	// the load is known to stick.
+	snapshot := maps.Clone(r.workflows)
+	var warnings ValidationErrors
+	r.deferred = &warnings
+	defer func() { r.deferred = nil }()
+	for _, lw := range dependencyOrder(loaded) {
+		if err := r.register(lw.workflow); err != nil {
+			errs = append(errs, &LoadError{Path: lw.path, WorkflowID: lw.workflow.ID, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		r.workflows = snapshot
+		return errors.Join(errs...)
+	}
+	r.logProblems(warnings)
+	return nil
+}
+
+type loadedWorkflow struct {
+	path     string
+	workflow *Workflow
+}
+
+// guardJSON is the file form of a guard.
+type guardJSON struct {
+	Type  GuardType `json:"type"`
+	Key   string    `json:"key"`
+	Value any       `json:"value"`
+	Name  string    `json:"name"`
+}
+
+// decodeWorkflows parses a workflow file in any of the supported layouts.
+func (r *Registry) decodeWorkflows(data []byte) ([]*Workflow, error) {
+	var raws []json.RawMessage
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &raws); err != nil {
+			return nil, err
+		}
+	default:
+		var probe struct {
+			Workflows []json.RawMessage `json:"workflows"`
+		}
+		if err := json.Unmarshal(trimmed, &probe); err != nil {
+			return nil, err
+		}
+		raws = probe.Workflows
+		if probe.Workflows == nil {
+			raws = []json.RawMessage{trimmed}
+		}
+	}
+
+	workflows := make([]*Workflow, 0, len(raws))
+	for i, raw := range raws {
+		w, err := r.decodeWorkflow(raw)
+		if err != nil {
+			return nil, fmt.Errorf("workflow %d: %w", i, err)
+		}
+		workflows = append(workflows, w)
+	}
+	return workflows, nil
+}
+
+// decodeWorkflow parses one workflow object, resolving edge guards, which the
+// Workflow type itself does not decode.
+func (r *Registry) decodeWorkflow(raw json.RawMessage) (*Workflow, error) {
+	var w Workflow
+	if err := json.Unmarshal(raw, &w); err != nil {
+		return nil, err
+	}
+	var guards struct {
+		Edges []struct {
+			Guard *guardJSON `json:"guard"`
+		} `json:"edges"`
+	}
+	if err := json.Unmarshal(raw, &guards); err != nil {
+		return nil, err
+	}
+	for i, edge := range guards.Edges {
+		if edge.Guard == nil {
+			continue
+		}
+		guard, err := r.resolveGuard(*edge.Guard)
+		if err != nil {
+			return nil, fmt.Errorf("edge '%s': %w", w.Edges[i].ID, err)
+		}
+		w.Edges[i].Guard = guard
+	}
+	return &w, nil
+}
+
+func (r *Registry) resolveGuard(g guardJSON) (Guard, error) {
+	switch g.Type {
+	case GuardExists, GuardNotExists, GuardEquals, GuardNotEquals:
+		if g.Key == "" {
+			return nil, fmt.Errorf("%s guard needs a key", g.Type)
+		}
+		return &BuiltinGuard{Type: g.Type, Key: g.Key, Value: g.Value}, nil
+	case customGuardType:
+		guard, ok := r.options.Guards[g.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom guard '%s'", g.Name)
+		}
+		return guard, nil
+	default:
+		return nil, fmt.Errorf("invalid guard type '%s'", g.Type)
+	}
+}
+
+// dependencyOrder sorts workflows so that invoked workflows come before their
+// invokers. Ties, and workflows in an invocation cycle, keep ID order.
+func dependencyOrder(loaded []loadedWorkflow) []loadedWorkflow {
+	sort.SliceStable(loaded, func(i, j int) bool {
+		a, b := loaded[i].workflow, loaded[j].workflow
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.Version < b.Version
+	})
+
+	byID := make(map[string][]int)
+	for i, lw := range loaded {
+		byID[lw.workflow.ID] = append(byID[lw.workflow.ID], i)
+	}
+	// deps[i] lists the loaded workflows i invokes: every version for a
+	// bare reference, the named one for "id@version".
+	deps := make([][]int, len(loaded))
+	for i, lw := range loaded {
+		for _, nodeID := range sortedNodeIDs(lw.workflow) {
+			node := lw.workflow.Nodes[nodeID]
+			if node.Invokes == nil {
+				continue
+			}
+			id, version, pinned, ok := parseWorkflowRef(node.Invokes.WorkflowID)
+			if !ok {
+				continue
+			}
+			for _, j := range byID[id] {
+				if j != i && (!pinned || loaded[j].workflow.Version == version) {
+					deps[i] = append(deps[i], j)
+				}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(loaded))
+	order := make([]loadedWorkflow, 0, len(loaded))
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != unvisited {
+			return
+		}
+		state[i] = visiting
+		for _, j := range deps[i] {
+			visit(j)
+		}
+		state[i] = done
+		order = append(order, loaded[i])
+	}
+	for i := range loaded {
+		visit(i)
+	}
+	return order
+}
@@ -0,0 +1,177 @@
+package reflex
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const parentJSON = `{
+  "id": "parent",
+  "entry": "START",
+  "nodes": {
+    "START": {"id": "START", "spec": {}},
+    "CALL": {"id": "CALL", "spec": {}, "invokes": {"workflowId": "child", "returnMap": [{"parentKey": "result", "childKey": "out"}]}},
+    "LEFT": {"id": "LEFT", "spec": {}},
+    "RIGHT": {"id": "RIGHT", "spec": {}}
+  },
+  "edges": [
+    {"id": "e1", "from": "START", "to": "CALL", "event": "NEXT"},
+    {"id": "e-left", "from": "CALL", "to": "LEFT", "event": "GO", "guard": {"type": "equals", "key": "result", "value": "left"}},
+    {"id": "e-right", "from": "CALL", "to": "RIGHT", "event": "GO", "guard": {"type": "custom", "name": "notLeft"}}
+  ]
+}`
+
+const bundleJSON = `{"workflows": [
+  {"id": "child", "entry": "A", "nodes": {"A": {"id": "A", "spec": {}}, "B": {"id": "B", "spec": {}}},
+   "edges": [{"id": "c1", "from": "A", "to": "B", "event": "NEXT"}]},
+  {"id": "other", "version": 2, "entry": "X", "nodes": {"X": {"id": "X", "spec": {}}}, "edges": []}
+]}`
+
+func loaderOptions(h *recordingHandler) RegistryOptions {
+	return RegistryOptions{
+		Logger: slog.New(h),
+		Guards: map[string]Guard{
+			"notLeft": &CustomGuardFunc{Name: "notLeft", Fn: func(bb BlackboardReader) (bool, error) {
+				v, _ := bb.Get("result")
+				return v != "left", nil
+			}},
+		},
+	}
+}
+
+func TestRegistryLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"workflows/a-parent.json": {Data: []byte(parentJSON)},
+		"workflows/bundle.json":   {Data: []byte(bundleJSON)},
+		"workflows/README.md":     {Data: []byte("not a workflow")},
+	}
+	h := &recordingHandler{}
+	r := NewRegistry(loaderOptions(h))
+	if err := r.LoadFS(fsys, "workflows/*.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := r.List(); len(ids) != 3 {
+		t.Fatalf("expected 3 workflows, got %v", ids)
+	}
+	if v := r.Versions("other"); len(v) != 1 || v[0] != 2 {
+		t.Errorf("expected other@2, got %v", v)
+	}
+	// The child is registered before its invoker, so no warning is logged.
+	for _, rec := range h.records {
+		if recordAttrs(rec)["code"] == string(ErrInvocationNotFound) {
+			t.Errorf("unexpected warning: %s", rec.Message)
+		}
+	}
+
+	w, _ := r.Get("parent")
+	builtin, ok := w.Edges[1].Guard.(*BuiltinGuard)
+	if !ok || builtin.Type != GuardEquals || builtin.Value != "left" {
+		t.Errorf("expected equals guard, got %#v", w.Edges[1].Guard)
+	}
+	if custom, ok := w.Edges[2].Guard.(*CustomGuardFunc); !ok || custom.Name != "notLeft" {
+		t.Errorf("expected notLeft custom guard, got %#v", w.Edges[2].Guard)
+	}
+
+	// The loaded workflows run.
+	e := NewEngine(r, autoAdvanceAgent())
+	_, _ = e.Init("parent")
+	if res, _ := e.Run(context.Background()); res.Status != StepCompleted {
+		t.Errorf("expected completion, got %s %q", res.Status, res.Reason)
+	}
+}
+
+func TestRegistryLoadFSArrayFile(t *testing.T) {
+	fsys := fstest.MapFS{"all.json": {Data: []byte("[" + parentJSON + "]")}}
+	r := NewRegistry(loaderOptions(&recordingHandler{}))
+	if err := r.LoadFS(fsys, "*.json"); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Has("parent") {
+		t.Error("expected parent registered")
+	}
+}
+
+func TestRegistryLoadFSAtomic(t *testing.T) {
+	broken := strings.Replace(parentJSON, `"entry": "START"`, `"entry": "NOPE"`, 1)
+	fsys := fstest.MapFS{
+		"a.json":      {Data: []byte(broken)},
+		"bundle.json": {Data: []byte(bundleJSON)},
+	}
+	r := NewRegistry(loaderOptions(&recordingHandler{}))
+	_ = r.Register(linearWorkflow("existing"))
+
+	err := r.LoadFS(fsys, "*.json")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	var le *LoadError
+	if !errors.As(err, &le) || le.Path != "a.json" || le.WorkflowID != "parent" {
+		t.Fatalf("expected LoadError for a.json, got %v", err)
+	}
+	assertValidationError(t, err, ErrInvalidEntryNode)
+	if !strings.HasPrefix(err.Error(), "a.json: workflow 'parent': ") {
+		t.Errorf("expected file path in message, got %q", err)
+	}
+	if ids := r.List(); len(ids) != 1 || ids[0] != "existing" {
+		t.Errorf("expected only the pre-existing workflow, got %v", ids)
+	}
+}
+
+func TestRegistryLoadFSRollbackKeepsVersions(t *testing.T) {
+	broken := strings.Replace(parentJSON, `"entry": "START"`, `"entry": "NOPE"`, 1)
+	orphan := `{"id": "child", "version": 0, "entry": "A",
+	  "nodes": {"A": {"id": "A", "spec": {}}, "ORPHAN": {"id": "ORPHAN", "spec": {}}}, "edges": []}`
+	fsys := fstest.MapFS{
+		"a.json": {Data: []byte(broken)},
+		"b.json": {Data: []byte(orphan)},
+	}
+	h := &recordingHandler{}
+	r := NewRegistry(loaderOptions(h))
+	for v := 1; v <= 3; v++ {
+		w := linearWorkflow("child")
+		w.Version = v
+		_ = r.Register(w)
+	}
+
+	if err := r.LoadFS(fsys, "*.json"); err == nil {
+		t.Fatal("expected error")
+	}
+	if v := r.Versions("child"); len(v) != 3 || v[0] != 1 || v[2] != 3 {
+		t.Errorf("expected versions [1 2 3] restored, got %v", v)
+	}
+	for _, rec := range h.records {
+		if recordAttrs(rec)["code"] == string(ErrUnreachableNode) {
+			t.Errorf("unexpected warning for a rolled-back workflow: %s", rec.Message)
+		}
+	}
+}
+
+func TestRegistryLoadFSErrors(t *testing.T) {
+	cases := []struct {
+		name, data, want string
+	}{
+		{"malformed", `{"id": `, "bad.json: "},
+		{"unknown custom guard", strings.Replace(parentJSON, "notLeft", "missing", 1), "unknown custom guard 'missing'"},
+		{"invalid guard type", strings.Replace(parentJSON, `"type": "equals"`, `"type": "eq"`, 1), "invalid guard type 'eq'"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"bad.json": {Data: []byte(tc.data)}}
+			r := NewRegistry(loaderOptions(&recordingHandler{}))
+			err := r.LoadFS(fsys, "*.json")
+			if err == nil || !strings.Contains(err.Error(), tc.want) || !strings.HasPrefix(err.Error(), "bad.json: ") {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	r := NewRegistry()
+	if err := r.LoadFS(fstest.MapFS{}, "*.json"); err == nil {
+		t.Error("expected error when nothing matches")
+	}
+}
@@ -0,0 +1,90 @@
+package reflex
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ---------------------------------------------------------------------------
+// Structured logging (log/slog)
+// ---------------------------------------------------------------------------
+
+// discardLogger is used by engines created without EngineOptions.Logger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// LogEvents returns an EventHandler that writes every event it receives to
+// logger. Register it with Engine.OnAll. engine:error is logged at Error,
+// engine:suspend and engine:complete at Info, everything else at Debug.
+// Blackboard writes log their keys only, never values.
+func LogEvents(logger *slog.Logger) EventHandler {
+	return func(ev Event) {
+		level := slog.LevelDebug
+		switch ev.Type {
+		case EventEngineError:
+			level = slog.LevelError
+		case EventEngineSuspend, EventEngineComplete:
+			level = slog.LevelInfo
+		}
+
+		attrs := []slog.Attr{slog.String("session_id", ev.SessionID)}
+		for _, a := range []struct{ key, value string }{
+			{"workflow", ev.WorkflowID},
+			{"node", ev.NodeID},
+			{"edge", ev.EdgeID},
+			{"invocation_id", ev.InvocationID},
+			{"reason", ev.Reason},
+		} {
+			if a.value != "" {
+				attrs = append(attrs, slog.String(a.key, a.value))
+			}
+		}
+		if len(ev.Entries) > 0 {
+			keys := make([]string, len(ev.Entries))
+			for i, entry := range ev.Entries {
+				keys[i] = entry.Key
+			}
+			attrs = append(attrs, slog.Any("keys", keys))
+		}
+		if ev.Error != nil {
+			attrs = append(attrs, slog.Any("error", ev.Error))
+		}
+		logger.LogAttrs(context.Background(), level, string(ev.Type), attrs...)
+	}
+}
+
+// log writes a record about the engine's current position: session,
+// workflow, node and stack depth are added to attrs.
+func (e *Engine) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if !e.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append([]slog.Attr{
+		slog.String("session_id", e.sessionID),
+		slog.String("workflow", e.currentWorkflowID),
+		slog.String("node", e.currentNodeID),
+		slog.Int("stack_depth", len(e.stack)),
+	}, attrs...)
+	e.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logger returns the configured logger, or slog.Default.
+func (r *Registry) logger() *slog.Logger {
+	if r.options.Logger != nil {
+		return r.options.Logger
+	}
+	return slog.Default()
+}
+
+// logProblems writes validation problems that did not fail an operation.
+func (r *Registry) logProblems(problems ValidationErrors) {
+	for _, p := range problems {
+		attrs := []slog.Attr{
+			slog.String("workflow", p.WorkflowID),
+			slog.String("code", string(p.Code)),
+		}
+		if nodeID, ok := p.Details["nodeId"].(string); ok {
+			attrs = append(attrs, slog.String("node", nodeID))
+		}
+		r.logger().LogAttrs(context.Background(), slog.LevelWarn, p.Message, attrs...)
+	}
+}
@@ -0,0 +1,134 @@
+package reflex
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// recordingHandler keeps every record it handles, at all levels.
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *recordingHandler) find(msg string) []slog.Record {
+	var found []slog.Record
+	for _, r := range h.records {
+		if r.Message == msg {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+func recordAttrs(r slog.Record) map[string]any {
+	attrs := make(map[string]any)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Any()
+		return true
+	})
+	return attrs
+}
+
+func TestRegistryLogger(t *testing.T) {
+	h := &recordingHandler{}
+	r := NewRegistry(RegistryOptions{Logger: slog.New(h)})
+	_ = r.Register(invokerWorkflow("parent", "ghost"))
+
+	var warnings []slog.Record
+	for _, rec := range h.records {
+		if rec.Level == slog.LevelWarn {
+			warnings = append(warnings, rec)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected one warning record, got %d", len(warnings))
+	}
+	attrs := recordAttrs(warnings[0])
+	if attrs["workflow"] != "parent" || attrs["node"] != "A" || attrs["code"] != string(ErrInvocationNotFound) {
+		t.Errorf("unexpected attrs %v", attrs)
+	}
+	if len(h.find("workflow registered")) != 1 {
+		t.Error("expected a registration record")
+	}
+}
+
+func TestEngineLogger(t *testing.T) {
+	t.Run("records every step", func(t *testing.T) {
+		h := &recordingHandler{}
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("linear"))
+		e := NewEngine(r, autoAdvanceAgent(), EngineOptions{Logger: slog.New(h)})
+		sid, _ := e.Init("linear")
+		_, _ = e.Run(context.Background())
+
+		steps := h.find("step")
+		if len(steps) != 3 {
+			t.Fatalf("expected 3 step records, got %d", len(steps))
+		}
+		attrs := recordAttrs(steps[0])
+		if attrs["session_id"] != sid || attrs["workflow"] != "linear" || attrs["node"] != "A" ||
+			attrs["decision_type"] != string(DecisionAdvance) || attrs["edge"] != "e1" || attrs["stack_depth"] != int64(0) {
+			t.Errorf("unexpected attrs %v", attrs)
+		}
+		if len(h.find("session completed")) != 1 {
+			t.Error("expected a completion record")
+		}
+	})
+
+	t.Run("agent errors are logged at error level", func(t *testing.T) {
+		h := &recordingHandler{}
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		agent := agentFunc(func(context.Context, DecisionContext) (Decision, error) {
+			return Decision{}, errors.New("model unavailable")
+		})
+		e := NewEngine(r, agent, EngineOptions{Logger: slog.New(h)})
+		_, _ = e.Init("wf")
+		_, _ = e.Step(context.Background())
+
+		failed := h.find("decision agent failed")
+		if len(failed) != 1 || failed[0].Level != slog.LevelError {
+			t.Fatalf("expected one error record, got %v", failed)
+		}
+		if err, _ := recordAttrs(failed[0])["error"].(error); err == nil || err.Error() != "model unavailable" {
+			t.Errorf("expected agent error attr, got %v", recordAttrs(failed[0]))
+		}
+	})
+}
+
+func TestLogEvents(t *testing.T) {
+	h := &recordingHandler{}
+	e, _ := setupLinear()
+	var events []Event
+	e.OnAll(func(ev Event) { events = append(events, ev) })
+	e.OnAll(LogEvents(slog.New(h)))
+	_, _ = e.Init("linear")
+	_, _ = e.Run(context.Background())
+
+	if len(h.records) != len(events) || len(events) == 0 {
+		t.Fatalf("expected a record per event, got %d records for %d events", len(h.records), len(events))
+	}
+	for i, rec := range h.records {
+		if rec.Message != string(events[i].Type) {
+			t.Errorf("record %d: expected %s, got %s", i, events[i].Type, rec.Message)
+		}
+	}
+	last := h.records[len(h.records)-1]
+	if last.Message != string(EventEngineComplete) || last.Level != slog.LevelInfo {
+		t.Errorf("expected engine:complete at info, got %s at %s", last.Message, last.Level)
+	}
+}
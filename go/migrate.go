@@ -0,0 +1,183 @@
+package reflex
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// ---------------------------------------------------------------------------
+// Migrating sessions between workflow versions
+// ---------------------------------------------------------------------------
+
+// Migration moves sessions from one registered version of a workflow to
+// another. Both versions must be registered while it is applied; the old one
+// can be unregistered afterwards.
+type Migration struct {
+	WorkflowID  string
+	FromVersion int
+	ToVersion   int
+	// NodeMap maps node IDs of the old version to node IDs of the new one.
+	// Nodes not listed keep their ID.
+	NodeMap map[string]string
+}
+
+// MapNode returns the new node ID for an old one.
+func (m Migration) MapNode(nodeID string) string {
+	if to, ok := m.NodeMap[nodeID]; ok {
+		return to
+	}
+	return nodeID
+}
+
+func (m Migration) from() string { return workflowRef(m.WorkflowID, m.FromVersion) }
+func (m Migration) to() string   { return workflowRef(m.WorkflowID, m.ToVersion) }
+
+// migrationTarget checks that both versions m names are registered and
+// returns them.
+func (r *Registry) migrationTarget(m Migration) (from, to *Workflow, err error) {
+	var errs ValidationErrors
+	for _, ref := range []string{m.from(), m.to()} {
+		if _, ok := r.Get(ref); !ok {
+			err := newValidationError(ErrWorkflowNotFound, m.WorkflowID,
+				fmt.Sprintf("cannot migrate: workflow '%s' is not registered", ref))
+			err.Details = map[string]any{"ref": ref}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	from, _ = r.Get(m.from())
+	to, _ = r.Get(m.to())
+	return from, to, nil
+}
+
+// unmappedFrames reports every frame inside the old version whose node has
+// no target in the new one, or whose invocation node maps to a node that
+// invokes nothing: the frame's returnMap would outlive its invocation.
+func unmappedFrames(m Migration, from, to *Workflow, sessionID string, frames []StackFrame) ValidationErrors {
+	var errs ValidationErrors
+	for _, f := range frames {
+		if f.WorkflowID != m.WorkflowID || f.WorkflowVersion != m.FromVersion {
+			continue
+		}
+		target, ok := to.Nodes[m.MapNode(f.CurrentNodeID)]
+		if !ok {
+			errs = append(errs, unmappedNode(m, sessionID, f.CurrentNodeID, "which does not exist"))
+			continue
+		}
+		if old := from.Nodes[f.CurrentNodeID]; old != nil && old.Invokes != nil && target.Invokes == nil {
+			errs = append(errs, unmappedNode(m, sessionID, f.CurrentNodeID, "which is not an invocation node"))
+		}
+	}
+	return errs
+}
+
+// remapFrames moves the frames inside the old version to the new one in
+// place. Frames at invocation nodes adopt the new node's returnMap.
+func remapFrames(m Migration, to *Workflow, frames []StackFrame) {
+	for i := range frames {
+		f := &frames[i]
+		if f.WorkflowID != m.WorkflowID || f.WorkflowVersion != m.FromVersion {
+			continue
+		}
+		f.WorkflowVersion = m.ToVersion
+		f.CurrentNodeID = m.MapNode(f.CurrentNodeID)
+		if node := to.Nodes[f.CurrentNodeID]; node.Invokes != nil {
+			f.ReturnMap = node.Invokes.ReturnMap
+		}
+	}
+}
+
+func unmappedNode(m Migration, sessionID, nodeID, problem string) *ValidationError {
+	err := newValidationError(ErrMigrationUnmapped, m.WorkflowID,
+		fmt.Sprintf("cannot migrate %s to %s: node '%s' maps to '%s' %s",
+			m.from(), m.to(), nodeID, m.MapNode(nodeID), problem))
+	err.Details = map[string]any{"nodeId": nodeID, "target": m.MapNode(nodeID)}
+	if sessionID != "" {
+		err.Details["sessionId"] = sessionID
+	}
+	return err
+}
+
+// MigrateFrames applies m to a persisted call stack and returns the migrated
+// copy. Persistence layers can store the session's current position as one
+// more frame to have it migrated too. Nothing is changed if any frame inside
+// the old version has no target node, or sits at an invocation node mapped to
+// one that is not; the error is a ValidationErrors with an
+// ErrMigrationUnmapped entry per such frame.
+func (r *Registry) MigrateFrames(m Migration, frames []StackFrame) ([]StackFrame, error) {
+	from, to, err := r.migrationTarget(m)
+	if err != nil {
+		return nil, err
+	}
+	if errs := unmappedFrames(m, from, to, "", frames); len(errs) > 0 {
+		return nil, errs
+	}
+	cp := append([]StackFrame(nil), frames...)
+	remapFrames(m, to, cp)
+	return cp, nil
+}
+
+// Migrate applies m to the engine's session if it is running or suspended
+// inside the old version, at its current node or in any stack frame. The
+// session is left untouched if any of those nodes has no target, or is an
+// invocation node whose target invokes nothing. On success
+// it emits a workflow:migrate event and records the new version's
+// fingerprint. Sessions not inside the old version are ignored.
+func (e *Engine) Migrate(m Migration) error {
+	return MigrateSessions(m, e)
+}
+
+// MigrateSessions applies m to every engine's session, checking all of them
+// first: if any session cannot be migrated, none is, and the error holds the
+// problems of every session.
+func MigrateSessions(m Migration, engines ...*Engine) error {
+	var errs ValidationErrors
+	var affected []*Engine
+	for _, e := range engines {
+		if len(e.SessionsIn(m.WorkflowID, m.FromVersion)) == 0 {
+			continue
+		}
+		from, to, err := e.registry.migrationTarget(m)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, unmappedFrames(m, from, to, e.sessionID, e.migrationFrames())...)
+		affected = append(affected, e)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	for _, e := range affected {
+		_, to, _ := e.registry.migrationTarget(m)
+		e.applyMigration(m, to)
+	}
+	return nil
+}
+
+// migrationFrames returns the session's position as a frame followed by its
+// stack.
+func (e *Engine) migrationFrames() []StackFrame {
+	current := StackFrame{WorkflowID: e.currentWorkflowID, WorkflowVersion: e.currentVersion, CurrentNodeID: e.currentNodeID}
+	return append([]StackFrame{current}, e.stack...)
+}
+
+func (e *Engine) applyMigration(m Migration, to *Workflow) {
+	remapFrames(m, to, e.stack)
+	if e.currentWorkflowID == m.WorkflowID && e.currentVersion == m.FromVersion {
+		e.currentVersion = m.ToVersion
+		e.currentNodeID = m.MapNode(e.currentNodeID)
+	}
+	e.recordFingerprint(to)
+	e.log(context.Background(), slog.LevelInfo, "session migrated",
+		slog.Int("from_version", m.FromVersion), slog.Int("to_version", m.ToVersion))
+	e.emit(EventWorkflowMigrate, Event{
+		Type:            EventWorkflowMigrate,
+		WorkflowID:      m.WorkflowID,
+		WorkflowVersion: m.ToVersion,
+		NodeID:          e.currentNodeID,
+		Migration:       &m,
+	})
+}
@@ -0,0 +1,167 @@
+package reflex
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupMigration suspends a session inside child@0, invoked from parent@1,
+// and registers parent@2 with renamed nodes.
+func setupMigration(t *testing.T) (*Engine, *Registry, *bool) {
+	t.Helper()
+	r := NewRegistry()
+	parentV1 := invokerWorkflow("parent", "child")
+	parentV1.Version = 1
+	for _, w := range []*Workflow{linearWorkflow("child"), parentV1} {
+		if err := r.Register(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resumed := false
+	e := NewEngine(r, agentFunc(func(ctx context.Context, dc DecisionContext) (Decision, error) {
+		if dc.Workflow.ID == "child" && dc.Node.ID == "B" && !resumed {
+			return Decision{Type: DecisionSuspend, Reason: "waiting"}, nil
+		}
+		return autoAdvanceAgent().Resolve(ctx, dc)
+	}))
+	_, _ = e.Init("parent")
+	if result, _ := e.Run(context.Background()); result.Status != StepSuspended {
+		t.Fatalf("expected suspension in child, got %+v", result)
+	}
+
+	parentV2, err := NewWorkflow("parent").Version(2).
+		Node("CALL", nil).Invoke("child", Return("result", "C")).
+		Node("DONE", nil).
+		Edge("CALL", "DONE").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(parentV2); err != nil {
+		t.Fatal(err)
+	}
+	return e, r, &resumed
+}
+
+func TestEngineMigrate(t *testing.T) {
+	e, _, resumed := setupMigration(t)
+	var events []Event
+	e.On(EventWorkflowMigrate, func(ev Event) { events = append(events, ev) })
+
+	m := Migration{WorkflowID: "parent", FromVersion: 1, ToVersion: 2, NodeMap: map[string]string{"A": "CALL", "B": "DONE"}}
+	if err := e.Migrate(m); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := e.Stack()[0]
+	if frame.WorkflowVersion != 2 || frame.CurrentNodeID != "CALL" {
+		t.Errorf("expected frame at parent@2/CALL, got %+v", frame)
+	}
+	if !reflect.DeepEqual(frame.ReturnMap, []ReturnMapping{{ParentKey: "result", ChildKey: "C"}}) {
+		t.Errorf("expected the new returnMap, got %v", frame.ReturnMap)
+	}
+	if len(events) != 1 || events[0].Migration == nil || events[0].Migration.ToVersion != 2 || events[0].SessionID != e.SessionID() {
+		t.Errorf("expected one workflow:migrate event, got %+v", events)
+	}
+	if _, ok := e.Fingerprints()["parent@2"]; !ok {
+		t.Error("expected parent@2 fingerprint recorded")
+	}
+	if e.SessionsIn("parent", 1) != nil || e.SessionsIn("parent", 2) == nil {
+		t.Error("expected the session to have left parent@1")
+	}
+
+	*resumed = true
+	result, err := e.Run(context.Background())
+	if err != nil || result.Status != StepCompleted {
+		t.Fatalf("expected completion, got %+v, %v", result, err)
+	}
+	if w := e.CurrentWorkflow(); w.Version != 2 || e.CurrentNode().ID != "DONE" {
+		t.Errorf("expected to finish at parent@2/DONE, got %s/%s", w.Ref(), e.CurrentNode().ID)
+	}
+}
+
+func TestEngineMigrateUnmappedNode(t *testing.T) {
+	e, _, _ := setupMigration(t)
+	events := 0
+	e.On(EventWorkflowMigrate, func(Event) { events++ })
+
+	err := e.Migrate(Migration{WorkflowID: "parent", FromVersion: 1, ToVersion: 2})
+	errs := validationErrors(t, err)
+	if len(errs) != 1 || errs[0].Code != ErrMigrationUnmapped || errs[0].Details["nodeId"] != "A" ||
+		errs[0].Details["sessionId"] != e.SessionID() {
+		t.Fatalf("expected A reported as unmapped, got %v", errs)
+	}
+	if e.Stack()[0].WorkflowVersion != 1 || events != 0 {
+		t.Error("expected the session to be left untouched")
+	}
+
+	if err := e.Migrate(Migration{WorkflowID: "parent", FromVersion: 1, ToVersion: 3}); err == nil {
+		t.Error("expected an unregistered target version to fail")
+	}
+	if err := e.Migrate(Migration{WorkflowID: "other", FromVersion: 0, ToVersion: 1}); err != nil {
+		t.Errorf("expected sessions outside the old version to be ignored: %v", err)
+	}
+}
+
+func TestEngineMigrateInvocationToPlainNode(t *testing.T) {
+	e, _, _ := setupMigration(t)
+	frame := e.Stack()[0]
+
+	// parent@1/A invokes child; parent@2/DONE invokes nothing, so the frame's
+	// returnMap would have no invocation left to belong to.
+	err := e.Migrate(Migration{WorkflowID: "parent", FromVersion: 1, ToVersion: 2, NodeMap: map[string]string{"A": "DONE"}})
+	errs := validationErrors(t, err)
+	if len(errs) != 1 || errs[0].Code != ErrMigrationUnmapped || errs[0].Details["target"] != "DONE" ||
+		!strings.Contains(errs[0].Message, "not an invocation node") {
+		t.Fatalf("expected A -> DONE rejected, got %v", errs)
+	}
+	if !reflect.DeepEqual(e.Stack()[0], frame) {
+		t.Error("expected the session to be left untouched")
+	}
+}
+
+func TestMigrateSessionsAllOrNothing(t *testing.T) {
+	good, r, _ := setupMigration(t)
+	bad := NewEngine(r, agentFunc(func(ctx context.Context, dc DecisionContext) (Decision, error) {
+		if dc.Workflow.ID == "parent" && dc.Node.ID == "B" {
+			return Decision{Type: DecisionSuspend}, nil
+		}
+		return autoAdvanceAgent().Resolve(ctx, dc)
+	}))
+	_, _ = bad.Init("parent@1")
+	if _, _ = bad.Run(context.Background()); bad.CurrentNode().ID != "B" {
+		t.Fatalf("expected second session suspended at parent@1/B, got %s", bad.CurrentNode().ID)
+	}
+
+	m := Migration{WorkflowID: "parent", FromVersion: 1, ToVersion: 2, NodeMap: map[string]string{"A": "CALL"}}
+	errs := validationErrors(t, MigrateSessions(m, good, bad))
+	if len(errs) != 1 || errs[0].Details["sessionId"] != bad.SessionID() {
+		t.Fatalf("expected only the second session reported, got %v", errs)
+	}
+	if good.Stack()[0].WorkflowVersion != 1 {
+		t.Error("expected no session migrated")
+	}
+}
+
+func TestRegistryMigrateFrames(t *testing.T) {
+	_, r, _ := setupMigration(t)
+	frames := []StackFrame{
+		{WorkflowID: "child", CurrentNodeID: "B"},
+		{WorkflowID: "parent", WorkflowVersion: 1, CurrentNodeID: "A"},
+	}
+	m := Migration{WorkflowID: "parent", FromVersion: 1, ToVersion: 2, NodeMap: map[string]string{"A": "CALL"}}
+	migrated, err := r.MigrateFrames(m, frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated[0].WorkflowID != "child" || migrated[0].CurrentNodeID != "B" ||
+		migrated[1].WorkflowVersion != 2 || migrated[1].CurrentNodeID != "CALL" {
+		t.Errorf("unexpected migrated frames: %+v", migrated)
+	}
+	if frames[1].WorkflowVersion != 1 {
+		t.Error("expected the input frames to be left untouched")
+	}
+}
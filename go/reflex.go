@@ -1,12 +1,13 @@
 package reflex
 
 // CreateRegistry creates a new WorkflowRegistry. Register workflows before
-// creating an engine.
-func CreateRegistry() *Registry {
-	return NewRegistry()
+// creating an engine. An optional RegistryOptions may be provided.
+func CreateRegistry(opts ...RegistryOptions) *Registry {
+	return NewRegistry(opts...)
 }
 
 // CreateEngine creates a ReflexEngine bound to a registry and decision agent.
-func CreateEngine(registry *Registry, agent DecisionAgent) *Engine {
-	return NewEngine(registry, agent)
+// An optional EngineOptions may be provided.
+func CreateEngine(registry *Registry, agent DecisionAgent, opts ...EngineOptions) *Engine {
+	return NewEngine(registry, agent, opts...)
 }
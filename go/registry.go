@@ -2,8 +2,13 @@ package reflex
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -15,18 +20,55 @@ import (
 type ValidationErrorCode string
 
 const (
-	ErrCycleDetected      ValidationErrorCode = "CYCLE_DETECTED"
-	ErrInvalidEdge        ValidationErrorCode = "INVALID_EDGE"
-	ErrInvalidEntryNode   ValidationErrorCode = "INVALID_ENTRY_NODE"
-	ErrNoTerminalNodes    ValidationErrorCode = "NO_TERMINAL_NODES"
-	ErrDuplicateWorkflowID ValidationErrorCode = "DUPLICATE_WORKFLOW_ID"
-	ErrNodeIDMismatch     ValidationErrorCode = "NODE_ID_MISMATCH"
-	ErrEmptyWorkflow      ValidationErrorCode = "EMPTY_WORKFLOW"
+	ErrCycleDetected        ValidationErrorCode = "CYCLE_DETECTED"
+	ErrInvalidEdge          ValidationErrorCode = "INVALID_EDGE"
+	ErrInvalidEntryNode     ValidationErrorCode = "INVALID_ENTRY_NODE"
+	ErrNoTerminalNodes      ValidationErrorCode = "NO_TERMINAL_NODES"
+	ErrDuplicateWorkflowID  ValidationErrorCode = "DUPLICATE_WORKFLOW_ID"
+	ErrNodeIDMismatch       ValidationErrorCode = "NODE_ID_MISMATCH"
+	ErrEmptyWorkflow        ValidationErrorCode = "EMPTY_WORKFLOW"
+	ErrInvalidKeyPattern    ValidationErrorCode = "INVALID_KEY_PATTERN"
+	ErrInvalidWorkflowID    ValidationErrorCode = "INVALID_WORKFLOW_ID"
+	ErrWorkflowNotFound     ValidationErrorCode = "WORKFLOW_NOT_FOUND"
+	ErrWorkflowInUse        ValidationErrorCode = "WORKFLOW_IN_USE"
+	ErrInvocationNotFound   ValidationErrorCode = "INVOCATION_TARGET_NOT_FOUND"
+	ErrUnreachableReturnKey ValidationErrorCode = "UNREACHABLE_RETURN_KEY"
+	ErrInvocationCycle      ValidationErrorCode = "INVOCATION_CYCLE"
+	ErrDuplicateEdgeID      ValidationErrorCode = "DUPLICATE_EDGE_ID"
+	ErrEmptyEdgeID          ValidationErrorCode = "EMPTY_EDGE_ID"
+	ErrUnreachableNode      ValidationErrorCode = "UNREACHABLE_NODE"
+	ErrPotentialDeadEnd     ValidationErrorCode = "POTENTIAL_DEAD_END"
+	ErrMissingInput         ValidationErrorCode = "MISSING_INPUT"
+	ErrConditionalInput     ValidationErrorCode = "CONDITIONAL_INPUT"
+	ErrUnguaranteedReturn   ValidationErrorCode = "UNGUARANTEED_RETURN_KEY"
+	ErrNoContracts          ValidationErrorCode = "NO_CONTRACTS"
+	ErrInvalidNodeSpec      ValidationErrorCode = "INVALID_NODE_SPEC"
+	ErrCustomValidation     ValidationErrorCode = "CUSTOM_VALIDATION"
+	ErrDuplicateNodeID      ValidationErrorCode = "DUPLICATE_NODE_ID"
+	ErrBuilderUsage         ValidationErrorCode = "INVALID_BUILDER_USAGE"
+	ErrFingerprintMismatch  ValidationErrorCode = "FINGERPRINT_MISMATCH"
+	ErrMigrationUnmapped    ValidationErrorCode = "MIGRATION_UNMAPPED_NODE"
+	ErrStrandedNode         ValidationErrorCode = "STRANDED_NODE"
+	ErrTemplateParams       ValidationErrorCode = "INVALID_TEMPLATE_PARAMS"
+	ErrInvalidExtension     ValidationErrorCode = "INVALID_EXTENSION"
+)
+
+// Severity distinguishes problems that prevent registration from advisory
+// ones.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
 )
 
 // ValidationError is returned when a workflow fails structural validation.
+// Details names the offending elements under "nodeId", "edgeId" and similar
+// keys when the check can attribute the problem.
 type ValidationError struct {
 	Code       ValidationErrorCode
+	Severity   Severity
 	WorkflowID string
 	Message    string
 	Details    map[string]any
@@ -37,7 +79,76 @@ func (e *ValidationError) Error() string {
 }
 
 func newValidationError(code ValidationErrorCode, wfID, msg string) *ValidationError {
-	return &ValidationError{Code: code, WorkflowID: wfID, Message: msg}
+	return &ValidationError{Code: code, Severity: SeverityError, WorkflowID: wfID, Message: msg}
+}
+
+func newValidationWarning(code ValidationErrorCode, wfID, msg string) *ValidationError {
+	return &ValidationError{Code: code, Severity: SeverityWarning, WorkflowID: wfID, Message: msg}
+}
+
+func newValidationInfo(code ValidationErrorCode, wfID, msg string) *ValidationError {
+	return &ValidationError{Code: code, Severity: SeverityInfo, WorkflowID: wfID, Message: msg}
+}
+
+// ValidationErrors collects every problem found by one validation run. It
+// unwraps like the result of errors.Join, so errors.As and errors.Is reach the
+// individual *ValidationError values.
+type ValidationErrors []*ValidationError
+
+// Error joins the messages of all entries with newlines.
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Message
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the entries as a slice of errors.
+func (errs ValidationErrors) Unwrap() []error {
+	result := make([]error, len(errs))
+	for i, e := range errs {
+		result[i] = e
+	}
+	return result
+}
+
+// Errors returns the entries with SeverityError.
+func (errs ValidationErrors) Errors() ValidationErrors {
+	return errs.filter(SeverityError)
+}
+
+// Warnings returns the entries with SeverityWarning.
+func (errs ValidationErrors) Warnings() ValidationErrors {
+	return errs.filter(SeverityWarning)
+}
+
+// Info returns the entries with SeverityInfo.
+func (errs ValidationErrors) Info() ValidationErrors {
+	return errs.filter(SeverityInfo)
+}
+
+// HasErrors reports whether any entry has SeverityError.
+func (errs ValidationErrors) HasErrors() bool {
+	return len(errs.Errors()) > 0
+}
+
+// Err returns errs as an error, or nil if it is empty.
+func (errs ValidationErrors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (errs ValidationErrors) filter(severity Severity) ValidationErrors {
+	var result ValidationErrors
+	for _, e := range errs {
+		if e.Severity == severity {
+			result = append(result, e)
+		}
+	}
+	return result
 }
 
 // ---------------------------------------------------------------------------
@@ -45,69 +156,611 @@ func newValidationError(code ValidationErrorCode, wfID, msg string) *ValidationE
 // ---------------------------------------------------------------------------
 
 // Registry stores validated workflows and provides lookup by ID.
+//
+// A workflow ID may be registered in several versions (Workflow.Version).
+// Lookups accept a workflow reference: a bare ID resolves to the highest
+// registered version, "id@version" to that exact version.
 type Registry struct {
-	mu        sync.RWMutex
-	workflows map[string]*Workflow
+	mu         sync.RWMutex
+	workflows  map[string][]*Workflow // versions in ascending order
+	options    RegistryOptions
+	validators []Validator
+	templates  map[string]*Template
+	// deferred collects the warnings of registrations that may still be
+	// rolled back, instead of logging them. Nil outside LoadFS.
+	deferred *ValidationErrors
+}
+
+// RegistryOptions configures optional registry behaviour. Pass it to
+// NewRegistry.
+type RegistryOptions struct {
+	// RemovalPolicy decides what Unregister and Replace do when the target is
+	// still invoked by other workflows or occupied by a tracked session.
+	// Defaults to RemovalRefuse.
+	RemovalPolicy RemovalPolicy
+	// Sessions reports which sessions are inside a workflow. Consulted by
+	// Unregister and Replace; nil means no sessions are tracked. Engines
+	// created after the registry are added with TrackSessions.
+	Sessions SessionTracker
+	// ValidationMode decides whether Register and Replace return the first
+	// error or every problem found. Defaults to ValidateFailFast.
+	ValidationMode ValidationMode
+	// Logger receives registration, removal and validation warning records.
+	// Defaults to slog.Default().
+	Logger *slog.Logger
+	// Guards resolves custom guards referenced by name in workflow files
+	// loaded with LoadFS.
+	Guards map[string]Guard
+}
+
+// ValidationMode controls how many problems a failed registration reports.
+type ValidationMode int
+
+const (
+	// ValidateFailFast returns the first error as a *ValidationError.
+	ValidateFailFast ValidationMode = iota
+	// ValidateAll runs every check and returns ValidationErrors holding all
+	// errors and warnings.
+	ValidateAll
+)
+
+// RemovalPolicy controls how Unregister and Replace treat a workflow in use.
+type RemovalPolicy int
+
+const (
+	// RemovalRefuse rejects the change with an ErrWorkflowInUse error.
+	RemovalRefuse RemovalPolicy = iota
+	// RemovalWarn logs a warning and applies the change anyway.
+	RemovalWarn
+)
+
+// SessionTracker reports the sessions currently inside a workflow version,
+// either at its current node or suspended in a stack frame. *Engine
+// implements it for its own session; SessionTrackers combines several.
+type SessionTracker interface {
+	SessionsIn(workflowID string, version int) []string
+}
+
+// SessionTrackers combines several trackers into one.
+type SessionTrackers []SessionTracker
+
+// SessionsIn returns the sessions reported by every tracker.
+func (ts SessionTrackers) SessionsIn(workflowID string, version int) []string {
+	var sessions []string
+	for _, t := range ts {
+		sessions = append(sessions, t.SessionsIn(workflowID, version)...)
+	}
+	return sessions
+}
+
+// TrackSessions adds trackers to the ones Unregister and Replace consult,
+// typically the engines running against r:
+//
+//	engine := reflex.NewEngine(registry, agent)
+//	registry.TrackSessions(engine)
+func (r *Registry) TrackSessions(trackers ...SessionTracker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var all SessionTrackers
+	switch t := r.options.Sessions.(type) {
+	case nil:
+	case SessionTrackers:
+		all = slices.Clone(t)
+	default:
+		all = SessionTrackers{t}
+	}
+	r.options.Sessions = append(all, trackers...)
 }
 
 // NewRegistry creates an empty workflow registry.
-func NewRegistry() *Registry {
-	return &Registry{workflows: make(map[string]*Workflow)}
+// An optional RegistryOptions may be provided.
+func NewRegistry(opts ...RegistryOptions) *Registry {
+	r := &Registry{workflows: make(map[string][]*Workflow)}
+	if len(opts) > 0 {
+		r.options = opts[0]
+	}
+	return r
 }
 
 // Register validates and stores a workflow. Returns a ValidationError on
-// structural problems.
+// structural problems. A workflow whose ID is already registered is accepted
+// as long as its Version differs from every registered version.
 func (r *Registry) Register(w *Workflow) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.register(w)
+}
 
-	if err := r.validateNoDuplicate(w); err != nil {
+// register validates and stores w. Caller must hold r.mu.
+func (r *Registry) register(w *Workflow) error {
+	if err := r.result(r.checkWorkflow(w, true)); err != nil {
 		return err
 	}
-	if err := validateNotEmpty(w); err != nil {
+
+	// Version slices are never modified in place, so snapshots taken here
+	// and by LoadFS stay intact.
+	snapshot := maps.Clone(r.workflows)
+	versions := append(slices.Clone(r.workflows[w.ID]), w)
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	r.workflows[w.ID] = versions
+	// Instances are created after w is stored so that a template invoking
+	// itself finds its own instance instead of recursing. If one fails, w
+	// and every instance created for it are removed again.
+	if err := r.instantiateInvocations(w); err != nil {
+		r.workflows = snapshot
 		return err
 	}
-	if err := validateEntryNode(w); err != nil {
-		return err
+	r.logger().Debug("workflow registered", "workflow", w.ID, "version", w.Version, "nodes", len(w.Nodes))
+	return nil
+}
+
+// Unregister removes a workflow. A bare ID removes every version;
+// "id@version" removes one. If other workflows invoke the target, or the
+// configured SessionTracker reports sessions inside it, Unregister returns an
+// ErrWorkflowInUse ValidationError under RemovalRefuse and logs a warning
+// under RemovalWarn. Workflows left with dangling invocation references are
+// reported afterwards.
+func (r *Registry) Unregister(ref string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	targets := r.resolveAll(ref)
+	if len(targets) == 0 {
+		return newValidationError(ErrWorkflowNotFound, ref,
+			fmt.Sprintf("cannot unregister: workflow '%s' is not registered", ref))
+	}
+
+	id := targets[0].ID
+	remaining := r.workflows[id][:0:0]
+	for _, w := range r.workflows[id] {
+		if !containsWorkflow(targets, w) {
+			remaining = append(remaining, w)
+		}
 	}
-	if err := validateNodeIDConsistency(w); err != nil {
+	after := func(ref string) (*Workflow, bool) {
+		refID, version, pinned, ok := parseWorkflowRef(ref)
+		if !ok || refID != id {
+			return r.lookup(ref)
+		}
+		return pickVersion(remaining, version, pinned)
+	}
+	dependents := r.dependents(targets, after)
+	if err := r.checkInUse("unregister", ref, targets, dependents); err != nil {
 		return err
 	}
-	if err := validateEdgeIntegrity(w); err != nil {
+
+	if len(remaining) == 0 {
+		delete(r.workflows, id)
+	} else {
+		r.workflows[id] = remaining
+	}
+	r.logger().Debug("workflow unregistered", "workflow", ref, "versions", len(targets))
+	r.revalidateDependents(dependents)
+	return nil
+}
+
+// Replace swaps the registered workflow with the same ID and Version for w.
+// w is validated like a new registration, and the instances its template
+// invocations need are created. Workflows invoking the replaced version count
+// as dependents only if their invocations no longer validate against w;
+// they and tracked sessions are handled as in Unregister.
+func (r *Registry) Replace(w *Workflow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.lookup(w.Ref())
+	if !ok {
+		return newValidationError(ErrWorkflowNotFound, w.ID,
+			fmt.Sprintf("cannot replace: workflow '%s' version %d is not registered", w.ID, w.Version))
+	}
+	if err := r.result(r.checkWorkflow(w, false)); err != nil {
 		return err
 	}
-	if err := validateTerminalNodes(w); err != nil {
+
+	targets := []*Workflow{old}
+	invokers := r.dependents(targets, func(string) (*Workflow, bool) { return nil, false })
+
+	// Version slices are never modified in place; see register.
+	snapshot := maps.Clone(r.workflows)
+	versions := slices.Clone(r.workflows[w.ID])
+	for i := range versions {
+		if versions[i] == old {
+			versions[i] = w
+		}
+	}
+	r.workflows[w.ID] = versions
+
+	var dependents []*Workflow
+	for _, invoker := range invokers {
+		if r.invokesBroken(invoker, w) {
+			dependents = append(dependents, invoker)
+		}
+	}
+	if err := r.checkInUse("replace", w.Ref(), targets, dependents); err != nil {
+		r.workflows = snapshot
 		return err
 	}
-	if err := validateAcyclic(w); err != nil {
+	if err := r.instantiateInvocations(w); err != nil {
+		r.workflows = snapshot
 		return err
 	}
-	r.warnInvocationRefs(w)
-
-	r.workflows[w.ID] = w
+	r.logger().Debug("workflow replaced", "workflow", w.ID, "version", w.Version)
+	r.revalidateDependents(dependents)
 	return nil
 }
 
-// Get returns the workflow with the given ID, or false if not found.
-func (r *Registry) Get(id string) (*Workflow, bool) {
+// invokesBroken reports whether an invocation in invoker that resolves to
+// target fails validateInvocation. Caller must hold r.mu.
+func (r *Registry) invokesBroken(invoker, target *Workflow) bool {
+	for _, nodeID := range sortedNodeIDs(invoker) {
+		node := invoker.Nodes[nodeID]
+		if node.Invokes == nil {
+			continue
+		}
+		if child, ok := r.invocationTarget(node.Invokes); !ok || child != target {
+			continue
+		}
+		if len(r.validateInvocation(invoker, node)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Check runs every validation check on w against the current registry
+// contents without registering it, regardless of ValidationMode. The result
+// includes warnings; use HasErrors to decide whether Register would refuse w.
+func (r *Registry) Check(w *Workflow) ValidationErrors {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.checkWorkflow(w, true)
+}
+
+// Get returns the workflow for a reference, or false if not found. A bare ID
+// returns the latest version; "id@version" returns that exact version.
+func (r *Registry) Get(ref string) (*Workflow, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	w, ok := r.workflows[id]
-	return w, ok
+	return r.lookup(ref)
 }
 
-// Has returns true if a workflow with the given ID is registered.
-func (r *Registry) Has(id string) bool {
+// Has returns true if a workflow reference resolves to a registered workflow.
+func (r *Registry) Has(ref string) bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	_, ok := r.workflows[id]
+	_, ok := r.lookup(ref)
 	return ok
 }
 
-// List returns all registered workflow IDs in sorted order.
+// Versions returns the registered versions of a workflow ID in ascending
+// order, or nil if the ID is not registered.
+func (r *Registry) Versions(id string) []int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var versions []int
+	for _, w := range r.workflows[id] {
+		versions = append(versions, w.Version)
+	}
+	return versions
+}
+
+// List returns all registered workflow IDs in sorted order. Each ID appears
+// once regardless of how many versions are registered.
 func (r *Registry) List() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	return r.sortedIDs()
+}
+
+// ---------------------------------------------------------------------------
+// Whole-registry validation
+// ---------------------------------------------------------------------------
+
+// Validate checks the invocation graph across every registered workflow, which
+// Register cannot do while targets may still be missing. Like Register, it
+// returns nil unless an error is found, logging any warnings; the result is
+// otherwise a ValidationErrors holding every problem. CheckInvocations returns
+// the problems without logging.
+func (r *Registry) Validate() error {
+	problems := r.CheckInvocations()
+	if !problems.HasErrors() {
+		r.logProblems(problems.Warnings())
+		return nil
+	}
+	return problems
+}
+
+// CheckInvocations runs the checks of Validate and returns every problem,
+// warnings included:
+//
+//   - ErrInvocationNotFound: an InvocationSpec targets an unregistered workflow.
+//   - ErrUnreachableReturnKey: a returnMap ChildKey is not among the child's
+//     OutputKeys or the ParentKeys of the child's own returnMaps. Only
+//     children that declare outputs are checked.
+//   - ErrInvocationCycle: workflows invoke each other recursively. The cycle
+//     may be intentional; Details["cycle"] holds its path of workflow refs.
+//
+// Missing targets and unreachable keys have SeverityError, cycles
+// SeverityWarning. Problems are in a deterministic order.
+func (r *Registry) CheckInvocations() ValidationErrors {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var errs ValidationErrors
+	for _, id := range r.sortedIDs() {
+		for _, w := range r.workflows[id] {
+			for _, nodeID := range sortedNodeIDs(w) {
+				errs = append(errs, r.validateInvocation(w, w.Nodes[nodeID])...)
+			}
+		}
+	}
+	return append(errs, r.invocationCycles()...)
+}
+
+// validateInvocation checks one node's InvocationSpec. Caller must hold r.mu.
+func (r *Registry) validateInvocation(w *Workflow, node *Node) ValidationErrors {
+	if node.Invokes == nil {
+		return nil
+	}
+	target := node.Invokes.WorkflowID
+	child, ok := r.invocationTarget(node.Invokes)
+	if !ok {
+		err := newValidationError(ErrInvocationNotFound, w.ID,
+			fmt.Sprintf("workflow '%s', node '%s': invokes '%s' which is not registered", w.Ref(), node.ID, target))
+		err.Details = map[string]any{"nodeId": node.ID, "target": target}
+		return ValidationErrors{err}
+	}
+	outputs := child.OutputKeys()
+	if len(outputs) == 0 {
+		return nil
+	}
+	produced := make(map[string]bool)
+	for _, key := range outputs {
+		produced[key] = true
+	}
+	for _, childNode := range child.Nodes {
+		if childNode.Invokes != nil {
+			for _, m := range childNode.Invokes.ReturnMap {
+				produced[m.ParentKey] = true
+			}
+		}
+	}
+
+	var errs ValidationErrors
+	for _, m := range node.Invokes.ReturnMap {
+		if produced[m.ChildKey] {
+			continue
+		}
+		err := newValidationError(ErrUnreachableReturnKey, w.ID,
+			fmt.Sprintf("workflow '%s', node '%s': returnMap child key '%s' is never written by '%s'",
+				w.Ref(), node.ID, m.ChildKey, child.Ref()))
+		err.Details = map[string]any{"nodeId": node.ID, "target": child.Ref(), "childKey": m.ChildKey}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// invocationCycles finds recursive invocations between registered workflow
+// versions by depth-first search. Each cycle is reported once, starting from
+// its smallest ref. Caller must hold r.mu.
+func (r *Registry) invocationCycles() ValidationErrors {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make(map[*Workflow]int)
+	var stack []*Workflow
+	seen := make(map[string]bool)
+	var errs ValidationErrors
+
+	var visit func(w *Workflow)
+	visit = func(w *Workflow) {
+		state[w] = active
+		stack = append(stack, w)
+		for _, nodeID := range sortedNodeIDs(w) {
+			node := w.Nodes[nodeID]
+			if node.Invokes == nil {
+				continue
+			}
+			child, ok := r.invocationTarget(node.Invokes)
+			if !ok {
+				continue
+			}
+			switch state[child] {
+			case unvisited:
+				visit(child)
+			case active:
+				cycle := cyclePath(stack, child)
+				if key := strings.Join(cycle, " -> "); !seen[key] {
+					seen[key] = true
+					id, _, _, _ := parseWorkflowRef(cycle[0])
+					err := newValidationWarning(ErrInvocationCycle, id,
+						fmt.Sprintf("recursive invocation: %s", key))
+					err.Details = map[string]any{"cycle": cycle}
+					errs = append(errs, err)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[w] = done
+	}
+
+	for _, id := range r.sortedIDs() {
+		for _, w := range r.workflows[id] {
+			if state[w] == unvisited {
+				visit(w)
+			}
+		}
+	}
+	return errs
+}
+
+// cyclePath returns the refs of the cycle closed by an invocation of start
+// from the top of stack, rotated to begin at the smallest ref and repeating
+// it at the end.
+func cyclePath(stack []*Workflow, start *Workflow) []string {
+	var refs []string
+	for i := len(stack) - 1; i >= 0; i-- {
+		refs = append([]string{stack[i].Ref()}, refs...)
+		if stack[i] == start {
+			break
+		}
+	}
+	first := 0
+	for i, ref := range refs {
+		if ref < refs[first] {
+			first = i
+		}
+	}
+	cycle := append(append([]string(nil), refs[first:]...), refs[:first]...)
+	return append(cycle, cycle[0])
+}
+
+// ---------------------------------------------------------------------------
+// Workflow references
+// ---------------------------------------------------------------------------
+
+// Ref returns the reference that resolves to exactly this workflow version,
+// in the form "id@version".
+func (w *Workflow) Ref() string {
+	return workflowRef(w.ID, w.Version)
+}
+
+func workflowRef(id string, version int) string {
+	return id + "@" + strconv.Itoa(version)
+}
+
+// parseWorkflowRef splits "id@version" into its parts. pinned is false for a
+// bare ID.
+func parseWorkflowRef(ref string) (id string, version int, pinned bool, ok bool) {
+	id, v, found := strings.Cut(ref, "@")
+	if !found {
+		return ref, 0, false, true
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil {
+		return "", 0, false, false
+	}
+	return id, version, true, true
+}
+
+// lookup resolves ref. Caller must hold r.mu.
+func (r *Registry) lookup(ref string) (*Workflow, bool) {
+	id, version, pinned, ok := parseWorkflowRef(ref)
+	if !ok {
+		return nil, false
+	}
+	return pickVersion(r.workflows[id], version, pinned)
+}
+
+// pickVersion selects from versions (ascending): the latest unless pinned.
+func pickVersion(versions []*Workflow, version int, pinned bool) (*Workflow, bool) {
+	if len(versions) == 0 {
+		return nil, false
+	}
+	if !pinned {
+		return versions[len(versions)-1], true
+	}
+	for _, w := range versions {
+		if w.Version == version {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// resolveAll returns every workflow a removal reference names: all versions
+// for a bare ID, one for "id@version". Caller must hold r.mu.
+func (r *Registry) resolveAll(ref string) []*Workflow {
+	_, _, pinned, ok := parseWorkflowRef(ref)
+	if !ok {
+		return nil
+	}
+	if pinned {
+		if w, ok := r.lookup(ref); ok {
+			return []*Workflow{w}
+		}
+		return nil
+	}
+	return append([]*Workflow(nil), r.workflows[ref]...)
+}
+
+func containsWorkflow(ws []*Workflow, w *Workflow) bool {
+	for _, candidate := range ws {
+		if candidate == w {
+			return true
+		}
+	}
+	return false
+}
+
+// dependents returns the registered workflows, other than the targets' own
+// ID, with an invocation that resolves to one of targets now and would resolve
+// differently according to after. Caller must hold r.mu.
+func (r *Registry) dependents(targets []*Workflow, after func(ref string) (*Workflow, bool)) []*Workflow {
+	var result []*Workflow
+	for _, id := range r.sortedIDs() {
+		if id == targets[0].ID {
+			continue
+		}
+		for _, w := range r.workflows[id] {
+			for _, nodeID := range sortedNodeIDs(w) {
+				node := w.Nodes[nodeID]
+				if node.Invokes == nil {
+					continue
+				}
+				current, ok := r.lookup(node.Invokes.WorkflowID)
+				if !ok || !containsWorkflow(targets, current) {
+					continue
+				}
+				if next, ok := after(node.Invokes.WorkflowID); ok && next == current {
+					continue
+				}
+				result = append(result, w)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// checkInUse applies the removal policy when targets still have dependents or
+// tracked sessions. Caller must hold r.mu.
+func (r *Registry) checkInUse(op, ref string, targets, dependents []*Workflow) error {
+	var sessions []string
+	if r.options.Sessions != nil {
+		for _, w := range targets {
+			sessions = append(sessions, r.options.Sessions.SessionsIn(w.ID, w.Version)...)
+		}
+	}
+	if len(dependents) == 0 && len(sessions) == 0 {
+		return nil
+	}
+
+	dependentRefs := make([]string, len(dependents))
+	for i, w := range dependents {
+		dependentRefs[i] = w.Ref()
+	}
+	msg := fmt.Sprintf("cannot %s workflow '%s': invoked by %v, active sessions %v", op, ref, dependentRefs, sessions)
+	if r.options.RemovalPolicy == RemovalWarn {
+		r.logger().Warn(msg+" (proceeding)", "workflow", ref, "dependents", dependentRefs, "sessions", sessions)
+		return nil
+	}
+	err := newValidationError(ErrWorkflowInUse, targets[0].ID, msg)
+	err.Details = map[string]any{"dependents": dependentRefs, "sessions": sessions}
+	return err
+}
+
+// revalidateDependents re-checks invocation references of workflows affected
+// by a removal or replacement. Caller must hold r.mu.
+func (r *Registry) revalidateDependents(dependents []*Workflow) {
+	for _, w := range dependents {
+		r.logProblems(r.checkInvocationRefs(w))
+	}
+}
+
+func (r *Registry) sortedIDs() []string {
 	ids := make([]string, 0, len(r.workflows))
 	for id := range r.workflows {
 		ids = append(ids, id)
@@ -116,59 +769,180 @@ func (r *Registry) List() []string {
 	return ids
 }
 
+func sortedNodeIDs(w *Workflow) []string {
+	ids := make([]string, 0, len(w.Nodes))
+	for id := range w.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // ---------------------------------------------------------------------------
 // Validation — private methods
 // ---------------------------------------------------------------------------
 
-func (r *Registry) validateNoDuplicate(w *Workflow) error {
-	if _, exists := r.workflows[w.ID]; exists {
-		return newValidationError(ErrDuplicateWorkflowID, w.ID,
-			fmt.Sprintf("workflow '%s' is already registered", w.ID))
+// checkWorkflow runs every per-workflow check and collects the problems, in
+// check order. Checks that depend on an earlier one passing are skipped when
+// it fails. Caller must hold r.mu.
+func (r *Registry) checkWorkflow(w *Workflow, isNew bool) ValidationErrors {
+	var problems ValidationErrors
+	problems = append(problems, validateWorkflowID(w)...)
+	if isNew {
+		problems = append(problems, r.validateNoDuplicate(w)...)
+	}
+	problems = append(problems, r.checkStructure(w)...)
+	return problems
+}
+
+// checkStructure runs the structural checks shared by Register, Replace and
+// Check, then the custom validators if no structural error was found. Caller
+// must hold r.mu.
+func (r *Registry) checkStructure(w *Workflow) ValidationErrors {
+	problems := r.structureProblems(w)
+	if !problems.HasErrors() {
+		problems = append(problems, r.runValidators(w)...)
+	}
+	return problems
+}
+
+// structureProblems runs the built-in structural checks. Caller must hold
+// r.mu.
+func (r *Registry) structureProblems(w *Workflow) ValidationErrors {
+	if empty := validateNotEmpty(w); len(empty) > 0 {
+		return empty
+	}
+	var problems ValidationErrors
+	problems = append(problems, validateEntryNode(w)...)
+	problems = append(problems, validateNodeIDConsistency(w)...)
+	problems = append(problems, validateEdgeIDs(w)...)
+	edges := validateEdgeIntegrity(w)
+	problems = append(problems, edges...)
+	problems = append(problems, validateTerminalNodes(w)...)
+	if len(edges) == 0 {
+		problems = append(problems, validateAcyclic(w)...)
+		if _, ok := w.Nodes[w.Entry]; ok {
+			problems = append(problems, validateReachability(w)...)
+		}
+	}
+	problems = append(problems, validateFallbackEdges(w)...)
+	problems = append(problems, validateSensitiveKeys(w)...)
+	problems = append(problems, r.checkInvocationRefs(w)...)
+	return problems
+}
+
+// result turns collected problems into Register's return value according to
+// the validation mode. Warnings never fail a registration; they are logged
+// when it succeeds.
+func (r *Registry) result(problems ValidationErrors) error {
+	if !problems.HasErrors() {
+		if r.deferred != nil {
+			*r.deferred = append(*r.deferred, problems.Warnings()...)
+		} else {
+			r.logProblems(problems.Warnings())
+		}
+		return nil
+	}
+	first := problems.Errors()[0]
+	r.logger().Debug("workflow rejected", "workflow", first.WorkflowID, "code", string(first.Code),
+		"errors", len(problems.Errors()), "error", first.Message)
+	if r.options.ValidationMode == ValidateAll {
+		return problems
+	}
+	return problems.Errors()[0]
+}
+
+func validateWorkflowID(w *Workflow) ValidationErrors {
+	if w.ID == "" || strings.Contains(w.ID, "@") {
+		return ValidationErrors{newValidationError(ErrInvalidWorkflowID, w.ID,
+			fmt.Sprintf("workflow ID '%s' must be non-empty and must not contain '@'", w.ID))}
+	}
+	return nil
+}
+
+func (r *Registry) validateNoDuplicate(w *Workflow) ValidationErrors {
+	if _, exists := r.lookup(w.Ref()); exists {
+		return ValidationErrors{newValidationError(ErrDuplicateWorkflowID, w.ID,
+			fmt.Sprintf("workflow '%s' version %d is already registered", w.ID, w.Version))}
 	}
 	return nil
 }
 
-func validateNotEmpty(w *Workflow) error {
+func validateNotEmpty(w *Workflow) ValidationErrors {
 	if len(w.Nodes) == 0 {
-		return newValidationError(ErrEmptyWorkflow, w.ID,
-			fmt.Sprintf("workflow '%s' has no nodes", w.ID))
+		return ValidationErrors{newValidationError(ErrEmptyWorkflow, w.ID,
+			fmt.Sprintf("workflow '%s' has no nodes", w.ID))}
 	}
 	return nil
 }
 
-func validateEntryNode(w *Workflow) error {
+func validateEntryNode(w *Workflow) ValidationErrors {
 	if _, exists := w.Nodes[w.Entry]; !exists {
-		return newValidationError(ErrInvalidEntryNode, w.ID,
+		err := newValidationError(ErrInvalidEntryNode, w.ID,
 			fmt.Sprintf("workflow '%s' declares entry node '%s' which does not exist", w.ID, w.Entry))
+		err.Details = map[string]any{"nodeId": w.Entry}
+		return ValidationErrors{err}
 	}
 	return nil
 }
 
-func validateNodeIDConsistency(w *Workflow) error {
-	for key, node := range w.Nodes {
-		if key != node.ID {
-			return newValidationError(ErrNodeIDMismatch, w.ID,
+func validateNodeIDConsistency(w *Workflow) ValidationErrors {
+	var problems ValidationErrors
+	for _, key := range sortedNodeIDs(w) {
+		if node := w.Nodes[key]; key != node.ID {
+			err := newValidationError(ErrNodeIDMismatch, w.ID,
 				fmt.Sprintf("workflow '%s': node dict key '%s' != node.ID '%s'", w.ID, key, node.ID))
+			err.Details = map[string]any{"nodeId": key}
+			problems = append(problems, err)
 		}
 	}
-	return nil
+	return problems
 }
 
-func validateEdgeIntegrity(w *Workflow) error {
+func validateEdgeIntegrity(w *Workflow) ValidationErrors {
+	var problems ValidationErrors
 	for _, edge := range w.Edges {
 		if _, exists := w.Nodes[edge.From]; !exists {
-			return newValidationError(ErrInvalidEdge, w.ID,
+			err := newValidationError(ErrInvalidEdge, w.ID,
 				fmt.Sprintf("workflow '%s': edge '%s' references non-existent source '%s'", w.ID, edge.ID, edge.From))
+			err.Details = map[string]any{"edgeId": edge.ID, "nodeId": edge.From}
+			problems = append(problems, err)
 		}
 		if _, exists := w.Nodes[edge.To]; !exists {
-			return newValidationError(ErrInvalidEdge, w.ID,
+			err := newValidationError(ErrInvalidEdge, w.ID,
 				fmt.Sprintf("workflow '%s': edge '%s' references non-existent target '%s'", w.ID, edge.ID, edge.To))
+			err.Details = map[string]any{"edgeId": edge.ID, "nodeId": edge.To}
+			problems = append(problems, err)
 		}
 	}
-	return nil
+	return problems
+}
+
+// validateEdgeIDs rejects empty and repeated edge IDs, since Engine.Step
+// selects edges by ID.
+func validateEdgeIDs(w *Workflow) ValidationErrors {
+	var problems ValidationErrors
+	seen := make(map[string]bool)
+	for i, edge := range w.Edges {
+		if edge.ID == "" {
+			err := newValidationError(ErrEmptyEdgeID, w.ID,
+				fmt.Sprintf("workflow '%s': edge %d (%s -> %s) has an empty ID", w.ID, i, edge.From, edge.To))
+			err.Details = map[string]any{"index": i, "from": edge.From, "to": edge.To}
+			problems = append(problems, err)
+			continue
+		}
+		if seen[edge.ID] {
+			err := newValidationError(ErrDuplicateEdgeID, w.ID,
+				fmt.Sprintf("workflow '%s': edge ID '%s' is used more than once", w.ID, edge.ID))
+			err.Details = map[string]any{"edgeId": edge.ID, "index": i}
+			problems = append(problems, err)
+		}
+		seen[edge.ID] = true
+	}
+	return problems
 }
 
-func validateTerminalNodes(w *Workflow) error {
+func validateTerminalNodes(w *Workflow) ValidationErrors {
 	nodesWithOutgoing := make(map[string]bool)
 	for _, edge := range w.Edges {
 		nodesWithOutgoing[edge.From] = true
@@ -181,65 +955,107 @@ func validateTerminalNodes(w *Workflow) error {
 		}
 	}
 	if !hasTerminal {
-		return newValidationError(ErrNoTerminalNodes, w.ID,
-			fmt.Sprintf("workflow '%s' has no terminal nodes", w.ID))
+		return ValidationErrors{newValidationError(ErrNoTerminalNodes, w.ID,
+			fmt.Sprintf("workflow '%s' has no terminal nodes", w.ID))}
 	}
 	return nil
 }
 
 // validateAcyclic uses Kahn's algorithm for topological sort. O(V+E).
-func validateAcyclic(w *Workflow) error {
-	inDegree := make(map[string]int)
-	adjList := make(map[string][]string)
-	for id := range w.Nodes {
-		inDegree[id] = 0
-		adjList[id] = nil
+// Edges must reference existing nodes.
+func validateAcyclic(w *Workflow) ValidationErrors {
+	if _, err := topologicalOrder(w); err != nil {
+		return ValidationErrors{err}
 	}
-	for _, edge := range w.Edges {
-		adjList[edge.From] = append(adjList[edge.From], edge.To)
-		inDegree[edge.To]++
+	return nil
+}
+
+// validateReachability warns about nodes no path from Entry reaches. Entry and
+// edges must be valid.
+func validateReachability(w *Workflow) ValidationErrors {
+	reached := reachable(w)
+
+	var problems ValidationErrors
+	for _, id := range sortedNodeIDs(w) {
+		if !reached[id] {
+			warning := newValidationWarning(ErrUnreachableNode, w.ID,
+				fmt.Sprintf("workflow '%s': node '%s' is unreachable from entry '%s'", w.ID, id, w.Entry))
+			warning.Details = map[string]any{"nodeId": id}
+			problems = append(problems, warning)
+		}
 	}
+	return problems
+}
 
-	queue := make([]string, 0)
-	for id, deg := range inDegree {
-		if deg == 0 {
-			queue = append(queue, id)
+// validateFallbackEdges warns about fan-out nodes whose every outgoing edge is
+// guarded. If no guard passes at runtime the session has nowhere to go. A
+// single guarded edge is a deliberate gate and is not reported.
+func validateFallbackEdges(w *Workflow) ValidationErrors {
+	outgoing := make(map[string][]string)
+	unguarded := make(map[string]bool)
+	for _, edge := range w.Edges {
+		outgoing[edge.From] = append(outgoing[edge.From], edge.ID)
+		if edge.Guard == nil {
+			unguarded[edge.From] = true
 		}
 	}
 
-	sorted := 0
-	for len(queue) > 0 {
-		node := queue[0]
-		queue = queue[1:]
-		sorted++
-		for _, neighbor := range adjList[node] {
-			inDegree[neighbor]--
-			if inDegree[neighbor] == 0 {
-				queue = append(queue, neighbor)
-			}
+	var problems ValidationErrors
+	for _, id := range sortedNodeIDs(w) {
+		if len(outgoing[id]) <= 1 || unguarded[id] {
+			continue
 		}
+		warning := newValidationWarning(ErrPotentialDeadEnd, w.ID,
+			fmt.Sprintf("workflow '%s': every outgoing edge of node '%s' is guarded and none is a fallback", w.ID, id))
+		warning.Details = map[string]any{"nodeId": id, "edgeIds": outgoing[id]}
+		problems = append(problems, warning)
 	}
+	return problems
+}
 
-	if sorted != len(w.Nodes) {
-		var cycleNodes []string
-		for id, deg := range inDegree {
-			if deg > 0 {
-				cycleNodes = append(cycleNodes, id)
-			}
+func validateSensitiveKeys(w *Workflow) ValidationErrors {
+	var problems ValidationErrors
+	for _, pattern := range w.SensitiveKeys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			verr := newValidationError(ErrInvalidKeyPattern, w.ID,
+				fmt.Sprintf("workflow '%s': invalid sensitive key pattern '%s'", w.ID, pattern))
+			verr.Details = map[string]any{"pattern": pattern}
+			problems = append(problems, verr)
 		}
-		return newValidationError(ErrCycleDetected, w.ID,
-			fmt.Sprintf("workflow '%s' contains a cycle involving nodes: %v", w.ID, cycleNodes))
 	}
-	return nil
+	return problems
 }
 
-func (r *Registry) warnInvocationRefs(w *Workflow) {
-	for nodeID, node := range w.Nodes {
-		if node.Invokes != nil {
-			if _, exists := r.workflows[node.Invokes.WorkflowID]; !exists {
-				log.Printf("workflow '%s', node '%s': invokes '%s' which is not yet registered",
-					w.ID, nodeID, node.Invokes.WorkflowID)
+// checkInvocationRefs warns about invocations of workflows that are not
+// registered yet. Registry.Validate reports them as errors once registration
+// is complete.
+func (r *Registry) checkInvocationRefs(w *Workflow) ValidationErrors {
+	var problems ValidationErrors
+	for _, nodeID := range sortedNodeIDs(w) {
+		node := w.Nodes[nodeID]
+		if node.Invokes == nil {
+			continue
+		}
+		if node.Invokes.Params != nil {
+			if t, ok := r.templates[node.Invokes.WorkflowID]; ok {
+				if _, err := t.resolve(node.Invokes.Params); err != nil {
+					for _, problem := range err.(ValidationErrors) {
+						problem.WorkflowID = w.ID
+						problem.Message = fmt.Sprintf("workflow '%s', node '%s': %s", w.ID, nodeID, problem.Message)
+						problem.Details["nodeId"] = nodeID
+						problems = append(problems, problem)
+					}
+				}
+				continue
 			}
+		} else if _, exists := r.lookup(node.Invokes.WorkflowID); exists {
+			continue
 		}
+		warning := newValidationWarning(ErrInvocationNotFound, w.ID,
+			fmt.Sprintf("workflow '%s', node '%s': invokes '%s' which is not yet registered",
+				w.ID, nodeID, node.Invokes.WorkflowID))
+		warning.Details = map[string]any{"nodeId": nodeID, "target": node.Invokes.WorkflowID}
+		problems = append(problems, warning)
 	}
+	return problems
 }
@@ -1,7 +1,9 @@
 package reflex
 
 import (
+	"context"
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -181,6 +183,14 @@ func TestRegistrySelfLoop(t *testing.T) {
 	}
 }
 
+func TestRegistryInvalidSensitiveKeyPattern(t *testing.T) {
+	r := NewRegistry()
+	w := linearWorkflow("sensitive")
+	w.SensitiveKeys = []string{"api_["}
+	err := r.Register(w)
+	assertValidationError(t, err, ErrInvalidKeyPattern)
+}
+
 func TestRegistryDuplicateID(t *testing.T) {
 	r := NewRegistry()
 	_ = r.Register(linearWorkflow("dup"))
@@ -223,6 +233,491 @@ func TestRegistryGetHasList(t *testing.T) {
 	})
 }
 
+func TestRegistryVersions(t *testing.T) {
+	r := NewRegistry()
+	v1 := linearWorkflow("combat")
+	v1.Version = 1
+	v3 := linearWorkflow("combat")
+	v3.Version = 3
+	v2 := linearWorkflow("combat")
+	v2.Version = 2
+	for _, w := range []*Workflow{v1, v3, v2} {
+		if err := r.Register(w); err != nil {
+			t.Fatalf("register version %d: %v", w.Version, err)
+		}
+	}
+
+	t.Run("bare ID returns latest", func(t *testing.T) {
+		w, ok := r.Get("combat")
+		if !ok || w != v3 {
+			t.Errorf("expected version 3, got %v", w)
+		}
+	})
+	t.Run("pinned ref returns exact version", func(t *testing.T) {
+		w, ok := r.Get("combat@2")
+		if !ok || w != v2 {
+			t.Errorf("expected version 2, got %v", w)
+		}
+		if w.Ref() != "combat@2" {
+			t.Errorf("expected ref combat@2, got %s", w.Ref())
+		}
+	})
+	t.Run("unknown version is not found", func(t *testing.T) {
+		if r.Has("combat@4") || r.Has("combat@x") {
+			t.Error("expected unknown versions to be missing")
+		}
+	})
+	t.Run("Versions lists ascending", func(t *testing.T) {
+		versions := r.Versions("combat")
+		if len(versions) != 3 || versions[0] != 1 || versions[1] != 2 || versions[2] != 3 {
+			t.Errorf("expected [1 2 3], got %v", versions)
+		}
+		if r.Versions("nope") != nil {
+			t.Error("expected nil for unknown ID")
+		}
+	})
+	t.Run("List reports each ID once", func(t *testing.T) {
+		if list := r.List(); len(list) != 1 || list[0] != "combat" {
+			t.Errorf("expected [combat], got %v", list)
+		}
+	})
+	t.Run("same version is a duplicate", func(t *testing.T) {
+		dup := linearWorkflow("combat")
+		dup.Version = 2
+		assertValidationError(t, r.Register(dup), ErrDuplicateWorkflowID)
+	})
+}
+
+func TestRegistryInvalidWorkflowID(t *testing.T) {
+	r := NewRegistry()
+	assertValidationError(t, r.Register(linearWorkflow("combat@2")), ErrInvalidWorkflowID)
+	assertValidationError(t, r.Register(linearWorkflow("")), ErrInvalidWorkflowID)
+}
+
+func invokerWorkflow(id, target string) *Workflow {
+	return &Workflow{
+		ID:    id,
+		Entry: "A",
+		Nodes: map[string]*Node{
+			"A": {ID: "A", Spec: NodeSpec{}, Invokes: &InvocationSpec{WorkflowID: target}},
+			"B": {ID: "B", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "e1", From: "A", To: "B", Event: "NEXT"}},
+	}
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	t.Run("removes an unused workflow", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		if err := r.Unregister("wf"); err != nil {
+			t.Fatal(err)
+		}
+		if r.Has("wf") || len(r.List()) != 0 {
+			t.Error("expected wf removed")
+		}
+	})
+	t.Run("unknown workflow", func(t *testing.T) {
+		r := NewRegistry()
+		assertValidationError(t, r.Unregister("nope"), ErrWorkflowNotFound)
+	})
+	t.Run("refuses while invoked by another workflow", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("child"))
+		_ = r.Register(invokerWorkflow("parent", "child"))
+		err := r.Unregister("child")
+		assertValidationError(t, err, ErrWorkflowInUse)
+		var ve *ValidationError
+		_ = errors.As(err, &ve)
+		if deps, _ := ve.Details["dependents"].([]string); len(deps) != 1 || deps[0] != "parent@0" {
+			t.Errorf("expected dependents [parent@0], got %v", ve.Details["dependents"])
+		}
+		if !r.Has("child") {
+			t.Error("refused unregister must not remove the workflow")
+		}
+	})
+	t.Run("warn policy proceeds", func(t *testing.T) {
+		r := NewRegistry(RegistryOptions{RemovalPolicy: RemovalWarn})
+		_ = r.Register(linearWorkflow("child"))
+		_ = r.Register(invokerWorkflow("parent", "child"))
+		if err := r.Unregister("child"); err != nil {
+			t.Fatal(err)
+		}
+		if r.Has("child") {
+			t.Error("expected child removed")
+		}
+	})
+	t.Run("removing an older version keeps bare references valid", func(t *testing.T) {
+		r := NewRegistry()
+		v1 := linearWorkflow("child")
+		v1.Version = 1
+		v2 := linearWorkflow("child")
+		v2.Version = 2
+		_ = r.Register(v1)
+		_ = r.Register(v2)
+		_ = r.Register(invokerWorkflow("parent", "child"))
+		if err := r.Unregister("child@1"); err != nil {
+			t.Fatal(err)
+		}
+		if versions := r.Versions("child"); len(versions) != 1 || versions[0] != 2 {
+			t.Errorf("expected [2], got %v", versions)
+		}
+		assertValidationError(t, r.Unregister("child@2"), ErrWorkflowInUse)
+	})
+	t.Run("refuses while a tracked session is inside", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		e := NewEngine(r, autoAdvanceAgent())
+		r.TrackSessions(e)
+		sid, _ := e.Init("wf")
+
+		err := r.Unregister("wf")
+		assertValidationError(t, err, ErrWorkflowInUse)
+		var ve *ValidationError
+		_ = errors.As(err, &ve)
+		if s, _ := ve.Details["sessions"].([]string); len(s) != 1 || s[0] != sid {
+			t.Errorf("expected session %s, got %v", sid, ve.Details["sessions"])
+		}
+
+		_, _ = e.Run(context.Background())
+		if err := r.Unregister("wf"); err != nil {
+			t.Errorf("expected unregister after completion, got %v", err)
+		}
+	})
+	t.Run("trackers are combined", func(t *testing.T) {
+		r := NewRegistry(RegistryOptions{Sessions: fixedSessions{"s1"}})
+		_ = r.Register(linearWorkflow("wf"))
+		e := NewEngine(r, autoAdvanceAgent())
+		r.TrackSessions(e)
+		sid, _ := e.Init("wf")
+
+		var ve *ValidationError
+		if !errors.As(r.Unregister("wf"), &ve) || !reflect.DeepEqual(ve.Details["sessions"], []string{"s1", sid}) {
+			t.Errorf("expected both trackers consulted, got %v", ve)
+		}
+	})
+}
+
+func TestEngineWorkflowUnregistered(t *testing.T) {
+	t.Run("current workflow", func(t *testing.T) {
+		r := NewRegistry(RegistryOptions{RemovalPolicy: RemovalWarn})
+		_ = r.Register(linearWorkflow("wf"))
+		e := NewEngine(r, autoAdvanceAgent())
+		var errEvents []Event
+		e.On(EventEngineError, func(ev Event) { errEvents = append(errEvents, ev) })
+		_, _ = e.Init("wf")
+		_ = r.Unregister("wf")
+
+		res, err := e.Step(context.Background())
+		if err != nil || res.Status != StepSuspended || len(errEvents) != 1 {
+			t.Errorf("expected an engine:error suspension, got %+v, %v, %v", res, err, errEvents)
+		}
+	})
+	t.Run("parent on return", func(t *testing.T) {
+		r := NewRegistry(RegistryOptions{RemovalPolicy: RemovalWarn})
+		_ = r.Register(linearWorkflow("child"))
+		_ = r.Register(invokerWorkflow("parent", "child"))
+		e := NewEngine(r, autoAdvanceAgent())
+		var errEvents []Event
+		e.On(EventEngineError, func(ev Event) { errEvents = append(errEvents, ev) })
+		_, _ = e.Init("parent")
+		_, _ = e.Step(context.Background()) // invoke child
+		_ = r.Unregister("parent")
+
+		res, _ := e.Run(context.Background())
+		if res.Status != StepSuspended || len(errEvents) != 1 || e.CurrentWorkflow().ID != "child" {
+			t.Errorf("expected suspension inside the child, got %+v, %v", res, errEvents)
+		}
+		if len(e.Stack()) != 1 {
+			t.Error("expected the stack to be kept")
+		}
+	})
+}
+
+func TestRegistryReplace(t *testing.T) {
+	t.Run("swaps the registered definition", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		next := linearWorkflow("wf")
+		next.Nodes["A"].Description = "replaced"
+		if err := r.Replace(next); err != nil {
+			t.Fatal(err)
+		}
+		if w, _ := r.Get("wf"); w != next {
+			t.Error("expected replacement to be registered")
+		}
+	})
+	t.Run("unknown workflow", func(t *testing.T) {
+		r := NewRegistry()
+		assertValidationError(t, r.Replace(linearWorkflow("wf")), ErrWorkflowNotFound)
+	})
+	t.Run("replacement is validated", func(t *testing.T) {
+		r := NewRegistry()
+		original := linearWorkflow("wf")
+		_ = r.Register(original)
+		broken := linearWorkflow("wf")
+		broken.Entry = "MISSING"
+		assertValidationError(t, r.Replace(broken), ErrInvalidEntryNode)
+		if w, _ := r.Get("wf"); w != original {
+			t.Error("failed replace must keep the original")
+		}
+	})
+	t.Run("refuses when an invoker breaks", func(t *testing.T) {
+		child := func(output string) *Workflow {
+			w := linearWorkflow("child")
+			w.Nodes["C"].Outputs = []NodeOutput{{Key: output, Guaranteed: true}}
+			return w
+		}
+		r := NewRegistry()
+		_ = r.Register(child("result"))
+		parent := invokerWorkflow("parent", "child")
+		parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "r", ChildKey: "result"}}
+		_ = r.Register(parent)
+
+		if err := r.Replace(child("result")); err != nil {
+			t.Errorf("expected a compatible replacement to pass, got %v", err)
+		}
+		incompatible := child("other")
+		assertValidationError(t, r.Replace(incompatible), ErrWorkflowInUse)
+		if w, _ := r.Get("child"); w == incompatible {
+			t.Error("refused replace must keep the registered definition")
+		}
+	})
+	t.Run("keeps version slices intact", func(t *testing.T) {
+		r := NewRegistry()
+		original := linearWorkflow("wf")
+		_ = r.Register(original)
+		before := r.workflows["wf"]
+		if err := r.Replace(linearWorkflow("wf")); err != nil {
+			t.Fatal(err)
+		}
+		if before[0] != original {
+			t.Error("expected Replace to install a new version slice")
+		}
+	})
+	t.Run("instantiates template invocations", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.RegisterTemplate(enemyTemplate())
+		_ = r.Register(linearWorkflow("parent"))
+		if err := r.Replace(templateInvoker(map[string]any{"name": "Orc"})); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.List()) != 2 {
+			t.Errorf("expected parent and one instance, got %v", r.List())
+		}
+		assertValidationError(t, r.Replace(templateInvoker(map[string]any{"hp": 1})), ErrTemplateParams)
+	})
+}
+
+func brokenWorkflow() *Workflow {
+	return &Workflow{
+		ID:    "broken",
+		Entry: "MISSING",
+		Nodes: map[string]*Node{
+			"A": {ID: "A", Spec: NodeSpec{}, Invokes: &InvocationSpec{WorkflowID: "ghost"}},
+			"B": {ID: "WRONG", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{
+			{ID: "e1", From: "A", To: "B", Event: "NEXT"},
+			{ID: "e2", From: "A", To: "NOWHERE", Event: "NEXT"},
+		},
+	}
+}
+
+func TestRegistryValidateAll(t *testing.T) {
+	r := NewRegistry(RegistryOptions{ValidationMode: ValidateAll})
+	err := r.Register(brokenWorkflow())
+
+	var problems ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+	want := []struct {
+		code     ValidationErrorCode
+		severity Severity
+		details  map[string]any
+	}{
+		{ErrInvalidEntryNode, SeverityError, map[string]any{"nodeId": "MISSING"}},
+		{ErrNodeIDMismatch, SeverityError, map[string]any{"nodeId": "B"}},
+		{ErrInvalidEdge, SeverityError, map[string]any{"edgeId": "e2", "nodeId": "NOWHERE"}},
+		{ErrInvocationNotFound, SeverityWarning, map[string]any{"nodeId": "A", "target": "ghost"}},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d: %v", len(want), len(problems), problems)
+	}
+	for i, w := range want {
+		p := problems[i]
+		if p.Code != w.code || p.Severity != w.severity {
+			t.Errorf("problem %d: expected %s %s, got %s %s", i, w.severity, w.code, p.Severity, p.Code)
+		}
+		for k, v := range w.details {
+			if p.Details[k] != v {
+				t.Errorf("problem %d: expected Details[%s]=%v, got %v", i, k, v, p.Details[k])
+			}
+		}
+	}
+	if len(problems.Errors()) != 3 || len(problems.Warnings()) != 1 {
+		t.Errorf("expected 3 errors and 1 warning, got %d and %d", len(problems.Errors()), len(problems.Warnings()))
+	}
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code != ErrInvalidEntryNode {
+		t.Errorf("errors.As should reach the first *ValidationError, got %v", ve)
+	}
+	if r.Has("broken") {
+		t.Error("workflow with errors must not be registered")
+	}
+}
+
+func TestRegistryFailFastReturnsFirstError(t *testing.T) {
+	r := NewRegistry()
+	err := r.Register(brokenWorkflow())
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected a single *ValidationError, got %T", err)
+	}
+	assertValidationError(t, err, ErrInvalidEntryNode)
+}
+
+func TestRegistryCheck(t *testing.T) {
+	r := NewRegistry()
+	w := invokerWorkflow("parent", "ghost")
+	problems := r.Check(w)
+	if problems.HasErrors() {
+		t.Fatalf("expected no errors, got %v", problems.Errors())
+	}
+	if len(problems.Warnings()) != 1 || problems.Warnings()[0].Code != ErrInvocationNotFound {
+		t.Errorf("expected invocation warning, got %v", problems)
+	}
+	if r.Has("parent") {
+		t.Error("Check must not register")
+	}
+	if err := r.Register(w); err != nil {
+		t.Errorf("warnings must not fail registration, got %v", err)
+	}
+	if problems := r.Check(w); len(problems.Errors()) != 1 || problems.Errors()[0].Code != ErrDuplicateWorkflowID {
+		t.Errorf("expected duplicate error, got %v", problems)
+	}
+}
+
+func validationErrors(t *testing.T, err error) []*ValidationError {
+	t.Helper()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %v", err)
+	}
+	var result []*ValidationError
+	for _, e := range joined.Unwrap() {
+		var ve *ValidationError
+		if !errors.As(e, &ve) {
+			t.Fatalf("expected *ValidationError, got %T", e)
+		}
+		result = append(result, ve)
+	}
+	return result
+}
+
+func TestRegistryValidate(t *testing.T) {
+	t.Run("valid invocation graph", func(t *testing.T) {
+		r := NewRegistry()
+		child := linearWorkflow("child")
+		child.Outputs = []string{"out"}
+		parent := invokerWorkflow("parent", "child")
+		parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "result", ChildKey: "out"}}
+		_ = r.Register(child)
+		_ = r.Register(parent)
+		if err := r.Validate(); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+	t.Run("missing target", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(invokerWorkflow("parent", "ghost"))
+		errs := validationErrors(t, r.Validate())
+		if len(errs) != 1 || errs[0].Code != ErrInvocationNotFound {
+			t.Fatalf("expected one %s, got %v", ErrInvocationNotFound, errs)
+		}
+		if errs[0].Details["nodeId"] != "A" || errs[0].Details["target"] != "ghost" {
+			t.Errorf("unexpected details %v", errs[0].Details)
+		}
+	})
+	t.Run("unreachable return key", func(t *testing.T) {
+		r := NewRegistry()
+		child := invokerWorkflow("child", "grandchild")
+		child.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "promoted", ChildKey: "x"}}
+		child.Outputs = []string{"out"}
+		grandchild := linearWorkflow("grandchild")
+		parent := invokerWorkflow("parent", "child")
+		parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{
+			{ParentKey: "a", ChildKey: "out"},
+			{ParentKey: "b", ChildKey: "promoted"},
+			{ParentKey: "c", ChildKey: "missing"},
+		}
+		_ = r.Register(grandchild)
+		_ = r.Register(child)
+		_ = r.Register(parent)
+		errs := validationErrors(t, r.Validate())
+		if len(errs) != 1 || errs[0].Code != ErrUnreachableReturnKey || errs[0].Details["childKey"] != "missing" {
+			t.Fatalf("expected unreachable 'missing', got %v", errs)
+		}
+	})
+	t.Run("node contracts declare child outputs", func(t *testing.T) {
+		r := NewRegistry()
+		child := linearWorkflow("child")
+		child.Nodes["B"].Outputs = []NodeOutput{{Key: "out"}}
+		parent := invokerWorkflow("parent", "child")
+		parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{
+			{ParentKey: "a", ChildKey: "out"},
+			{ParentKey: "b", ChildKey: "missing"},
+		}
+		_ = r.Register(child)
+		_ = r.Register(parent)
+		errs := validationErrors(t, r.Validate())
+		if len(errs) != 1 || errs[0].Details["childKey"] != "missing" {
+			t.Fatalf("expected unreachable 'missing', got %v", errs)
+		}
+	})
+	t.Run("undeclared child outputs are not checked", func(t *testing.T) {
+		r := NewRegistry()
+		parent := invokerWorkflow("parent", "child")
+		parent.Nodes["A"].Invokes.ReturnMap = []ReturnMapping{{ParentKey: "a", ChildKey: "anything"}}
+		_ = r.Register(linearWorkflow("child"))
+		_ = r.Register(parent)
+		if err := r.Validate(); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+	t.Run("recursive cycle reported with path", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(invokerWorkflow("b", "c"))
+		_ = r.Register(invokerWorkflow("c", "a"))
+		_ = r.Register(invokerWorkflow("a", "b"))
+		_ = r.Register(invokerWorkflow("self", "self"))
+		if err := r.Validate(); err != nil {
+			t.Errorf("expected warnings only to pass, got %v", err)
+		}
+		errs := r.CheckInvocations()
+		if len(errs) != 2 {
+			t.Fatalf("expected 2 cycles, got %v", errs)
+		}
+		want := [][]string{{"a@0", "b@0", "c@0", "a@0"}, {"self@0", "self@0"}}
+		for i, e := range errs {
+			if e.Code != ErrInvocationCycle || e.Severity != SeverityWarning {
+				t.Fatalf("expected %s warning, got %s %s", ErrInvocationCycle, e.Severity, e.Code)
+			}
+			cycle, _ := e.Details["cycle"].([]string)
+			if len(cycle) != len(want[i]) {
+				t.Fatalf("cycle %d: expected %v, got %v", i, want[i], cycle)
+			}
+			for j := range cycle {
+				if cycle[j] != want[i][j] {
+					t.Errorf("cycle %d: expected %v, got %v", i, want[i], cycle)
+					break
+				}
+			}
+		}
+	})
+}
+
 func TestRegistryInvocationRefWarning(t *testing.T) {
 	// Should register without error even if invocation target doesn't exist yet
 	r := NewRegistry()
@@ -0,0 +1,418 @@
+package reflex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// ---------------------------------------------------------------------------
+// Workflow templates
+// ---------------------------------------------------------------------------
+
+// ParamRef is a placeholder for a template parameter. Use it as a NodeSpec
+// value, a built-in guard's Value or an InvocationSpec.Params value, at any
+// depth of nested maps, slices and arrays of any type, as long as it is held
+// in an interface slot (an any value, say); instantiation replaces it with
+// the parameter's value.
+type ParamRef struct {
+	Name string
+}
+
+// Param returns a placeholder for the named template parameter.
+func Param(name string) ParamRef {
+	return ParamRef{Name: name}
+}
+
+// TemplateParam declares a template parameter. A parameter that is not
+// Required takes Default when omitted.
+type TemplateParam struct {
+	Name        string
+	Required    bool
+	Default     any
+	Description string
+}
+
+// Template is a workflow definition with parameters, instantiated into
+// ordinary workflows by Registry.Instantiate or by an InvocationSpec with
+// Params. The body's own ID is ignored.
+type Template struct {
+	ID     string
+	Params []TemplateParam
+	Body   *Workflow
+}
+
+// RegisterTemplate validates and stores a template. Its parameters must have
+// unique, non-empty names, every ParamRef in the body must name one of them,
+// and the body must pass the structural checks. Custom validators run on each
+// instance rather than on the template, since placeholders stand in for the
+// real values.
+func (r *Registry) RegisterTemplate(t *Template) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t.Body == nil {
+		return newValidationError(ErrEmptyWorkflow, t.ID,
+			fmt.Sprintf("template '%s' has no body", t.ID))
+	}
+	body := *t.Body
+	body.ID = t.ID
+	problems := validateWorkflowID(&body)
+	if _, exists := r.templates[t.ID]; exists {
+		problems = append(problems, newValidationError(ErrDuplicateWorkflowID, t.ID,
+			fmt.Sprintf("template '%s' is already registered", t.ID)))
+	}
+	problems = append(problems, t.checkParams()...)
+	problems = append(problems, r.structureProblems(&body)...)
+	if err := r.result(problems); err != nil {
+		return err
+	}
+
+	if r.templates == nil {
+		r.templates = make(map[string]*Template)
+	}
+	r.templates[t.ID] = t
+	r.logger().Debug("template registered", "template", t.ID, "params", len(t.Params))
+	return nil
+}
+
+// Template returns a registered template.
+func (r *Registry) Template(id string) (*Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.templates[id]
+	return t, ok
+}
+
+// Instantiate registers the instance of a template for params and returns
+// it. The instance ID is the template ID followed by "#" and a hash of the
+// resolved parameters, so instantiating the same parameters again returns the
+// registered instance. Parameter problems are reported as
+// ErrTemplateParams; the instance itself is validated like any other
+// workflow.
+func (r *Registry) Instantiate(templateID string, params map[string]any) (*Workflow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.instantiate(templateID, params)
+}
+
+// instantiate is Instantiate for callers holding r.mu.
+func (r *Registry) instantiate(templateID string, params map[string]any) (*Workflow, error) {
+	t, ok := r.templates[templateID]
+	if !ok {
+		return nil, newValidationError(ErrWorkflowNotFound, templateID,
+			fmt.Sprintf("template '%s' is not registered", templateID))
+	}
+	resolved, err := t.resolve(params)
+	if err != nil {
+		return nil, err
+	}
+	id := instanceID(templateID, resolved)
+	if w, ok := r.lookup(workflowRef(id, t.Body.Version)); ok {
+		if !sameInstanceParams(w, resolved) {
+			return nil, newValidationError(ErrDuplicateWorkflowID, id,
+				fmt.Sprintf("instance '%s' of template '%s' is already registered with different parameters", id, templateID))
+		}
+		return w, nil
+	}
+	w := t.apply(id, resolved)
+	if err := r.register(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Apply substitutes params into a copy of the body and returns it under id,
+// without registering it. Registry.Instantiate is the usual entry point.
+func (t *Template) Apply(id string, params map[string]any) (*Workflow, error) {
+	resolved, err := t.resolve(params)
+	if err != nil {
+		return nil, err
+	}
+	return t.apply(id, resolved), nil
+}
+
+// resolve checks params against the declarations and fills in defaults.
+func (t *Template) resolve(params map[string]any) (map[string]any, error) {
+	declared := make(map[string]bool, len(t.Params))
+	resolved := make(map[string]any, len(t.Params))
+	var problems ValidationErrors
+	for _, p := range t.Params {
+		declared[p.Name] = true
+		if v, ok := params[p.Name]; ok {
+			resolved[p.Name] = v
+		} else if p.Required {
+			problems = append(problems, t.paramProblem(p.Name,
+				fmt.Sprintf("template '%s': missing required parameter '%s'", t.ID, p.Name)))
+		} else {
+			resolved[p.Name] = p.Default
+		}
+	}
+	for _, name := range sortedKeys(params) {
+		if !declared[name] {
+			problems = append(problems, t.paramProblem(name,
+				fmt.Sprintf("template '%s': unknown parameter '%s'", t.ID, name)))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return resolved, nil
+}
+
+func (t *Template) checkParams() ValidationErrors {
+	var problems ValidationErrors
+	declared := make(map[string]bool, len(t.Params))
+	for _, p := range t.Params {
+		if p.Name == "" || declared[p.Name] {
+			problems = append(problems, t.paramProblem(p.Name,
+				fmt.Sprintf("template '%s': parameter names must be unique and non-empty, got '%s'", t.ID, p.Name)))
+		}
+		declared[p.Name] = true
+	}
+
+	used := make(map[string]bool)
+	for _, node := range t.Body.Nodes {
+		collectParamRefs(node.Spec, used)
+		if node.Invokes != nil {
+			collectParamRefs(node.Invokes.Params, used)
+		}
+	}
+	for _, edge := range t.Body.Edges {
+		if g, ok := edge.Guard.(*BuiltinGuard); ok {
+			collectParamRefs(g.Value, used)
+		}
+	}
+	for _, name := range sortedKeys(used) {
+		if !declared[name] {
+			problems = append(problems, t.paramProblem(name,
+				fmt.Sprintf("template '%s': placeholder for undeclared parameter '%s'", t.ID, name)))
+		}
+	}
+	return problems
+}
+
+func (t *Template) paramProblem(name, msg string) *ValidationError {
+	err := newValidationError(ErrTemplateParams, t.ID, msg)
+	err.Details = map[string]any{"param": name}
+	return err
+}
+
+// apply copies the body with resolved parameters substituted. Metadata
+// records the template and parameters the instance came from.
+func (t *Template) apply(id string, params map[string]any) *Workflow {
+	body := t.Body
+	w := &Workflow{
+		ID:            id,
+		Entry:         body.Entry,
+		Nodes:         make(map[string]*Node, len(body.Nodes)),
+		Edges:         make([]Edge, len(body.Edges)),
+		Metadata:      map[string]any{"template": t.ID, "params": params},
+		Version:       body.Version,
+		SensitiveKeys: append([]string(nil), body.SensitiveKeys...),
+		Outputs:       append([]string(nil), body.Outputs...),
+	}
+	for k, v := range body.Metadata {
+		if _, reserved := w.Metadata[k]; !reserved {
+			w.Metadata[k] = v
+		}
+	}
+	for nodeID, node := range body.Nodes {
+		cp := cloneNode(node)
+		cp.Spec, _ = substituteParams(node.Spec, params).(NodeSpec)
+		if cp.Invokes != nil && cp.Invokes.Params != nil {
+			cp.Invokes.Params, _ = substituteParams(node.Invokes.Params, params).(map[string]any)
+		}
+		w.Nodes[nodeID] = cp
+	}
+	for i, edge := range body.Edges {
+		if g, ok := edge.Guard.(*BuiltinGuard); ok {
+			edge.Guard = &BuiltinGuard{Type: g.Type, Key: g.Key, Value: substituteParams(g.Value, params)}
+		}
+		w.Edges[i] = edge
+	}
+	return w
+}
+
+var paramRefType = reflect.TypeOf(ParamRef{})
+
+// substituteParams returns v with every ParamRef replaced, copying the maps,
+// slices and arrays it descends into.
+func substituteParams(v any, params map[string]any) any {
+	if ref, ok := v.(ParamRef); ok {
+		return params[ref.Name]
+	}
+	if v == nil {
+		return nil
+	}
+	return substituteValue(reflect.ValueOf(v), params).Interface()
+}
+
+func substituteValue(v reflect.Value, params map[string]any) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		if v.Elem().Type() != paramRefType {
+			cp.Set(substituteValue(v.Elem(), params))
+			return cp
+		}
+		value := params[v.Elem().Interface().(ParamRef).Name]
+		if value == nil || !reflect.TypeOf(value).AssignableTo(v.Type()) {
+			return cp
+		}
+		cp.Set(reflect.ValueOf(value))
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), substituteValue(iter.Value(), params))
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(substituteValue(v.Index(i), params))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(substituteValue(v.Index(i), params))
+		}
+		return cp
+	default:
+		return v
+	}
+}
+
+// collectParamRefs records the names of the ParamRefs substituteParams would
+// replace in v.
+func collectParamRefs(v any, used map[string]bool) {
+	if ref, ok := v.(ParamRef); ok {
+		used[ref.Name] = true
+		return
+	}
+	if v != nil {
+		collectParamValues(reflect.ValueOf(v), used)
+	}
+}
+
+func collectParamValues(v reflect.Value, used map[string]bool) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if ref, ok := v.Interface().(ParamRef); ok {
+			used[ref.Name] = true
+			return
+		}
+		collectParamValues(v.Elem(), used)
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectParamValues(iter.Value(), used)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectParamValues(v.Index(i), used)
+		}
+	}
+}
+
+// instanceID derives a stable instance ID from the resolved parameters. The
+// hash is truncated to keep IDs readable, so callers reusing an instance
+// check its parameters with sameInstanceParams.
+func instanceID(templateID string, params map[string]any) string {
+	sum := sha256.Sum256(instanceKey(params))
+	return templateID + "#" + hex.EncodeToString(sum[:4])
+}
+
+// instanceKey encodes resolved parameters for instanceID in the typed form
+// fingerprints use, so that 3 and 3.0 make different instances. Parameters
+// that cannot be encoded fall back to fmt formatting.
+func instanceKey(params map[string]any) []byte {
+	typed, err := typedValue(reflect.ValueOf(params), make(visiting))
+	if err != nil {
+		return fmt.Appendf(nil, "%#v", params)
+	}
+	data, err := json.Marshal(typed)
+	if err != nil {
+		return fmt.Appendf(nil, "%#v", params)
+	}
+	return data
+}
+
+// sameInstanceParams reports whether the registered instance w was created
+// from params, rather than from parameters whose hash merely collides.
+func sameInstanceParams(w *Workflow, params map[string]any) bool {
+	stored, ok := w.Metadata["params"].(map[string]any)
+	return ok && bytes.Equal(instanceKey(stored), instanceKey(params))
+}
+
+// invocationTarget resolves an InvocationSpec to a registered workflow: the
+// instance for template invocations, the referenced workflow otherwise.
+// Template instances that do not exist yet are not found. Caller must hold
+// r.mu.
+func (r *Registry) invocationTarget(inv *InvocationSpec) (*Workflow, bool) {
+	if inv.Params == nil {
+		return r.lookup(inv.WorkflowID)
+	}
+	t, ok := r.templates[inv.WorkflowID]
+	if !ok {
+		return nil, false
+	}
+	resolved, err := t.resolve(inv.Params)
+	if err != nil {
+		return nil, false
+	}
+	w, ok := r.lookup(workflowRef(instanceID(t.ID, resolved), t.Body.Version))
+	if !ok || !sameInstanceParams(w, resolved) {
+		return nil, false
+	}
+	return w, true
+}
+
+// resolveInvocation returns the workflow an invocation starts, instantiating
+// a template if needed.
+func (r *Registry) resolveInvocation(inv *InvocationSpec) (*Workflow, error) {
+	if inv.Params != nil {
+		return r.Instantiate(inv.WorkflowID, inv.Params)
+	}
+	w, ok := r.Get(inv.WorkflowID)
+	if !ok {
+		return nil, fmt.Errorf("sub-workflow '%s' not found", inv.WorkflowID)
+	}
+	return w, nil
+}
+
+// instantiateInvocations creates the instances w's template invocations need,
+// for templates already registered. Caller must hold r.mu.
+func (r *Registry) instantiateInvocations(w *Workflow) error {
+	for _, nodeID := range sortedNodeIDs(w) {
+		inv := w.Nodes[nodeID].Invokes
+		if inv == nil || inv.Params == nil {
+			continue
+		}
+		if _, ok := r.templates[inv.WorkflowID]; !ok {
+			continue
+		}
+		if _, err := r.instantiate(inv.WorkflowID, inv.Params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package reflex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// enemyTemplate has a spec placeholder at the top level and nested in a map,
+// and a guard comparing against a parameter.
+func enemyTemplate() *Template {
+	return &Template{
+		ID: "enemy",
+		Params: []TemplateParam{
+			{Name: "name", Required: true},
+			{Name: "hp", Default: 3},
+		},
+		Body: &Workflow{
+			Entry: "A",
+			Nodes: map[string]*Node{
+				"A":    {ID: "A", Spec: NodeSpec{"name": Param("name"), "stats": map[string]any{"hp": Param("hp")}}},
+				"WIN":  {ID: "WIN", Spec: NodeSpec{}},
+				"LOSE": {ID: "LOSE", Spec: NodeSpec{}},
+			},
+			Edges: []Edge{
+				{ID: "e1", From: "A", To: "WIN", Event: "NEXT", Guard: Equals("hp", Param("hp"))},
+				{ID: "e2", From: "A", To: "LOSE", Event: "NEXT"},
+			},
+		},
+	}
+}
+
+func templateParamCode(t *testing.T, err error) {
+	t.Helper()
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code != ErrTemplateParams {
+		t.Errorf("expected %s, got %v", ErrTemplateParams, err)
+	}
+}
+
+func TestRegisterTemplate(t *testing.T) {
+	t.Run("undeclared placeholder", func(t *testing.T) {
+		tmpl := enemyTemplate()
+		tmpl.Body.Nodes["WIN"].Spec["loot"] = Param("loot")
+		err := NewRegistry().RegisterTemplate(tmpl)
+		templateParamCode(t, err)
+		if err == nil || !strings.Contains(err.Error(), "'loot'") {
+			t.Errorf("expected the undeclared parameter named, got %v", err)
+		}
+	})
+
+	t.Run("undeclared placeholder in a typed container", func(t *testing.T) {
+		tmpl := enemyTemplate()
+		tmpl.Body.Nodes["WIN"].Spec["drops"] = []map[string]any{{"item": Param("loot")}}
+		templateParamCode(t, NewRegistry().RegisterTemplate(tmpl))
+	})
+
+	t.Run("missing body", func(t *testing.T) {
+		var ve *ValidationError
+		if err := NewRegistry().RegisterTemplate(&Template{ID: "hollow"}); !errors.As(err, &ve) || ve.Code != ErrEmptyWorkflow {
+			t.Errorf("expected %s, got %v", ErrEmptyWorkflow, err)
+		}
+	})
+
+	t.Run("duplicate parameter", func(t *testing.T) {
+		tmpl := enemyTemplate()
+		tmpl.Params = append(tmpl.Params, TemplateParam{Name: "hp"})
+		templateParamCode(t, NewRegistry().RegisterTemplate(tmpl))
+	})
+
+	t.Run("structural problems in the body", func(t *testing.T) {
+		tmpl := enemyTemplate()
+		tmpl.Body.Entry = "MISSING"
+		var ve *ValidationError
+		if err := NewRegistry().RegisterTemplate(tmpl); !errors.As(err, &ve) || ve.Code != ErrInvalidEntryNode {
+			t.Errorf("expected %s, got %v", ErrInvalidEntryNode, err)
+		}
+	})
+
+	t.Run("duplicate template", func(t *testing.T) {
+		r := NewRegistry()
+		if err := r.RegisterTemplate(enemyTemplate()); err != nil {
+			t.Fatal(err)
+		}
+		var ve *ValidationError
+		if err := r.RegisterTemplate(enemyTemplate()); !errors.As(err, &ve) || ve.Code != ErrDuplicateWorkflowID {
+			t.Errorf("expected %s, got %v", ErrDuplicateWorkflowID, err)
+		}
+		if _, ok := r.Template("enemy"); !ok {
+			t.Error("expected the template to be registered")
+		}
+	})
+}
+
+func TestInstantiate(t *testing.T) {
+	r := NewRegistry()
+	if err := r.RegisterTemplate(enemyTemplate()); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := r.Instantiate("enemy", map[string]any{"name": "Goblin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(w.ID, "enemy#") || !r.Has(w.Ref()) {
+		t.Errorf("expected a registered enemy# instance, got %s", w.ID)
+	}
+	spec := w.Nodes["A"].Spec
+	if spec["name"] != "Goblin" || spec["stats"].(map[string]any)["hp"] != 3 {
+		t.Errorf("expected substituted spec with default hp, got %v", spec)
+	}
+	if g := w.Edges[0].Guard.(*BuiltinGuard); g.Value != 3 {
+		t.Errorf("expected guard value 3, got %v", g.Value)
+	}
+	if w.Metadata["template"] != "enemy" {
+		t.Errorf("expected template metadata, got %v", w.Metadata)
+	}
+	tmpl, _ := r.Template("enemy")
+	if _, ok := tmpl.Body.Nodes["A"].Spec["name"].(ParamRef); !ok {
+		t.Error("instantiation must not modify the template body")
+	}
+
+	again, err := r.Instantiate("enemy", map[string]any{"name": "Goblin", "hp": 3})
+	if err != nil || again != w {
+		t.Errorf("expected the same instance for the same resolved params, got %v, %v", again, err)
+	}
+	other, err := r.Instantiate("enemy", map[string]any{"name": "Goblin", "hp": 7})
+	if err != nil || other.ID == w.ID {
+		t.Errorf("expected a distinct instance for different params, got %v, %v", other, err)
+	}
+
+	// A workflow already holding the instance ID, as a truncated hash
+	// collision would, is not mistaken for the instance.
+	squatter := linearWorkflow(instanceID("enemy", map[string]any{"name": "Imp", "hp": 3}))
+	squatter.Metadata = map[string]any{"template": "enemy", "params": map[string]any{"name": "Bat", "hp": 1}}
+	if err := r.Register(squatter); err != nil {
+		t.Fatal(err)
+	}
+	var dup *ValidationError
+	if _, err := r.Instantiate("enemy", map[string]any{"name": "Imp"}); !errors.As(err, &dup) || dup.Code != ErrDuplicateWorkflowID {
+		t.Errorf("expected %s for a colliding instance ID, got %v", ErrDuplicateWorkflowID, err)
+	}
+
+	_, err = r.Instantiate("enemy", nil)
+	templateParamCode(t, err)
+	_, err = r.Instantiate("enemy", map[string]any{"name": "Goblin", "armor": 2})
+	templateParamCode(t, err)
+	var ve *ValidationError
+	if _, err := r.Instantiate("missing", nil); !errors.As(err, &ve) || ve.Code != ErrWorkflowNotFound {
+		t.Errorf("expected %s, got %v", ErrWorkflowNotFound, err)
+	}
+}
+
+func TestInstantiateTypedContainers(t *testing.T) {
+	r := NewRegistry()
+	tmpl := enemyTemplate()
+	tmpl.Body.Nodes["WIN"].Spec["drops"] = []map[string]any{{"item": Param("name")}}
+	tmpl.Body.Nodes["LOSE"].Spec["nested"] = NodeSpec{"hp": [2]any{Param("hp"), 0}}
+	if err := r.RegisterTemplate(tmpl); err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Instantiate("enemy", map[string]any{"name": "Orc", "hp": 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Nodes["WIN"].Spec["drops"].([]map[string]any)[0]["item"]; got != "Orc" {
+		t.Errorf("expected the placeholder in []map[string]any substituted, got %v", got)
+	}
+	if got := w.Nodes["LOSE"].Spec["nested"].(NodeSpec)["hp"].([2]any)[0]; got != 4 {
+		t.Errorf("expected the placeholder in a nested NodeSpec substituted, got %v", got)
+	}
+	if _, ok := tmpl.Body.Nodes["WIN"].Spec["drops"].([]map[string]any)[0]["item"].(ParamRef); !ok {
+		t.Error("instantiation must not modify the template body")
+	}
+}
+
+func TestInstantiateParamTypes(t *testing.T) {
+	r := NewRegistry()
+	if err := r.RegisterTemplate(enemyTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	whole, err := r.Instantiate("enemy", map[string]any{"name": "Orc", "hp": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	float, err := r.Instantiate("enemy", map[string]any{"name": "Orc", "hp": 3.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whole == float || float.Nodes["A"].Spec["stats"].(map[string]any)["hp"] != 3.0 {
+		t.Errorf("expected hp 3 and 3.0 to make different instances, got %s and %s", whole.ID, float.ID)
+	}
+}
+
+func TestInstantiateRunsValidators(t *testing.T) {
+	r := NewRegistry()
+	r.AddValidator(func(w *Workflow) error {
+		if hp, _ := w.Nodes["A"].Spec["stats"].(map[string]any)["hp"].(int); hp < 0 {
+			return errors.New("hp must not be negative")
+		}
+		return nil
+	})
+	if err := r.RegisterTemplate(enemyTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	var ve *ValidationError
+	if _, err := r.Instantiate("enemy", map[string]any{"name": "Ghost", "hp": -1}); !errors.As(err, &ve) || ve.Code != ErrCustomValidation {
+		t.Errorf("expected %s, got %v", ErrCustomValidation, err)
+	}
+	if len(r.List()) != 0 {
+		t.Errorf("expected the invalid instance not to be registered, got %v", r.List())
+	}
+}
+
+func templateInvoker(params map[string]any) *Workflow {
+	w := invokerWorkflow("parent", "enemy")
+	w.Nodes["A"].Invokes.Params = params
+	return w
+}
+
+func TestTemplateInvocation(t *testing.T) {
+	t.Run("instantiated at registration", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.RegisterTemplate(enemyTemplate())
+		if err := r.Register(templateInvoker(map[string]any{"name": "Orc"})); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.List()) != 2 {
+			t.Errorf("expected parent and one instance, got %v", r.List())
+		}
+		if r.CallGraph().Calls[0].Callee == "" {
+			t.Error("expected the call graph to resolve the instance")
+		}
+	})
+
+	t.Run("bad params reject the invoker", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.RegisterTemplate(enemyTemplate())
+		templateParamCode(t, r.Register(templateInvoker(map[string]any{"hp": 1})))
+		if r.Has("parent") {
+			t.Error("expected the invoker not to be registered")
+		}
+	})
+
+	t.Run("failed instance rolls back earlier ones", func(t *testing.T) {
+		r := NewRegistry()
+		r.AddValidator(func(w *Workflow) error {
+			if stats, ok := w.Nodes["A"].Spec["stats"].(map[string]any); ok && stats["hp"].(int) < 0 {
+				return errors.New("hp must not be negative")
+			}
+			return nil
+		})
+		_ = r.RegisterTemplate(enemyTemplate())
+		w := templateInvoker(map[string]any{"name": "Orc"})
+		w.Nodes["B"].Invokes = &InvocationSpec{WorkflowID: "enemy", Params: map[string]any{"name": "Ghost", "hp": -1}}
+		var ve *ValidationError
+		if err := r.Register(w); !errors.As(err, &ve) || ve.Code != ErrCustomValidation {
+			t.Fatalf("expected %s, got %v", ErrCustomValidation, err)
+		}
+		if len(r.List()) != 0 {
+			t.Errorf("expected no workflows or instances left, got %v", r.List())
+		}
+	})
+
+	t.Run("instantiated at invocation", func(t *testing.T) {
+		r := NewRegistry()
+		if err := r.Register(templateInvoker(map[string]any{"name": "Troll", "hp": 9})); err != nil {
+			t.Fatal(err)
+		}
+		_ = r.RegisterTemplate(enemyTemplate())
+
+		var seen NodeSpec
+		e := NewEngine(r, agentFunc(func(ctx context.Context, dc DecisionContext) (Decision, error) {
+			if dc.Workflow.Metadata["template"] == "enemy" && dc.Node.ID == "A" {
+				seen = dc.Node.Spec
+			}
+			return autoAdvanceAgent().Resolve(ctx, dc)
+		}))
+		_, _ = e.Init("parent")
+		result, err := e.Run(context.Background())
+		if err != nil || result.Status != StepCompleted {
+			t.Fatalf("expected completion, got %+v, %v", result, err)
+		}
+		if seen["name"] != "Troll" || seen["stats"].(map[string]any)["hp"] != 9 {
+			t.Errorf("expected the instance's spec, got %v", seen)
+		}
+	})
+
+	t.Run("missing template suspends", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(templateInvoker(map[string]any{"name": "Troll"}))
+		e := NewEngine(r, autoAdvanceAgent())
+		_, _ = e.Init("parent")
+		result, _ := e.Step(context.Background())
+		if result.Status != StepSuspended || !strings.Contains(result.Reason, "template 'enemy'") {
+			t.Errorf("expected suspension naming the template, got %+v", result)
+		}
+	})
+}
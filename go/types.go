@@ -8,7 +8,10 @@
 // See DESIGN.md in the repository root for the formal specification.
 package reflex
 
-import "context"
+import (
+	"context"
+	"log/slog"
+)
 
 // ---------------------------------------------------------------------------
 // 2.3 NodeSpec — Opaque to Reflex
@@ -36,9 +39,13 @@ type ReturnMapping struct {
 
 // InvocationSpec declares that a node is a composition point. When the engine
 // enters a node with an InvocationSpec, it automatically starts the sub-workflow.
+// WorkflowID is a workflow reference: a bare ID starts the latest registered
+// version, "id@version" pins one. With Params set, WorkflowID names a
+// Template instead and the sub-workflow is its instance for Params.
 type InvocationSpec struct {
 	WorkflowID string          `json:"workflowId"`
 	ReturnMap  []ReturnMapping `json:"returnMap"`
+	Params     map[string]any  `json:"params,omitempty"`
 }
 
 // ---------------------------------------------------------------------------
@@ -51,6 +58,25 @@ type Node struct {
 	Description string          `json:"description,omitempty"`
 	Spec        NodeSpec        `json:"spec"`
 	Invokes     *InvocationSpec `json:"invokes,omitempty"`
+	// Inputs and Outputs declare the blackboard keys the node reads and
+	// writes. They are checked by Registry.Verify.
+	Inputs  []NodeInput  `json:"inputs,omitempty"`
+	Outputs []NodeOutput `json:"outputs,omitempty"`
+}
+
+// NodeInput declares a blackboard key a node expects to read.
+type NodeInput struct {
+	Key         string `json:"key"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// NodeOutput declares a blackboard key a node may write. Guaranteed outputs
+// are written every time the node runs.
+type NodeOutput struct {
+	Key         string `json:"key"`
+	Guaranteed  bool   `json:"guaranteed"`
+	Description string `json:"description,omitempty"`
 }
 
 // ---------------------------------------------------------------------------
@@ -84,7 +110,10 @@ type BuiltinGuard struct {
 // CustomGuardFunc wraps an arbitrary function as a Guard.
 // The function must be total, terminating, and side-effect free.
 type CustomGuardFunc struct {
-	Fn func(BlackboardReader) (bool, error)
+	// Name identifies the guard in workflow files, where it is referenced as
+	// {"type": "custom", "name": "..."}. Optional for programmatic workflows.
+	Name string
+	Fn   func(BlackboardReader) (bool, error)
 }
 
 // ---------------------------------------------------------------------------
@@ -112,6 +141,16 @@ type Workflow struct {
 	Nodes    map[string]*Node `json:"nodes"`
 	Edges    []Edge           `json:"edges"`
 	Metadata map[string]any   `json:"metadata,omitempty"`
+	// Version distinguishes definitions registered under the same ID. Sessions
+	// stay on the version they entered even if a newer one is registered.
+	Version int `json:"version,omitempty"`
+	// SensitiveKeys lists blackboard key patterns (path.Match syntax, e.g.
+	// "api_*") whose writes in this workflow are marked sensitive.
+	SensitiveKeys []string `json:"sensitiveKeys,omitempty"`
+	// Outputs lists the keys this workflow writes to its local scope that no
+	// node declares in its Outputs. OutputKeys combines both, and returnMaps
+	// invoking this workflow are checked against it.
+	Outputs []string `json:"outputs,omitempty"`
 }
 
 // ---------------------------------------------------------------------------
@@ -131,6 +170,13 @@ type BlackboardEntry struct {
 	Value     any              `json:"value"`
 	Source    BlackboardSource `json:"source"`
 	Timestamp int64            `json:"timestamp"`
+	// PromotedFrom is set on entries written by a returnMap promotion. It
+	// holds the child entry the value was copied from, which may itself have
+	// been promoted. The chain ends at the node that first wrote the value.
+	PromotedFrom *BlackboardEntry `json:"promotedFrom,omitempty"`
+	// Sensitive entries are readable by guards and agents but their values
+	// are replaced with RedactedValue in events and exports.
+	Sensitive bool `json:"sensitive,omitempty"`
 }
 
 // InitOptions configures optional parameters for Engine.Init().
@@ -141,10 +187,40 @@ type InitOptions struct {
 	Blackboard []BlackboardWrite
 }
 
+// EngineOptions configures optional engine behaviour. Pass it to NewEngine.
+type EngineOptions struct {
+	// ArchiveScopes keeps the local blackboard of every sub-workflow that pops
+	// off the stack, keyed by invocation ID, instead of discarding it. Parent
+	// reads are unaffected: archived entries are never visible to guards or
+	// agents. See Engine.Archive.
+	ArchiveScopes bool
+
+	// ValueMode controls how written values are stored. ValueModeCopy
+	// deep-copies values so writers cannot rewrite history by mutating them
+	// afterwards; ValueModeStrict also rejects non-JSON-compatible values,
+	// suspending with engine:error. Defaults to ValueModeReference.
+	ValueMode ValueMode
+
+	// StrictContracts enforces node Outputs at runtime: a decision that
+	// writes a key its node does not declare, or advances or completes
+	// without the node's guaranteed outputs, suspends with an engine:error
+	// carrying a *ContractError. Nothing is written. Only nodes that declare
+	// Outputs are checked, so contracts can be adopted node by node.
+	StrictContracts bool
+
+	// Logger receives structured records for session start, every step,
+	// invocations, suspensions, rejections and errors. Defaults to discarding
+	// them.
+	Logger *slog.Logger
+}
+
 // BlackboardWrite is a key-value pair to append to the blackboard.
 type BlackboardWrite struct {
 	Key   string `json:"key"`
 	Value any    `json:"value"`
+	// Sensitive marks the resulting entry as sensitive. Keys matching the
+	// workflow's SensitiveKeys are marked regardless.
+	Sensitive bool `json:"sensitive,omitempty"`
 }
 
 // BlackboardReader provides read-only access to the scoped blackboard.
@@ -164,6 +240,15 @@ type BlackboardReader interface {
 	Local() []BlackboardEntry
 }
 
+// LineageReader is implemented by the engine's BlackboardReaders. It is kept
+// out of BlackboardReader so that existing implementations stay valid; assert
+// it where provenance is needed.
+type LineageReader interface {
+	// Lineage returns the entry Get would read for key, followed by every
+	// entry it was promoted from, ending at the original write.
+	Lineage(key string) []BlackboardEntry
+}
+
 // ---------------------------------------------------------------------------
 // 2.9 Call Stack
 // ---------------------------------------------------------------------------
@@ -174,6 +259,26 @@ type StackFrame struct {
 	CurrentNodeID string           `json:"currentNodeId"`
 	ReturnMap     []ReturnMapping  `json:"returnMap"`
 	Blackboard    []BlackboardEntry `json:"blackboard"`
+	// WorkflowVersion is the version of WorkflowID the frame is running.
+	WorkflowVersion int `json:"workflowVersion,omitempty"`
+	// InvocationID identifies this frame's workflow instance. It is empty for
+	// the root workflow.
+	InvocationID string `json:"invocationId,omitempty"`
+}
+
+// ArchivedScope is the local blackboard of a sub-workflow instance that has
+// completed and popped off the stack. Recorded only when
+// EngineOptions.ArchiveScopes is set.
+type ArchivedScope struct {
+	InvocationID string `json:"invocationId"`
+	WorkflowID   string `json:"workflowId"`
+	// ParentWorkflowID and InvokingNodeID identify the invocation node the
+	// sub-workflow was started from.
+	ParentWorkflowID   string            `json:"parentWorkflowId"`
+	InvokingNodeID     string            `json:"invokingNodeId"`
+	ParentInvocationID string            `json:"parentInvocationId,omitempty"`
+	StackDepth         int               `json:"stackDepth"`
+	Entries            []BlackboardEntry `json:"entries"`
 }
 
 // ---------------------------------------------------------------------------
@@ -258,6 +363,9 @@ const (
 	EventEngineComplete EventType = "engine:complete"
 	EventEngineSuspend  EventType = "engine:suspend"
 	EventEngineError    EventType = "engine:error"
+	// EventWorkflowMigrate reports a session moved to another workflow
+	// version by Engine.Migrate.
+	EventWorkflowMigrate EventType = "workflow:migrate"
 )
 
 // Event carries data about an engine lifecycle event.
@@ -269,8 +377,35 @@ type Event struct {
 	EdgeID     string           `json:"edgeId,omitempty"`
 	Entries    []BlackboardEntry `json:"entries,omitempty"`
 	Reason     string           `json:"reason,omitempty"`
+	// InvocationID identifies the sub-workflow instance on workflow:push and
+	// workflow:pop events.
+	InvocationID string `json:"invocationId,omitempty"`
+	// WorkflowVersion is the version of WorkflowID the event refers to.
+	WorkflowVersion int `json:"workflowVersion,omitempty"`
+	// Migration is the migration applied, on workflow:migrate events.
+	Migration *Migration `json:"migration,omitempty"`
 	Error      error            `json:"-"`
 }
 
 // EventHandler is a callback invoked synchronously when an engine event occurs.
 type EventHandler func(Event)
+
+// WatchEvent reports a change in the effective value of a watched key.
+// Sensitive values are redacted.
+type WatchEvent struct {
+	SessionID string `json:"sessionId,omitempty"`
+	Key       string `json:"key"`
+	// Existed and Exists report whether the key was visible before and after
+	// the change; OldValue and NewValue are nil when it was not.
+	Existed  bool `json:"existed"`
+	Exists   bool `json:"exists"`
+	OldValue any  `json:"oldValue,omitempty"`
+	NewValue any  `json:"newValue,omitempty"`
+	// Entry is the entry now providing the value, or nil if the key is no
+	// longer visible.
+	Entry *BlackboardEntry `json:"entry,omitempty"`
+}
+
+// WatchHandler is a callback invoked synchronously when a watched key's
+// effective value changes. See Engine.Watch.
+type WatchHandler func(WatchEvent)
@@ -0,0 +1,154 @@
+package reflex
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Custom validators and NodeSpec linting
+// ---------------------------------------------------------------------------
+
+// Validator is an extra check run by Register and Replace after the built-in
+// structural checks pass. Return nil, a *ValidationError, a ValidationErrors,
+// or any other error, which is reported with code ErrCustomValidation.
+// Validators run while the registry is locked and must not call back into it.
+type Validator func(w *Workflow) error
+
+// SpecValidator is implemented by DecisionAgents that can check the NodeSpecs
+// they will be asked to resolve. Wrap one with SpecLinter to run it at
+// registration:
+//
+//	registry.AddValidator(reflex.SpecLinter(agent))
+type SpecValidator interface {
+	ValidateSpec(node *Node) error
+}
+
+// AddValidator registers v to run on every subsequent Register and Replace.
+func (r *Registry) AddValidator(v Validator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.validators = append(r.validators, v)
+}
+
+// SpecLinter adapts a SpecValidator to a Validator that checks every node of
+// the workflow. Each failing node is reported as ErrInvalidNodeSpec with its
+// ID in Details["nodeId"].
+func SpecLinter(sv SpecValidator) Validator {
+	return func(w *Workflow) error {
+		var problems ValidationErrors
+		for _, nodeID := range sortedNodeIDs(w) {
+			if err := sv.ValidateSpec(w.Nodes[nodeID]); err != nil {
+				problem := newValidationError(ErrInvalidNodeSpec, w.ID,
+					fmt.Sprintf("workflow '%s', node '%s': %v", w.ID, nodeID, err))
+				problem.Details = map[string]any{"nodeId": nodeID}
+				problems = append(problems, problem)
+			}
+		}
+		return problems.Err()
+	}
+}
+
+// CheckSpecKeys returns an error naming every key of spec not in known,
+// suggesting the closest known key for likely typos. Agents can use it to
+// implement SpecValidator.
+func CheckSpecKeys(spec NodeSpec, known ...string) error {
+	allowed := make(map[string]bool, len(known))
+	for _, k := range known {
+		allowed[k] = true
+	}
+	var unknown []string
+	for key := range spec {
+		if !allowed[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	msgs := make([]string, len(unknown))
+	for i, key := range unknown {
+		msgs[i] = fmt.Sprintf("unknown spec key '%s'", key)
+		if suggestion := closestKey(key, known); suggestion != "" {
+			msgs[i] += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+		}
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// runValidators runs the registered validators on w. Caller must hold r.mu.
+func (r *Registry) runValidators(w *Workflow) ValidationErrors {
+	var problems ValidationErrors
+	for _, v := range r.validators {
+		err := v(w)
+		if err == nil {
+			continue
+		}
+		var list ValidationErrors
+		var single *ValidationError
+		switch {
+		case errors.As(err, &list):
+			for _, ve := range list {
+				problems = append(problems, withDefaultSeverity(ve))
+			}
+		case errors.As(err, &single):
+			problems = append(problems, withDefaultSeverity(single))
+		default:
+			problems = append(problems, newValidationError(ErrCustomValidation, w.ID,
+				fmt.Sprintf("workflow '%s': %v", w.ID, err)))
+		}
+	}
+	return problems
+}
+
+// withDefaultSeverity returns ve, or a copy with SeverityError if it has no
+// severity, so that a ValidationError a validator builds by hand still
+// counts as an error.
+func withDefaultSeverity(ve *ValidationError) *ValidationError {
+	if ve.Severity != "" {
+		return ve
+	}
+	cp := *ve
+	cp.Severity = SeverityError
+	return &cp
+}
+
+// closestKey returns the candidate within edit distance 2 of key, or "".
+func closestKey(key string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(key, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Damerau-Levenshtein (optimal string alignment)
+// distance, so a swapped pair of letters counts as one edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,116 @@
+package reflex
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type specValidatorFunc func(node *Node) error
+
+func (f specValidatorFunc) ValidateSpec(node *Node) error { return f(node) }
+
+func TestRegistryAddValidator(t *testing.T) {
+	t.Run("plain errors become CUSTOM_VALIDATION", func(t *testing.T) {
+		r := NewRegistry()
+		r.AddValidator(func(w *Workflow) error {
+			if w.Metadata["owner"] == nil {
+				return errors.New("missing owner metadata")
+			}
+			return nil
+		})
+		err := r.Register(linearWorkflow("wf"))
+		assertValidationError(t, err, ErrCustomValidation)
+		if !strings.Contains(err.Error(), "missing owner metadata") {
+			t.Errorf("expected validator message, got %q", err)
+		}
+		if r.Has("wf") {
+			t.Error("rejected workflow must not be registered")
+		}
+
+		ok := linearWorkflow("wf")
+		ok.Metadata = map[string]any{"owner": "team"}
+		if err := r.Register(ok); err != nil {
+			t.Errorf("expected registration, got %v", err)
+		}
+		if err := r.Replace(linearWorkflow("wf")); err == nil {
+			t.Error("expected Replace to run validators")
+		}
+	})
+
+	t.Run("validation errors pass through", func(t *testing.T) {
+		r := NewRegistry(RegistryOptions{ValidationMode: ValidateAll})
+		r.AddValidator(func(w *Workflow) error {
+			return ValidationErrors{
+				newValidationWarning("NAMING", w.ID, "lowercase IDs preferred"),
+				newValidationError("POLICY", w.ID, "forbidden workflow"),
+			}
+		})
+		var problems ValidationErrors
+		if !errors.As(r.Register(linearWorkflow("wf")), &problems) {
+			t.Fatal("expected ValidationErrors")
+		}
+		if len(problems) != 2 || problems[0].Code != "NAMING" || problems[1].Code != "POLICY" {
+			t.Errorf("unexpected problems %v", problems)
+		}
+	})
+
+	t.Run("missing severity means error", func(t *testing.T) {
+		r := NewRegistry()
+		returned := &ValidationError{Code: "POLICY", WorkflowID: "wf", Message: "forbidden workflow"}
+		r.AddValidator(func(*Workflow) error { return returned })
+		err := r.Register(linearWorkflow("wf"))
+		assertValidationError(t, err, "POLICY")
+		if r.Has("wf") {
+			t.Error("rejected workflow must not be registered")
+		}
+		if returned.Severity != "" {
+			t.Error("the validator's error must not be modified")
+		}
+	})
+
+	t.Run("skipped when structural checks fail", func(t *testing.T) {
+		r := NewRegistry()
+		called := false
+		r.AddValidator(func(*Workflow) error { called = true; return nil })
+		broken := linearWorkflow("wf")
+		broken.Entry = "MISSING"
+		_ = r.Register(broken)
+		if called {
+			t.Error("validator should not run on a structurally invalid workflow")
+		}
+	})
+}
+
+func TestSpecLinter(t *testing.T) {
+	r := NewRegistry(RegistryOptions{ValidationMode: ValidateAll})
+	r.AddValidator(SpecLinter(specValidatorFunc(func(node *Node) error {
+		return CheckSpecKeys(node.Spec, "complete")
+	})))
+
+	w := linearWorkflow("wf")
+	w.Nodes["A"].Spec = NodeSpec{"compelte": true}
+	w.Nodes["C"].Spec = NodeSpec{"complete": true}
+
+	var problems ValidationErrors
+	if !errors.As(r.Register(w), &problems) {
+		t.Fatal("expected ValidationErrors")
+	}
+	if len(problems) != 1 || problems[0].Code != ErrInvalidNodeSpec || problems[0].Details["nodeId"] != "A" {
+		t.Fatalf("expected invalid spec at A, got %v", problems)
+	}
+	if !strings.Contains(problems[0].Message, "did you mean 'complete'?") {
+		t.Errorf("expected suggestion, got %q", problems[0].Message)
+	}
+}
+
+func TestCheckSpecKeys(t *testing.T) {
+	if err := CheckSpecKeys(NodeSpec{"edge": "e1"}, "edge", "writes"); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	err := CheckSpecKeys(NodeSpec{"zzz": 1, "wirtes": nil}, "edge", "writes")
+	want := "unknown spec key 'wirtes' (did you mean 'writes'?); unknown spec key 'zzz'"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
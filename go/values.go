@@ -0,0 +1,298 @@
+package reflex
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ---------------------------------------------------------------------------
+// Value handling on append (DESIGN.md Section 5, The Append-Only Invariant)
+// ---------------------------------------------------------------------------
+
+// ValueMode controls how a ScopedBlackboard stores the values it is given.
+type ValueMode int
+
+const (
+	// ValueModeReference stores values as written. A map or slice mutated by
+	// its writer after the write changes the blackboard's history. Default.
+	ValueModeReference ValueMode = iota
+	// ValueModeCopy deep-copies maps, slices, arrays, pointers and exported
+	// struct fields on append, so later mutation by the writer is not
+	// observable through the blackboard. Readers, events, stack frames and
+	// archived scopes receive their own copies, so mutating a value read
+	// back does not change history either. Values containing reference cycles are rejected.
+	ValueModeCopy
+	// ValueModeStrict copies like ValueModeCopy and additionally rejects
+	// values that are not JSON-compatible (funcs, channels, pointers, complex
+	// numbers, maps with non-string keys) before they are written.
+	ValueModeStrict
+)
+
+// CopyValue returns a deep copy of v. Maps, slices, arrays and pointers are
+// copied recursively; structs are copied by value with their exported fields
+// copied recursively. Funcs and channels are returned as-is. Like
+// json.Marshal, CopyValue returns an error if v contains a reference cycle.
+func CopyValue(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	cp, err := deepCopy(reflect.ValueOf(v), make(visiting))
+	if err != nil {
+		return nil, err
+	}
+	return cp.Interface(), nil
+}
+
+// visiting records the maps, slices and pointers on the path from the root
+// of a value being walked, so that a reference cycle is reported instead of
+// recursing without bound. Values shared without a cycle are walked each
+// time they are reached.
+type visiting map[visitKey]struct{}
+
+// visitKey identifies a map, slice or pointer. Slices are keyed by length as
+// well as address, since a slice and a subslice share their first element.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter marks v as being walked. It returns false if v is already on the
+// path, i.e. if v is reachable from itself.
+func (seen visiting) enter(v reflect.Value) bool {
+	key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if _, ok := seen[key]; ok {
+		return false
+	}
+	seen[key] = struct{}{}
+	return true
+}
+
+func (seen visiting) leave(v reflect.Value) {
+	key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	delete(seen, key)
+}
+
+func cycleError(v reflect.Value) error {
+	return fmt.Errorf("encountered a cycle via %s", v.Type())
+}
+
+func deepCopy(v reflect.Value, seen visiting) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		if !seen.enter(v) {
+			return v, cycleError(v)
+		}
+		defer seen.leave(v)
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, err := deepCopyElem(iter.Value(), v.Type().Elem(), seen)
+			if err != nil {
+				return v, err
+			}
+			cp.SetMapIndex(iter.Key(), elem)
+		}
+		return cp, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		if v.Len() > 0 {
+			if !seen.enter(v) {
+				return v, cycleError(v)
+			}
+			defer seen.leave(v)
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, err := deepCopyElem(v.Index(i), v.Type().Elem(), seen)
+			if err != nil {
+				return v, err
+			}
+			cp.Index(i).Set(elem)
+		}
+		return cp, nil
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			elem, err := deepCopyElem(v.Index(i), v.Type().Elem(), seen)
+			if err != nil {
+				return v, err
+			}
+			cp.Index(i).Set(elem)
+		}
+		return cp, nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, nil
+		}
+		if !seen.enter(v) {
+			return v, cycleError(v)
+		}
+		defer seen.leave(v)
+		elem, err := deepCopy(v.Elem(), seen)
+		if err != nil {
+			return v, err
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(elem)
+		return cp, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		return deepCopy(v.Elem(), seen)
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if cp.Field(i).CanSet() {
+				elem, err := deepCopyElem(v.Field(i), v.Type().Field(i).Type, seen)
+				if err != nil {
+					return v, err
+				}
+				cp.Field(i).Set(elem)
+			}
+		}
+		return cp, nil
+	default:
+		return v, nil
+	}
+}
+
+// deepCopyElem copies v for storage in a slot of type t. Interface slots hold
+// a dynamic value which is copied and re-wrapped.
+func deepCopyElem(v reflect.Value, t reflect.Type, seen visiting) (reflect.Value, error) {
+	if t.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		elem, err := deepCopy(v.Elem(), seen)
+		if err != nil {
+			return v, err
+		}
+		cp := reflect.New(t).Elem()
+		cp.Set(elem)
+		return cp, nil
+	}
+	return deepCopy(v, seen)
+}
+
+// CheckValue reports whether v can be stored in ValueModeStrict. It returns an
+// error naming the path of the first value that is not JSON-compatible or
+// that closes a reference cycle.
+func CheckValue(v any) error {
+	if v == nil {
+		return nil
+	}
+	return checkValue(reflect.ValueOf(v), "$", true, make(visiting))
+}
+
+// checkValue walks v looking for reference cycles and, if strict, for values
+// that are not JSON-compatible.
+func checkValue(v reflect.Value, path string, strict bool, seen visiting) error {
+	switch v.Kind() {
+	case reflect.Invalid, reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkValue(v.Elem(), path, strict, seen)
+	case reflect.Map:
+		if strict {
+			switch v.Type().Key().Kind() {
+			case reflect.String,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			default:
+				return fmt.Errorf("%s: map key type %s is not JSON-compatible", path, v.Type().Key())
+			}
+		}
+		if v.IsNil() {
+			return nil
+		}
+		if !seen.enter(v) {
+			return fmt.Errorf("%s: %w", path, cycleError(v))
+		}
+		defer seen.leave(v)
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), strict, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			if !seen.enter(v) {
+				return fmt.Errorf("%s: %w", path, cycleError(v))
+			}
+			defer seen.leave(v)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := checkValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), strict, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := checkValue(v.Field(i), path+"."+v.Type().Field(i).Name, strict, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Pointer:
+		if strict {
+			break
+		}
+		if v.IsNil() {
+			return nil
+		}
+		if !seen.enter(v) {
+			return fmt.Errorf("%s: %w", path, cycleError(v))
+		}
+		defer seen.leave(v)
+		return checkValue(v.Elem(), path, strict, seen)
+	default:
+		if !strict {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %s value is not JSON-compatible", path, v.Kind())
+}
+
+// checkWrites validates writes for mode. ValueModeCopy rejects values with
+// reference cycles, which cannot be copied; ValueModeStrict also rejects
+// values that are not JSON-compatible.
+func checkWrites(writes []BlackboardWrite, mode ValueMode) error {
+	if mode == ValueModeReference {
+		return nil
+	}
+	for _, w := range writes {
+		if w.Value == nil {
+			continue
+		}
+		if err := checkValue(reflect.ValueOf(w.Value), "$", mode == ValueModeStrict, make(visiting)); err != nil {
+			return fmt.Errorf("write to key '%s' rejected: %w", w.Key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package reflex
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ---------------------------------------------------------------------------
+// CopyValue
+// ---------------------------------------------------------------------------
+
+// mustCopy returns CopyValue(v), failing the test on error.
+func mustCopy(t *testing.T, v any) any {
+	t.Helper()
+	cp, err := CopyValue(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cp
+}
+
+func TestCopyValue(t *testing.T) {
+	t.Run("map is copied", func(t *testing.T) {
+		orig := map[string]any{"hp": 8, "items": []any{"sword"}}
+		cp := mustCopy(t, orig).(map[string]any)
+		orig["hp"] = 0
+		orig["items"].([]any)[0] = "stick"
+		if cp["hp"] != 8 || cp["items"].([]any)[0] != "sword" {
+			t.Errorf("copy changed with original: %v", cp)
+		}
+	})
+	t.Run("slice is copied", func(t *testing.T) {
+		orig := []int{1, 2, 3}
+		cp := mustCopy(t, orig).([]int)
+		orig[0] = 99
+		if cp[0] != 1 {
+			t.Errorf("expected 1, got %d", cp[0])
+		}
+	})
+	t.Run("struct fields are copied", func(t *testing.T) {
+		orig := []BlackboardWrite{{Key: "k", Value: map[string]any{"a": 1}}}
+		cp := mustCopy(t, orig).([]BlackboardWrite)
+		orig[0].Value.(map[string]any)["a"] = 2
+		if cp[0].Value.(map[string]any)["a"] != 1 {
+			t.Error("nested struct field value changed with original")
+		}
+	})
+	t.Run("pointer target is copied", func(t *testing.T) {
+		n := 1
+		cp := mustCopy(t, &n).(*int)
+		n = 2
+		if *cp != 1 {
+			t.Errorf("expected 1, got %d", *cp)
+		}
+	})
+	t.Run("nil containers stay nil", func(t *testing.T) {
+		var m map[string]any
+		if mustCopy(t, m).(map[string]any) != nil {
+			t.Error("expected nil map")
+		}
+		if mustCopy(t, nil) != nil {
+			t.Error("expected nil")
+		}
+	})
+	t.Run("scalars are unchanged", func(t *testing.T) {
+		for _, v := range []any{42, "s", true, 1.5} {
+			if !reflect.DeepEqual(mustCopy(t, v), v) {
+				t.Errorf("expected %v unchanged", v)
+			}
+		}
+	})
+	t.Run("shared values are copied", func(t *testing.T) {
+		shared := []any{1}
+		cp := mustCopy(t, map[string]any{"a": shared, "b": shared}).(map[string]any)
+		if !reflect.DeepEqual(cp["a"], shared) || !reflect.DeepEqual(cp["b"], shared) {
+			t.Errorf("unexpected copy %v", cp)
+		}
+	})
+	t.Run("cycles are rejected", func(t *testing.T) {
+		m := map[string]any{}
+		m["self"] = m
+		s := []any{nil}
+		s[0] = s
+		type node struct{ Next *node }
+		n := &node{}
+		n.Next = n
+		for _, v := range []any{m, s, n} {
+			if _, err := CopyValue(v); err == nil {
+				t.Errorf("expected a cycle error for %T", v)
+			}
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// CheckValue
+// ---------------------------------------------------------------------------
+
+func TestCheckValue(t *testing.T) {
+	n := 1
+	tests := []struct {
+		name  string
+		value any
+		ok    bool
+	}{
+		{"nil", nil, true},
+		{"string", "s", true},
+		{"number", 3.5, true},
+		{"nested containers", map[string]any{"a": []any{1, "x", map[string]any{}}}, true},
+		{"struct", BlackboardWrite{Key: "k", Value: 1}, true},
+		{"int-keyed map", map[int]string{1: "a"}, true},
+		{"func", func() {}, false},
+		{"channel", make(chan int), false},
+		{"pointer", &n, false},
+		{"complex", complex(1, 2), false},
+		{"bool-keyed map", map[bool]int{true: 1}, false},
+		{"func nested in map", map[string]any{"cb": func() {}}, false},
+		{"pointer nested in slice", []any{1, &n}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckValue(tt.value)
+			if tt.ok && err != nil {
+				t.Errorf("expected ok, got %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	t.Run("cycles are rejected", func(t *testing.T) {
+		m := map[string]any{"items": []any{1}}
+		m["items"].([]any)[0] = m
+		err := CheckValue(m)
+		if err == nil || err.Error() != "$[items][0]: encountered a cycle via map[string]interface {}" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("error names the offending path", func(t *testing.T) {
+		err := CheckValue(map[string]any{"items": []any{1, func() {}}})
+		if err == nil || err.Error() != "$[items][1]: func value is not JSON-compatible" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package reflex
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+)
+
+// ---------------------------------------------------------------------------
+// Per-key watches
+// ---------------------------------------------------------------------------
+
+type watcher struct {
+	pattern string
+	handler WatchHandler
+}
+
+// Watch registers handler for changes to the effective value of any key
+// matching keyPattern (path.Match syntax, e.g. "player_*"). The effective
+// value is what BlackboardReader.Get returns over the current scope chain, so
+// the handler fires when a write changes it, when a sub-workflow pop reveals
+// a value it was shadowing, and when a session is initialized. Writes that
+// leave the value unchanged (reflect.DeepEqual) do not fire.
+//
+// Handlers run synchronously after the step's events have been emitted.
+// Returns an error if keyPattern is malformed.
+func (e *Engine) Watch(keyPattern string, handler WatchHandler) error {
+	if _, err := path.Match(keyPattern, ""); err != nil {
+		return &EngineError{Message: fmt.Sprintf("invalid watch pattern '%s': %v", keyPattern, err)}
+	}
+	e.watchers = append(e.watchers, watcher{pattern: keyPattern, handler: handler})
+	return nil
+}
+
+// watchedEntries returns the effective entry of every visible key matched by
+// at least one watcher.
+func (e *Engine) watchedEntries() map[string]BlackboardEntry {
+	reader := e.buildBlackboardReader()
+	lineage, _ := reader.(LineageReader)
+	result := make(map[string]BlackboardEntry)
+	for _, key := range reader.Keys() {
+		if !e.isWatched(key) {
+			continue
+		}
+		if lineage != nil {
+			if entries := lineage.Lineage(key); len(entries) > 0 {
+				result[key] = entries[0]
+			}
+		} else if entries := reader.GetAll(key); len(entries) > 0 {
+			result[key] = entries[0]
+		}
+	}
+	return result
+}
+
+func (e *Engine) isWatched(key string) bool {
+	for _, w := range e.watchers {
+		if ok, _ := path.Match(w.pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyWatchers compares the current effective values with before and calls
+// the matching handlers for each key whose value changed, in key order.
+func (e *Engine) notifyWatchers(before map[string]BlackboardEntry) {
+	after := e.watchedEntries()
+
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		old, hadOld := before[key]
+		cur, hasNew := after[key]
+		if hadOld == hasNew && (!hasNew || reflect.DeepEqual(old.Value, cur.Value)) {
+			continue
+		}
+		ev := WatchEvent{SessionID: e.sessionID, Key: key, Existed: hadOld, Exists: hasNew}
+		if hadOld {
+			ev.OldValue = redactEntry(old).Value
+		}
+		if hasNew {
+			entry := redactEntry(cur)
+			ev.NewValue = entry.Value
+			ev.Entry = &entry
+		}
+		for _, w := range e.watchers {
+			if ok, _ := path.Match(w.pattern, key); ok {
+				w.handler(ev)
+			}
+		}
+	}
+}
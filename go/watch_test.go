@@ -0,0 +1,144 @@
+package reflex
+
+import (
+	"context"
+	"testing"
+)
+
+// setupWatch registers a parent that seeds player_hp and invokes a child
+// that overwrites it locally. promote controls whether the child's value is
+// returned to the parent.
+func setupWatch(promote bool) *Engine {
+	r := NewRegistry()
+	_ = r.Register(&Workflow{
+		ID: "child", Entry: "C1",
+		Nodes: map[string]*Node{
+			"C1":   {ID: "C1", Spec: NodeSpec{}},
+			"CEND": {ID: "CEND", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "ec1", From: "C1", To: "CEND", Event: "NEXT"}},
+	})
+	invokes := &InvocationSpec{WorkflowID: "child"}
+	if promote {
+		invokes.ReturnMap = []ReturnMapping{{ParentKey: "player_hp", ChildKey: "player_hp"}}
+	}
+	_ = r.Register(&Workflow{
+		ID: "parent", Entry: "P1",
+		Nodes: map[string]*Node{
+			"P1": {ID: "P1", Spec: NodeSpec{}, Invokes: invokes},
+			"P2": {ID: "P2", Spec: NodeSpec{}},
+		},
+		Edges: []Edge{{ID: "ep1", From: "P1", To: "P2", Event: "NEXT"}},
+	})
+	agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+		if dc.Node.ID == "C1" {
+			return Decision{Type: DecisionAdvance, Edge: "ec1", Writes: []BlackboardWrite{
+				{Key: "player_hp", Value: 5},
+				{Key: "enemy_hp", Value: 3},
+			}}, nil
+		}
+		if len(dc.ValidEdges) == 0 {
+			return Decision{Type: DecisionComplete}, nil
+		}
+		return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID}, nil
+	})
+	return NewEngine(r, agent)
+}
+
+func TestEngineWatch(t *testing.T) {
+	seed := InitOptions{Blackboard: []BlackboardWrite{{Key: "player_hp", Value: 8}}}
+
+	t.Run("tracks shadowing across push and pop", func(t *testing.T) {
+		e := setupWatch(false)
+		var changes []WatchEvent
+		if err := e.Watch("player_hp", func(ev WatchEvent) { changes = append(changes, ev) }); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = e.Init("parent", seed)
+		_, _ = e.Run(context.Background())
+
+		// init: ∅→8, child write: 8→5, pop reveals parent value: 5→8
+		if len(changes) != 3 {
+			t.Fatalf("expected 3 changes, got %d: %+v", len(changes), changes)
+		}
+		if changes[0].Existed || changes[0].NewValue != 8 {
+			t.Errorf("change 0: expected ∅→8, got %+v", changes[0])
+		}
+		if changes[1].OldValue != 8 || changes[1].NewValue != 5 {
+			t.Errorf("change 1: expected 8→5, got %+v", changes[1])
+		}
+		if changes[2].OldValue != 5 || changes[2].NewValue != 8 {
+			t.Errorf("change 2: expected 5→8, got %+v", changes[2])
+		}
+		if changes[2].Entry == nil || changes[2].Entry.Source.NodeID != "__init__" {
+			t.Errorf("change 2: expected seed entry to be visible again, got %+v", changes[2].Entry)
+		}
+	})
+
+	t.Run("promotion of the same value does not fire on pop", func(t *testing.T) {
+		e := setupWatch(true)
+		var changes []WatchEvent
+		_ = e.Watch("player_hp", func(ev WatchEvent) { changes = append(changes, ev) })
+		_, _ = e.Init("parent", seed)
+		_, _ = e.Run(context.Background())
+
+		if len(changes) != 2 {
+			t.Fatalf("expected 2 changes (init, child write), got %d: %+v", len(changes), changes)
+		}
+		if v, _ := e.Blackboard().Get("player_hp"); v != 5 {
+			t.Errorf("expected promoted player_hp=5, got %v", v)
+		}
+	})
+
+	t.Run("key disappearing on pop is reported", func(t *testing.T) {
+		e := setupWatch(false)
+		var changes []WatchEvent
+		_ = e.Watch("enemy_*", func(ev WatchEvent) { changes = append(changes, ev) })
+		_, _ = e.Init("parent")
+		_, _ = e.Run(context.Background())
+
+		if len(changes) != 2 {
+			t.Fatalf("expected 2 changes, got %d: %+v", len(changes), changes)
+		}
+		if changes[1].Exists || !changes[1].Existed || changes[1].OldValue != 3 {
+			t.Errorf("expected enemy_hp to vanish on pop, got %+v", changes[1])
+		}
+	})
+
+	t.Run("rewriting an equal value does not fire", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(linearWorkflow("wf"))
+		agent := agentFunc(func(_ context.Context, dc DecisionContext) (Decision, error) {
+			if len(dc.ValidEdges) == 0 {
+				return Decision{Type: DecisionComplete}, nil
+			}
+			return Decision{Type: DecisionAdvance, Edge: dc.ValidEdges[0].ID,
+				Writes: []BlackboardWrite{{Key: "mode", Value: []string{"easy"}}}}, nil
+		})
+		e := NewEngine(r, agent)
+		count := 0
+		_ = e.Watch("mode", func(WatchEvent) { count++ })
+		_, _ = e.Init("wf")
+		_, _ = e.Run(context.Background())
+		if count != 1 {
+			t.Errorf("expected 1 change, got %d", count)
+		}
+	})
+
+	t.Run("sensitive values are redacted", func(t *testing.T) {
+		e := setupWatch(false)
+		var changes []WatchEvent
+		_ = e.Watch("*", func(ev WatchEvent) { changes = append(changes, ev) })
+		_, _ = e.Init("parent", InitOptions{Blackboard: []BlackboardWrite{{Key: "token", Value: "secret", Sensitive: true}}})
+		if len(changes) != 1 || changes[0].NewValue != RedactedValue {
+			t.Errorf("expected redacted token, got %+v", changes)
+		}
+	})
+
+	t.Run("invalid pattern is rejected", func(t *testing.T) {
+		e := setupWatch(false)
+		if err := e.Watch("[", func(WatchEvent) {}); err == nil {
+			t.Error("expected error for malformed pattern")
+		}
+	})
+}